@@ -18,6 +18,12 @@ const (
 	ArgAccessToken = "access-token"
 	// ArgContext is the name of the auth context
 	ArgContext = "context"
+	// ArgCredentialHelper is the credential helper storing auth context tokens
+	ArgCredentialHelper = "credential-helper"
+	// ArgCredentialsFile is the path of the encrypted credentials file
+	ArgCredentialsFile = "credentials-file"
+	// ArgProfile is the name of the configuration profile
+	ArgProfile = "profile"
 	// ArgDefaultContext is the default auth context
 	ArgDefaultContext = "default"
 	// ArgActionID is an action id argument.
@@ -92,6 +98,12 @@ const (
 	ArgClusterNodePool = "node-pool"
 	// ArgClusterUpdateKubeconfig updates the local kubeconfig.
 	ArgClusterUpdateKubeconfig = "update-kubeconfig"
+	// ArgClusterSpec is a path to a Kubernetes cluster spec in YAML or JSON.
+	ArgClusterSpec = "spec"
+	// ArgClusterSpecOutput is the format a Kubernetes cluster spec is written in.
+	ArgClusterSpecOutput = "spec-output"
+	// ArgClusterUpgradePreflight checks a cluster for upgrade blockers instead of upgrading it.
+	ArgClusterUpgradePreflight = "preflight"
 	// ArgNoCache represents whether or not to omit the cache on the next command.
 	ArgNoCache = "no-cache"
 	// ArgNodePoolName is a cluster's node pool name argument.
@@ -106,6 +118,10 @@ const (
 	ArgNodePoolMaxNodes = "max-nodes"
 	// ArgNodePoolNodeIDs is a cluster's node pool nodes argument.
 	ArgNodePoolNodeIDs = "node-ids"
+	// ArgNodePoolRotateBatch is how many nodes a node pool rotation replaces at once.
+	ArgNodePoolRotateBatch = "batch"
+	// ArgNodePoolRotateDrain cordons and drains nodes through the Kubernetes API before they are replaced.
+	ArgNodePoolRotateDrain = "drain"
 	// ArgMaintenanceWindow is a cluster's maintenance window argument
 	ArgMaintenanceWindow = "maintenance-window"
 	// ArgMajorVersion is a major version number.
@@ -191,6 +207,8 @@ const (
 	ArgKubernetesSSOClientID = "sso-client-id"
 	// ArgKubernetesSSOLocalServerPort is the port to use for the local server which handles SSO authentication flow.
 	ArgKubernetesSSOLocalServerPort = "sso-local-server-port"
+	// ArgKubernetesSSOFlow is how the user logs in for cluster SSO: auto, browser or device.
+	ArgKubernetesSSOFlow = "sso-flow"
 	// ArgSurgeUpgrade is a cluster's surge-upgrade argument.
 	ArgSurgeUpgrade = "surge-upgrade"
 	// ArgCommandUpsert is an upsert for a resource to be created or updated argument.
@@ -217,6 +235,12 @@ const (
 	ArgKubeConfigExpirySeconds = "expiry-seconds"
 	// ArgKubeConfigType selects the kubeconfig authentication mechanism: token, sso, or unset for the API default.
 	ArgKubeConfigType = "type"
+	// ArgKubeConfigPath is a path to a kubeconfig file to use instead of the default one.
+	ArgKubeConfigPath = "kubeconfig"
+	// ArgKubeConfigOutputDir is a directory to write a kubeconfig file per cluster in.
+	ArgKubeConfigOutputDir = "output-dir"
+	// ArgKubeConfigPrune removes the contexts of clusters that no longer exist from a kubeconfig.
+	ArgKubeConfigPrune = "prune"
 	// ArgImage is an image argument.
 	ArgImage = "image"
 	// ArgImageID is an image id argument.
@@ -259,6 +283,8 @@ const (
 	ArgMonitoring = "enable-monitoring"
 	// ArgDropletAgent is an argument for enabling/disabling the Droplet agent.
 	ArgDropletAgent = "droplet-agent"
+	// ArgMaxItems is the maximum number of items to list.
+	ArgMaxItems = "max-items"
 	// ArgRecordData is a record data argument.
 	ArgRecordData = "record-data"
 	// ArgRecordID is a record id argument.
@@ -279,6 +305,14 @@ const (
 	ArgRecordFlags = "record-flags"
 	// ArgRecordTag is a record tag argument.
 	ArgRecordTag = "record-tag"
+	// ArgZoneFile is the path of a DNS zone file.
+	ArgZoneFile = "zone-file"
+	// ArgZoneSync deletes the records missing from a zone file.
+	ArgZoneSync = "sync"
+	// ArgWaitPropagation waits for records to be served by the nameservers.
+	ArgWaitPropagation = "wait-propagation"
+	// ArgNameservers is the nameservers queried to check record propagation.
+	ArgNameservers = "nameservers"
 	// ArgRegionSlug is a region slug argument.
 	ArgRegionSlug = "region"
 	// ArgSchemaOnly is a schema only argument.
@@ -301,6 +335,23 @@ const (
 	ArgSSHCommand = "ssh-command"
 	// ArgSSHRetryMax is a ssh argument.
 	ArgSSHRetryMax = "ssh-retry-max"
+	// ArgSSHLocalForward is a ssh local port forwarding argument.
+	ArgSSHLocalForward = "ssh-local-forward"
+	// ArgSSHRemoteForward is a ssh remote port forwarding argument.
+	ArgSSHRemoteForward = "ssh-remote-forward"
+	// ArgSSHBastion is a ssh bastion Droplet argument.
+	ArgSSHBastion = "ssh-bastion"
+	// ArgSSHHostKey is a ssh host key fingerprint argument.
+	ArgSSHHostKey = "ssh-host-key"
+	// ArgSSHAcceptNew adds unknown host keys to the known hosts file without
+	// asking for confirmation.
+	ArgSSHAcceptNew = "ssh-accept-new"
+	// ArgSSHBinary runs the ssh or scp binary instead of the built-in client.
+	ArgSSHBinary = "ssh-binary"
+	// ArgSSHParallel is the number of Droplets a ssh command runs on at once.
+	ArgSSHParallel = "ssh-parallel"
+	// ArgRecursive is a recursive copy argument.
+	ArgRecursive = "recursive"
 	// ArgUserData is a user data argument.
 	ArgUserData = "user-data"
 	// ArgUserDataFile is a user data file location argument.
@@ -341,6 +392,8 @@ const (
 	ArgTag = "tag"
 	//ArgTemplate is template format
 	ArgTemplate = "template"
+	// ArgTemplateFile is the path to a file containing a Go template used to render output.
+	ArgTemplateFile = "template-file"
 	// ArgTimeout is a timeout duration
 	ArgTimeout = "timeout"
 	// ArgTriggerDeployment indicates whether to trigger a deployment
@@ -465,6 +518,10 @@ const (
 	ArgLoadBalancerTLSCipherPolicy = "tls-cipher-policy"
 	// ArgLoadBalancerIP is an optional BYOIP address to assign on load balancer create.
 	ArgLoadBalancerIP = "ip"
+	// ArgLoadBalancerSpec is a path to a load balancer spec in YAML or JSON.
+	ArgLoadBalancerSpec = "spec"
+	// ArgLoadBalancerSpecOutput is the format a load balancer spec is written in.
+	ArgLoadBalancerSpecOutput = "spec-output"
 
 	// ArgFirewallName is a name of the firewall.
 	ArgFirewallName = "name"
@@ -472,6 +529,16 @@ const (
 	ArgInboundRules = "inbound-rules"
 	// ArgOutboundRules is a list of outbound rules for the firewall.
 	ArgOutboundRules = "outbound-rules"
+	// ArgFirewallCheckDroplet is the Droplet whose firewalls are checked.
+	ArgFirewallCheckDroplet = "droplet"
+	// ArgFirewallCheckFrom is the address inbound traffic is checked from.
+	ArgFirewallCheckFrom = "from"
+	// ArgFirewallCheckTo is the address outbound traffic is checked to.
+	ArgFirewallCheckTo = "to"
+	// ArgFirewallCheckPort is the port traffic is checked on.
+	ArgFirewallCheckPort = "port"
+	// ArgFirewallCheckProtocol is the protocol of the traffic checked.
+	ArgFirewallCheckProtocol = "protocol"
 
 	// ArgProjectID is the ID of a project.
 	ArgProjectID = "project-id"
@@ -954,4 +1021,29 @@ const (
 	ArgInferenceText = "text"
 	// ArgInferenceSecondsTotal is the audio duration in seconds for async audio generation.
 	ArgInferenceSecondsTotal = "seconds-total"
+
+	// Stack Args
+
+	// ArgStackFile is the path of a stack file.
+	ArgStackFile = "file"
+	// ArgStackPrune deletes the resources missing from a stack.
+	ArgStackPrune = "prune"
+	// ArgDryRun shows changes without making them.
+	ArgDryRun = "dry-run"
+
+	// Inventory Args
+
+	// ArgInventoryFile is the file whose managed block an inventory is written to.
+	ArgInventoryFile = "file"
+	// ArgInventoryGroupBy is the Droplet attributes to group an inventory by.
+	ArgInventoryGroupBy = "group-by"
+
+	// Rollout Args
+
+	// ArgRolloutLoadBalancer is the load balancer Droplets are drained from during a rollout.
+	ArgRolloutLoadBalancer = "lb"
+	// ArgRolloutMaxUnavailable is how many Droplets a rollout replaces at once.
+	ArgRolloutMaxUnavailable = "max-unavailable"
+	// ArgRolloutHealthURL is the URL checked before a rebuilt Droplet is put back in service.
+	ArgRolloutHealthURL = "health-url"
 )
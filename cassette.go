@@ -0,0 +1,228 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces sensitive values written to traces and cassettes.
+const redactedValue = "REDACTED"
+
+// cassette is a file holding recorded HTTP interactions.
+type cassette struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// interaction is a recorded request and the response it received.
+type interaction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+
+	used bool
+}
+
+type cassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// cassetteRecorder is a http.RoundTripper saving every request it sends, and
+// the response received, to a cassette file. Sensitive headers and body
+// fields are masked before they are saved.
+type cassetteRecorder struct {
+	wrap   http.RoundTripper
+	path   string
+	redact *redactor
+
+	mu       sync.Mutex
+	cassette cassette
+}
+
+func newCassetteRecorder(transport http.RoundTripper, path string) *cassetteRecorder {
+	return &cassetteRecorder{
+		wrap:   transport,
+		path:   path,
+		redact: defaultRedactor,
+	}
+}
+
+func (rec *cassetteRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: reading request body, %v", err)
+	}
+
+	resp, err := rec.wrap.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: reading response body, %v", err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.cassette.Interactions = append(rec.cassette.Interactions, &interaction{
+		Request: cassetteRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: redactHeaders(req.Header),
+			Body:    rec.redact.body(reqBody),
+		},
+		Response: cassetteResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       rec.redact.body(respBody),
+		},
+	})
+
+	// the cassette is written after every interaction as doctl may exit
+	// without returning control to the caller.
+	if err := rec.save(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (rec *cassetteRecorder) save() error {
+	b, err := json.MarshalIndent(rec.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(rec.path, b, 0600); err != nil {
+		return fmt.Errorf("cassette: writing %s, %v", rec.path, err)
+	}
+
+	return nil
+}
+
+// cassettePlayer is a http.RoundTripper serving responses from a cassette
+// file without sending any request over the network.
+type cassettePlayer struct {
+	mu       sync.Mutex
+	cassette cassette
+	last     map[string]*interaction
+}
+
+func newCassettePlayer(path string) (*cassettePlayer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: reading %s, %v", path, err)
+	}
+
+	player := &cassettePlayer{last: map[string]*interaction{}}
+	if err := json.Unmarshal(b, &player.cassette); err != nil {
+		return nil, fmt.Errorf("cassette: parsing %s, %v", path, err)
+	}
+
+	return player, nil
+}
+
+// RoundTrip returns the first unplayed interaction recorded for the method
+// and URL of the request. Once every matching interaction was played, the
+// last one is repeated so polling requests keep receiving a response.
+func (p *cassettePlayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := req.Method + " " + req.URL.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	match := p.last[key]
+	for _, i := range p.cassette.Interactions {
+		if !i.used && i.Request.Method+" "+i.Request.URL == key {
+			i.used = true
+			match = i
+			break
+		}
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("cassette: no recorded response for %s", key)
+	}
+	p.last[key] = match
+
+	header := match.Response.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.Response.StatusCode, http.StatusText(match.Response.StatusCode)),
+		StatusCode:    match.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(match.Response.Body)),
+		ContentLength: int64(len(match.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads a request or response body and replaces it with a copy so
+// it can be read again.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	b, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func redactHeaders(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	out := h.Clone()
+	for _, name := range sensitiveHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, redactedValue)
+		}
+	}
+
+	return out
+}
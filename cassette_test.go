@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo":` + string(body) + `,"password":"hunter2"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recordClient := &http.Client{Transport: newCassetteRecorder(http.DefaultTransport, path)}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v2/droplets", strings.NewReader(`{"name":"web-1","token":"dop_v1_body"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer dop_v1_secret")
+
+	resp, err := recordClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"echo":{"name":"web-1","token":"dop_v1_body"},"password":"hunter2"}`, string(body))
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(saved), "dop_v1_secret")
+	assert.NotContains(t, string(saved), "session=secret")
+	assert.NotContains(t, string(saved), "dop_v1_body")
+	assert.NotContains(t, string(saved), "hunter2")
+	assert.Contains(t, string(saved), `"REDACTED"`)
+
+	player, err := newCassettePlayer(path)
+	require.NoError(t, err)
+	replayClient := &http.Client{Transport: player}
+
+	for i := 0; i < 2; i++ {
+		resp, err = replayClient.Post(server.URL+"/v2/droplets", "application/json", strings.NewReader(`{"name":"web-1"}`))
+		require.NoError(t, err)
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, `{"echo":{"name":"web-1","token":"REDACTED"},"password":"REDACTED"}`, string(body))
+	}
+	assert.Equal(t, 1, calls)
+
+	_, err = replayClient.Get(server.URL + "/v2/account")
+	assert.ErrorContains(t, err, "cassette: no recorded response for GET "+server.URL+"/v2/account")
+}
+
+func TestCassettePlayerOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	err := os.WriteFile(path, []byte(`{"interactions": [
+		{"request": {"method": "GET", "url": "https://api.digitalocean.com/v2/actions/1"}, "response": {"status_code": 200, "body": "in-progress"}},
+		{"request": {"method": "GET", "url": "https://api.digitalocean.com/v2/actions/1"}, "response": {"status_code": 200, "body": "completed"}}
+	]}`), 0600)
+	require.NoError(t, err)
+
+	player, err := newCassettePlayer(path)
+	require.NoError(t, err)
+	client := &http.Client{Transport: player}
+
+	for _, expected := range []string{"in-progress", "completed", "completed"} {
+		resp, err := client.Get("https://api.digitalocean.com/v2/actions/1")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	}
+}
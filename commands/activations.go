@@ -14,6 +14,7 @@ limitations under the License.
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -70,7 +71,7 @@ invoked functions.`,
 		aliasOpt("ls"),
 		displayerType(&displayers.Activation{}),
 	)
-	AddIntFlag(list, "limit", "l", 30, "Limit the number of activations returned to the specified amount. Default: 30, Maximum: 200")
+	AddIntFlag(list, "limit", "l", 30, "Limit the number of activations returned to the specified amount. Default: 30. Set to 0 to list every activation")
 	AddIntFlag(list, "skip", "s", 0, "Exclude a specified number of activations from the returned list, starting with the most recent.")
 	AddIntFlag(list, "since", "", 0, "Retrieve activations invoked after the specified date-time, in UNIX timestamp format measured in milliseconds.")
 	AddIntFlag(list, "upto", "", 0, "Retrieve activations invoked before the specified date-time; in UNIX timestamp format measured in milliseconds.")
@@ -261,11 +262,6 @@ func RunActivationsList(c *CmdConfig) error {
 	upToFlag, _ := c.Doit.GetInt(c.NS, flagUpto)
 	limitFlag, _ := c.Doit.GetInt(c.NS, flagLimit)
 
-	limit := limitFlag
-	if limitFlag > 200 {
-		limit = 200
-	}
-
 	if countFlags {
 		options := whisk.ActivationCountOptions{Since: int64(sinceFlag), Upto: int64(upToFlag), Name: name}
 		count, err := sls.GetActivationCount(options)
@@ -281,19 +277,20 @@ func RunActivationsList(c *CmdConfig) error {
 		return nil
 	}
 
-	options := whisk.ActivationListOptions{Limit: limit, Skip: skipFlag, Since: int64(sinceFlag), Upto: int64(upToFlag), Docs: fullFlag, Name: name}
-
-	actv, err := sls.ListActivations(options)
-	if err != nil {
-		return err
-	}
+	options := whisk.ActivationListOptions{Skip: skipFlag, Since: int64(sinceFlag), Upto: int64(upToFlag), Docs: fullFlag, Name: name}
+	it := sls.ActivationsIter(context.TODO(), options).Limit(limitFlag)
 
-	items := &displayers.Activation{Activations: actv}
 	if fullFlag {
-		return items.JSON(c.Out)
+		actv, err := do.Collect(it)
+		if err != nil {
+			return err
+		}
+		return (&displayers.Activation{Activations: actv}).JSON(c.Out)
 	}
 
-	return c.Display(items)
+	return displayPages(c, it, func(actv []whisk.Activation) displayers.Displayable {
+		return &displayers.Activation{Activations: actv}
+	})
 }
 
 // RunActivationsLogs supports the 'activations logs' command
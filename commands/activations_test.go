@@ -15,6 +15,7 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -22,8 +23,12 @@ import (
 	"time"
 
 	"github.com/apache/openwhisk-client-go/whisk"
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func TestActivationsCommand(t *testing.T) {
@@ -354,7 +359,6 @@ func TestActivationsList(t *testing.T) {
 				}
 
 				count := false
-				var limit any
 				var since any
 				var upto any
 				var skip any
@@ -365,10 +369,6 @@ func TestActivationsList(t *testing.T) {
 							count = true
 						}
 
-						if k == "limit" {
-							limit, _ = strconv.Atoi(v)
-						}
-
 						if k == "since" {
 							since, _ = strconv.ParseInt(v, 0, 64)
 						}
@@ -413,14 +413,11 @@ func TestActivationsList(t *testing.T) {
 					if len(config.Args) == 1 {
 						expectedListOptions.Name = config.Args[0]
 					}
-					if limit != nil {
-						expectedListOptions.Limit = limit.(int)
-					}
 
 					if skip != nil {
 						expectedListOptions.Skip = skip.(int)
 					}
-					tm.serverless.EXPECT().ListActivations(expectedListOptions).Return(theActivations, nil)
+					tm.serverless.EXPECT().ActivationsIter(gomock.Any(), expectedListOptions).Return(activationPages(nil))
 				}
 
 				err := RunActivationsList(config)
@@ -430,6 +427,38 @@ func TestActivationsList(t *testing.T) {
 	}
 }
 
+// activationPages iterates over theActivations, recording the pages fetched.
+func activationPages(fetched *[]int) *do.Iterator[whisk.Activation] {
+	return do.NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]whisk.Activation, *godo.Response, error) {
+		if fetched != nil {
+			*fetched = append(*fetched, opt.Page)
+		}
+		start := min((opt.Page-1)*opt.PerPage, len(theActivations))
+		return theActivations[start:min(start+opt.PerPage, len(theActivations))], nil, nil
+	})
+}
+
+func TestActivationsListPages(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		require.NoError(t, do.SetPaginationConfig(do.PaginationConfig{PageSize: 2}))
+		defer do.SetPaginationConfig(do.PaginationConfig{})
+
+		var fetched []int
+		tm.serverless.EXPECT().ActivationsIter(gomock.Any(), whisk.ActivationListOptions{}).Return(activationPages(&fetched))
+
+		buf := &bytes.Buffer{}
+		config.Out = buf
+		config.Doit.Set(config.NS, "limit", 3)
+		config.Doit.Set(config.NS, doctl.ArgFormat, "ActivationID")
+		config.Doit.Set(config.NS, doctl.ArgNoHeader, true)
+
+		err := RunActivationsList(config)
+		require.NoError(t, err)
+		assert.Equal(t, "activation-1\nactivation-2\nactivation-3\n", buf.String())
+		assert.Equal(t, []int{1, 2}, fetched)
+	})
+}
+
 func TestActivationsLogs(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+)
+
+// Apply creates the apply command and adds it to parent, so that the config
+// namespace of its flags matches the one it reads them from.
+func Apply(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunApply, "apply", "Create, update or delete resources to match a stack file", `Reads a stack file describing VPCs, volumes, Droplets, cloud firewalls, load balancers and domains, compares each resource to what exists in your account, and creates or updates only what differs.
+
+A stack file is a multi-document YAML file. Each document has a `+"`"+`kind`+"`"+` (`+"`"+`vpc`+"`"+`, `+"`"+`volume`+"`"+`, `+"`"+`droplet`+"`"+`, `+"`"+`firewall`+"`"+`, `+"`"+`load_balancer`+"`"+` or `+"`"+`domain`+"`"+`), a `+"`"+`name`+"`"+` identifying the resource, and a `+"`"+`spec`+"`"+`:
+
+    kind: droplet
+    name: web-1
+    spec:
+      region: nyc3
+      size: s-1vcpu-1gb
+      image: ubuntu-24-04-x64
+      tags: [web]
+    ---
+    kind: firewall
+    name: web
+    spec:
+      tags: [web]
+      inbound_rules:
+        - protocol: tcp
+          ports: "443"
+          sources:
+            addresses: [0.0.0.0/0, ::/0]
+    ---
+    kind: load_balancer
+    name: web
+    spec:
+      region: nyc3
+      tag: web
+      forwarding_rules:
+        - entry_protocol: https
+          entry_port: 443
+          target_protocol: http
+          target_port: 80
+          certificate_id: 892071a0-bb95-49bc-8021-3afd67a210bf
+    ---
+    kind: domain
+    name: example.com
+    spec:
+      records:
+        - type: A
+          name: www
+          data: 203.0.113.10
+
+A stack file may also be a JSON list of documents, as written by `+"`"+`doctl export --output json`+"`"+`.
+
+The spec of a firewall or load balancer uses the fields of the API request creating it. Droplets and load balancers can reference a VPC by name with `+"`"+`vpc`+"`"+`, and firewalls and load balancers their Droplets by name with `+"`"+`droplets`+"`"+`, instead of by ID with `+"`"+`vpc_uuid`+"`"+` and `+"`"+`droplet_ids`+"`"+`. Only the fields present in a spec are compared to the existing resource. A Droplet can only be updated in place by changing its tags, and a VPC by changing its description; other differences, such as the size or region of a Droplet or a volume, are reported as drift. Domain records are identified by their type, name and data.
+
+With `+"`"+`--tag`+"`"+`, the tag is added to the volumes, Droplets and load balancers created by apply. With `+"`"+`--prune`+"`"+`, volumes, Droplets and load balancers carrying the tag, and firewalls applied to it, are deleted when the stack file does not declare them. VPCs and domains are never deleted, but `+"`"+`--prune`+"`"+` deletes the records of a declared domain that are missing from its spec, except for its SOA and NS records.`, Writer, displayerType(&displayers.StackChanges{}))
+	cmd.GroupID = manageResourcesGroup
+	AddStringFlag(cmd, doctl.ArgStackFile, "f", "", "Path to the stack file in YAML format. Set to - to read from stdin", requiredOpt())
+	AddBoolFlag(cmd, doctl.ArgDryRun, "", false, "Display the changes without making them")
+	AddBoolFlag(cmd, doctl.ArgStackPrune, "", false, "Delete the resources missing from the stack file. Only resources carrying the tag set with --tag and the records of declared domains are deleted")
+	AddStringFlag(cmd, doctl.ArgTag, "", "", "A tag added to the volumes, Droplets and load balancers created, and used to find the resources to delete with --prune")
+	AddBoolFlag(cmd, doctl.ArgForce, "", false, "Delete resources without a confirmation prompt")
+	cmd.Example = `The following example displays the changes needed for your account to match ` + "`" + `stack.yaml` + "`" + `: doctl apply -f stack.yaml --dry-run`
+
+	return cmd
+}
+
+// RunApply creates, updates and deletes resources to match a stack file.
+func RunApply(c *CmdConfig) error {
+	path, err := c.Doit.GetString(c.NS, doctl.ArgStackFile)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := c.Doit.GetBool(c.NS, doctl.ArgDryRun)
+	if err != nil {
+		return err
+	}
+
+	prune, err := c.Doit.GetBool(c.NS, doctl.ArgStackPrune)
+	if err != nil {
+		return err
+	}
+
+	tag, err := c.Doit.GetString(c.NS, doctl.ArgTag)
+	if err != nil {
+		return err
+	}
+
+	force, err := c.Doit.GetBool(c.NS, doctl.ArgForce)
+	if err != nil {
+		return err
+	}
+
+	resources, err := readStack(os.Stdin, path)
+	if err != nil {
+		return err
+	}
+
+	changes, err := newStackPlanner(c, tag, prune).plan(resources)
+	if err != nil {
+		return err
+	}
+
+	item := &displayers.StackChanges{}
+	for _, change := range changes {
+		item.Changes = append(item.Changes, change.StackChange)
+	}
+
+	if dryRun {
+		return c.Display(item)
+	}
+
+	deletes := 0
+	for _, change := range changes {
+		if change.Action == stackActionDelete {
+			deletes++
+		}
+	}
+	if deletes > 0 && !force && AskForConfirmDelete("resource", deletes) != nil {
+		return errOperationAborted
+	}
+
+	for _, change := range changes {
+		if change.apply == nil {
+			continue
+		}
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("%s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+
+	return c.Display(item)
+}
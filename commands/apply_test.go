@@ -0,0 +1,335 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testStack = `
+# web tier
+kind: droplet
+name: web-1
+spec:
+  region: nyc3
+  size: s-1vcpu-1gb
+  image: ubuntu-24-04-x64
+  tags: [web]
+---
+kind: droplet
+name: web-2
+spec:
+  region: nyc3
+  size: s-1vcpu-1gb
+  image: ubuntu-24-04-x64
+  tags: [web]
+---
+kind: firewall
+name: web
+spec:
+  tags: [web]
+  inbound_rules:
+    - protocol: tcp
+      ports: "443"
+      sources:
+        addresses: [0.0.0.0/0]
+---
+kind: load_balancer
+name: web
+spec:
+  region: nyc3
+  tag: web
+---
+kind: domain
+name: example.com
+spec:
+  records:
+    - type: A
+      name: www
+      data: 203.0.113.10
+      ttl: 300
+    - type: TXT
+      name: "@"
+      data: v=spf1 -all
+`
+
+func writeTestStack(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "stack.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestApplyCommand(t *testing.T) {
+	cmd := Apply(&Command{Command: &cobra.Command{Use: "doctl"}})
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "apply", cmd.Name())
+	assert.Equal(t, cmdNS(cmd)+"."+doctl.ArgStackFile, flagName(cmd, doctl.ArgStackFile))
+}
+
+func TestParseStack(t *testing.T) {
+	resources, err := parseStack([]byte(testStack))
+	require.NoError(t, err)
+	require.Len(t, resources, 5)
+	assert.Equal(t, "droplet", resources[0].Kind)
+	assert.Equal(t, "web-1", resources[0].Name)
+	assert.Equal(t, "example.com", resources[4].Name)
+
+	_, err = parseStack([]byte("kind: database\nname: data\n"))
+	assert.EqualError(t, err, `stack document 1: unsupported kind "database", must be one of: vpc, volume, droplet, firewall, load_balancer, domain`)
+
+	_, err = parseStack([]byte("kind: droplet\nname: a\n---\nkind: droplet\nname: a\n"))
+	assert.EqualError(t, err, "stack document 2: droplet a is declared more than once")
+
+	_, err = parseStack([]byte("kind: droplet\nname: a\nsize: s-1vcpu-1gb\n"))
+	assert.Error(t, err)
+
+	resources, err = parseStack([]byte(`[{"kind": "vpc", "name": "private"}, {"kind": "droplet", "name": "a", "spec": {"vpc": "private"}}]`))
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "{}", string(resources[0].Spec))
+	assert.Equal(t, "a", resources[1].Name)
+
+	_, err = parseStack([]byte(`[{"kind": "droplet", "name": "a"}, {"kind": "droplet", "name": "a"}]`))
+	assert.EqualError(t, err, "stack document 1 item 2: droplet a is declared more than once")
+}
+
+func liveStack() (do.Droplets, do.Firewalls, do.LoadBalancers, do.DomainRecords) {
+	droplets := do.Droplets{
+		{Droplet: &godo.Droplet{
+			ID:       1,
+			Name:     "web-1",
+			SizeSlug: "s-2vcpu-2gb",
+			Region:   &godo.Region{Slug: "nyc3"},
+			Image:    &godo.Image{Slug: "ubuntu-24-04-x64"},
+			Tags:     []string{"web", "old"},
+		}},
+	}
+
+	firewalls := do.Firewalls{
+		{Firewall: &godo.Firewall{
+			ID:   "fw-1",
+			Name: "web",
+			Tags: []string{"web"},
+			InboundRules: []godo.InboundRule{
+				{Protocol: "tcp", PortRange: "80", Sources: &godo.Sources{Addresses: []string{"0.0.0.0/0"}}},
+			},
+			OutboundRules: []godo.OutboundRule{
+				{Protocol: "tcp", PortRange: "0", Destinations: &godo.Destinations{Addresses: []string{"0.0.0.0/0"}}},
+			},
+		}},
+	}
+
+	lbs := do.LoadBalancers{
+		{LoadBalancer: &godo.LoadBalancer{
+			ID:     "lb-1",
+			Name:   "web",
+			Region: &godo.Region{Slug: "nyc3"},
+			Tag:    "web",
+		}},
+	}
+
+	records := do.DomainRecords{
+		{DomainRecord: &godo.DomainRecord{ID: 10, Type: "SOA", Name: "@", Data: "1800"}},
+		{DomainRecord: &godo.DomainRecord{ID: 11, Type: "NS", Name: "@", Data: "ns1.digitalocean.com"}},
+		{DomainRecord: &godo.DomainRecord{ID: 12, Type: "A", Name: "www", Data: "203.0.113.10", TTL: 3600}},
+		{DomainRecord: &godo.DomainRecord{ID: 13, Type: "A", Name: "old", Data: "203.0.113.11", TTL: 3600}},
+	}
+
+	return droplets, firewalls, lbs, records
+}
+
+func TestRunApplyDryRun(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		droplets, firewalls, lbs, records := liveStack()
+
+		tm.droplets.EXPECT().List().Return(droplets, nil)
+		tm.firewalls.EXPECT().List().Return(firewalls, nil)
+		tm.loadBalancers.EXPECT().List().Return(lbs, nil)
+		tm.domains.EXPECT().List().Return(do.Domains{{Domain: &godo.Domain{Name: "example.com"}}}, nil)
+		tm.domains.EXPECT().Records("example.com").Return(records, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgStackFile, writeTestStack(t, testStack))
+		config.Doit.Set(config.NS, doctl.ArgDryRun, true)
+
+		err := RunApply(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind             Name                 Changes
+drift     droplet          web-1                size: s-2vcpu-2gb -> s-1vcpu-1gb
+update    droplet          web-1                tags: ["web","old"] -> ["web"]
+create    droplet          web-2                
+update    firewall         web                  inbound_rules: [{"ports":"80","protocol":"tcp","sources":{"addresses":["0.0.0.0/0"]}}] -> [{"ports":"443","protocol":"tcp","sources":{"addresses":["0.0.0.0/0"]}}]
+update    domain_record    example.com A www    ttl: 3600 -> 300
+create    domain_record    example.com TXT @    data: v=spf1 -all
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRunApplyPrune(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		droplets, firewalls, lbs, records := liveStack()
+		stale := do.Droplet{Droplet: &godo.Droplet{ID: 3, Name: "web-3", Tags: []string{"web"}}}
+		firewalls = append(firewalls, do.Firewall{Firewall: &godo.Firewall{ID: "fw-2", Name: "legacy", Tags: []string{"web"}}})
+		lbs = append(lbs, do.LoadBalancer{LoadBalancer: &godo.LoadBalancer{ID: "lb-2", Name: "other", Tags: []string{"team"}}})
+
+		tm.volumes.EXPECT().List().Return([]do.Volume{}, nil)
+		tm.droplets.EXPECT().List().Return(append(droplets, stale), nil)
+		tm.droplets.EXPECT().ListByTag("web").Return(append(droplets, stale), nil)
+		tm.firewalls.EXPECT().List().Return(firewalls, nil)
+		tm.loadBalancers.EXPECT().List().Return(lbs, nil)
+		tm.domains.EXPECT().List().Return(do.Domains{{Domain: &godo.Domain{Name: "example.com"}}}, nil)
+		tm.domains.EXPECT().Records("example.com").Return(records, nil)
+
+		resource := godo.Resource{ID: "1", Type: godo.DropletResourceType}
+		tm.tags.EXPECT().UntagResources("old", &godo.UntagResourcesRequest{Resources: []godo.Resource{resource}}).Return(nil)
+		tm.droplets.EXPECT().Create(gomock.Any(), false).DoAndReturn(func(dcr *godo.DropletCreateRequest, wait bool) (*do.Droplet, error) {
+			assert.Equal(t, "web-2", dcr.Name)
+			assert.Equal(t, godo.DropletCreateImage{Slug: "ubuntu-24-04-x64"}, dcr.Image)
+			assert.Equal(t, []string{"web"}, dcr.Tags)
+			return &do.Droplet{Droplet: &godo.Droplet{ID: 2, Name: "web-2"}}, nil
+		})
+		tm.firewalls.EXPECT().Update("fw-1", &godo.FirewallRequest{
+			Name: "web",
+			Tags: []string{"web"},
+			InboundRules: []godo.InboundRule{
+				{Protocol: "tcp", PortRange: "443", Sources: &godo.Sources{Addresses: []string{"0.0.0.0/0"}}},
+			},
+			OutboundRules: firewalls[0].OutboundRules,
+		}).Return(&firewalls[0], nil)
+		tm.domains.EXPECT().EditRecord("example.com", 12, gomock.Any()).Return(&records[2], nil)
+		tm.domains.EXPECT().CreateRecord("example.com", &do.DomainRecordEditRequest{Type: "TXT", Name: "@", Data: "v=spf1 -all"}).Return(&records[2], nil)
+		tm.domains.EXPECT().DeleteRecord("example.com", 13).Return(nil)
+		tm.firewalls.EXPECT().Delete("fw-2").Return(nil)
+		tm.droplets.EXPECT().Delete(3).Return(nil)
+
+		config.Doit.Set(config.NS, doctl.ArgStackFile, writeTestStack(t, testStack))
+		config.Doit.Set(config.NS, doctl.ArgStackPrune, true)
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunApply(config)
+		require.NoError(t, err)
+	})
+}
+
+func TestSpecMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired any
+		live    any
+		match   bool
+	}{
+		{"equal strings", "nyc3", "nyc3", true},
+		{"different strings", "nyc3", "sfo3", false},
+		{"unset fields are ignored", map[string]any{"a": 1.0}, map[string]any{"a": 1.0, "b": 2.0}, true},
+		{"lists ignore order", []any{"a", "b"}, []any{"b", "a"}, true},
+		{"lists compare length", []any{"a"}, []any{"a", "b"}, false},
+		{"empty list matches missing", []any{}, nil, true},
+		{"all ports", map[string]any{"ports": "all"}, map[string]any{"ports": "0"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, specMatches("", tt.desired, tt.live))
+		})
+	}
+}
+
+func TestRunApplyAmbiguousNames(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.firewalls.EXPECT().List().Return(do.Firewalls{
+			{Firewall: &godo.Firewall{ID: "fw-1", Name: "web"}},
+			{Firewall: &godo.Firewall{ID: "fw-2", Name: "web"}},
+		}, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgStackFile, writeTestStack(t, "kind: firewall\nname: web\nspec:\n  tags: [web]\n"))
+		config.Doit.Set(config.NS, doctl.ArgDryRun, true)
+
+		err := RunApply(config)
+		assert.EqualError(t, err, "firewall web: more than one firewall has this name")
+	})
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.loadBalancers.EXPECT().List().Return(do.LoadBalancers{
+			{LoadBalancer: &godo.LoadBalancer{ID: "lb-1", Name: "web"}},
+			{LoadBalancer: &godo.LoadBalancer{ID: "lb-2", Name: "web"}},
+		}, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgStackFile, writeTestStack(t, "kind: load_balancer\nname: web\nspec:\n  region: nyc3\n"))
+		config.Doit.Set(config.NS, doctl.ArgDryRun, true)
+
+		err := RunApply(config)
+		assert.EqualError(t, err, "load balancer web: more than one load balancer has this name")
+	})
+}
+
+func TestRecordKey(t *testing.T) {
+	assert.Equal(t, recordKey("cname", "WWW", "Example.com."), recordKey("CNAME", "www", "example.com"))
+	assert.Equal(t, recordKey("MX", "@", "Mail.Example.com."), recordKey("MX", "", "mail.example.com"))
+	assert.NotEqual(t, recordKey("TXT", "@", "v=DKIM1; p=ABC"), recordKey("TXT", "@", "v=dkim1; p=abc"))
+	assert.NotEqual(t, recordKey("TXT", "@", "trailing."), recordKey("TXT", "@", "trailing"))
+}
+
+func TestRunApplyReferencesByName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.vpcs.EXPECT().List().Return(do.VPCs{}, nil)
+		tm.vpcs.EXPECT().Create(&godo.VPCCreateRequest{Name: "private", RegionSlug: "nyc3"}).
+			Return(&do.VPC{VPC: &godo.VPC{ID: "vpc-1", Name: "private"}}, nil)
+		tm.droplets.EXPECT().List().Return(do.Droplets{}, nil)
+		tm.droplets.EXPECT().Create(gomock.Any(), false).DoAndReturn(func(dcr *godo.DropletCreateRequest, wait bool) (*do.Droplet, error) {
+			assert.Equal(t, "vpc-1", dcr.VPCUUID)
+			return &do.Droplet{Droplet: &godo.Droplet{ID: 7, Name: "db-1"}}, nil
+		})
+		tm.firewalls.EXPECT().List().Return(do.Firewalls{}, nil)
+		tm.firewalls.EXPECT().Create(&godo.FirewallRequest{Name: "db", DropletIDs: []int{7}}).Return(&do.Firewall{}, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgStackFile, writeTestStack(t, `
+kind: vpc
+name: private
+spec:
+  region: nyc3
+---
+kind: droplet
+name: db-1
+spec:
+  region: nyc3
+  size: s-1vcpu-1gb
+  image: ubuntu-24-04-x64
+  vpc: private
+---
+kind: firewall
+name: db
+spec:
+  droplets: [db-1]
+`))
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunApply(config)
+		require.NoError(t, err)
+	})
+}
@@ -100,7 +100,9 @@ If you work with a just one account, call ` + "`" + `doctl auth init` + "`" + `
 
 To switch between multiple DigitalOcean accounts, including team accounts, create named contexts using ` + "`" + `doctl auth init --context <name>` + "`" + `, then providing the applicable token when prompted. This saves the token under the name you provide. To switch between contexts, use ` + "`" + `doctl auth switch --context <name>` + "`" + `.
 
-To remove accounts from the configuration file, run ` + "`" + `doctl auth remove --context <name>` + "`" + `. This removes the token under the name you provide.`,
+To remove accounts from the configuration file, run ` + "`" + `doctl auth remove --context <name>` + "`" + `. This removes the token under the name you provide.
+
+To bundle a context with other settings, such as the API URL or per-command defaults, define a profile. See ` + "`" + `doctl auth profile --help` + "`" + `.`,
 			GroupID: configureDoctlGroup,
 		},
 	}
@@ -113,7 +115,15 @@ The `+"`"+`--context`+"`"+` flag allows you to add authentication for multiple a
 
 If the `+"`"+`--context`+"`"+` flag is not specified, doctl creates a default authentication context named `+"`"+`default`+"`"+`.
 
-You can use doctl without initializing it by adding the `+"`"+`--access-token`+"`"+` flag to each command and providing an API token as the argument.`, Writer, false)
+You can use doctl without initializing it by adding the `+"`"+`--access-token`+"`"+` flag to each command and providing an API token as the argument.
+
+By default, tokens are saved in plain text in the configuration file. To keep them out of it, set `+"`"+`credential-helper`+"`"+` in the configuration file, or use the `+"`"+`--credential-helper`+"`"+` flag or the `+"`"+`DIGITALOCEAN_CREDENTIAL_HELPER`+"`"+` environment variable, to one of:
+
+- `+"`"+`secret-service`+"`"+`: stores tokens with the Secret Service D-Bus API, e.g. GNOME Keyring or KWallet, using the `+"`"+`secret-tool`+"`"+` command from libsecret.
+- `+"`"+`file`+"`"+`: stores tokens in a file encrypted with a passphrase, read from the `+"`"+`DIGITALOCEAN_CREDENTIALS_PASSPHRASE`+"`"+` environment variable or prompted for.
+- the name of an external helper, such as `+"`"+`vault`+"`"+` for a `+"`"+`doctl-credential-vault`+"`"+` executable in your PATH, or the path of a helper executable. Like git credential helpers, the helper is run with `+"`"+`get`+"`"+`, `+"`"+`store`+"`"+` or `+"`"+`erase`+"`"+` as its last argument and reads `+"`"+`context=<name>`+"`"+` and, when storing, `+"`"+`token=<token>`+"`"+` lines from its standard input. On `+"`"+`get`+"`"+`, it prints a `+"`"+`token=<token>`+"`"+` line.
+
+Only the names of the contexts are then saved in the configuration file.`, Writer, false)
 	AddStringFlag(cmdAuthInit, doctl.ArgTokenValidationServer, "", TokenValidationServer, "The server used to validate a token")
 	cmdAuthInit.Example = `The following example initializes doctl with a token for a single account with the context ` + "`" + `your-team` + "`" + `: doctl auth init --context your-team`
 
@@ -151,6 +161,8 @@ To switch between the contexts use `+"`"+`doctl auth switch --context <name>`+"`
 To create new contexts, see the help for `+"`"+`doctl auth init`+"`"+`.`, Writer, false, aliasOpt("t"))
 	cmdAuthToken.Example = `The following example displays the token of the current context: doctl auth token`
 
+	cmd.AddCommand(authProfile())
+
 	return cmd
 }
 
@@ -174,7 +186,9 @@ func RunAuthInit(retrieveUserTokenFunc func() (string, error)) func(c *CmdConfig
 			template.Render(c.Out, `Using token for context {{highlight .}}{{nl}}`, context)
 		}
 
-		c.setContextAccessToken(token)
+		if err := c.setContextAccessToken(token); err != nil {
+			return fmt.Errorf("Unable to store DigitalOcean access token: %s", err)
+		}
 
 		template.Render(c.Out, `{{nl}}Validating token... `, nil)
 
@@ -210,7 +224,7 @@ func RunAuthRemove(c *CmdConfig) error {
 	err := c.removeContext(context)
 
 	if err != nil {
-		return fmt.Errorf("Context not found")
+		return err
 	}
 
 	fmt.Println("Context deleted successfully")
@@ -338,7 +352,7 @@ func writeConfig() error {
 
 	defer f.Close()
 
-	b, err := yaml.Marshal(viper.AllSettings())
+	b, err := yaml.Marshal(configSettings(viper.GetViper(), activeProfile))
 	if err != nil {
 		return errors.New("Unable to encode configuration to YAML format.")
 	}
@@ -36,7 +36,7 @@ type CmdConfig struct {
 
 	initServices            func(*CmdConfig) error
 	getContextAccessToken   func() string
-	setContextAccessToken   func(string)
+	setContextAccessToken   func(string) error
 	removeContext           func(string) error
 	componentBuilderFactory builder.ComponentBuilderFactory
 
@@ -109,6 +109,14 @@ func NewCmdConfig(ns string, dc doctl.Config, out io.Writer, args []string, init
 				return fmt.Errorf("Unable to initialize DigitalOcean API client: %s", err)
 			}
 
+			err = do.SetPaginationConfig(do.PaginationConfig{
+				PageSize:    viper.GetInt("page-size"),
+				PageRetries: viper.GetInt("page-retries"),
+			})
+			if err != nil {
+				return err
+			}
+
 			c.Keys = func() do.KeysService { return do.NewKeysService(godoClient) }
 			c.Sizes = func() do.SizesService { return do.NewSizesService(godoClient) }
 			c.Regions = func() do.RegionsService { return do.NewRegionsService(godoClient) }
@@ -174,57 +182,25 @@ func NewCmdConfig(ns string, dc doctl.Config, out io.Writer, args []string, init
 			if context == "" {
 				context = viper.GetString("context")
 			}
-			token := ""
 
-			switch context {
-			case doctl.ArgDefaultContext:
-				token = viper.GetString(doctl.ArgAccessToken)
-			default:
-				contexts := viper.GetStringMapString("auth-contexts")
-
-				token = contexts[context]
+			token, err := contextAccessToken(context)
+			if err != nil {
+				warn("Unable to read the access token of context %s: %v", context, err)
 			}
 
 			return token
 		},
 
-		setContextAccessToken: func(token string) {
+		setContextAccessToken: func(token string) error {
 			context := Context
 			if context == "" {
 				context = viper.GetString("context")
 			}
 
-			switch context {
-			case doctl.ArgDefaultContext:
-				viper.Set(doctl.ArgAccessToken, token)
-			default:
-				contexts := viper.GetStringMapString("auth-contexts")
-				contexts[context] = token
-
-				viper.Set("auth-contexts", contexts)
-			}
+			return setContextAccessToken(context, token)
 		},
 
-		removeContext: func(context string) error {
-			if context == "default" {
-				viper.Set("access-token", "")
-				return nil
-			}
-
-			contexts := viper.GetStringMapString("auth-contexts")
-
-			_, ok := contexts[context]
-
-			if !ok {
-				return fmt.Errorf("Context not found")
-			}
-
-			delete(contexts, context)
-
-			viper.Set("auth-contexts", contexts)
-
-			return nil
-		},
+		removeContext: removeContext,
 
 		componentBuilderFactory: &builder.DefaultComponentBuilderFactory{},
 	}
@@ -243,6 +219,16 @@ type CmdRunner func(*CmdConfig) error
 
 // Display displays the output from a command.
 func (c *CmdConfig) Display(d displayers.Displayable) error {
+	dc, err := c.displayer(d)
+	if err != nil {
+		return err
+	}
+
+	return dc.Display()
+}
+
+// displayer builds a Displayer for d configured from the output flags.
+func (c *CmdConfig) displayer(d displayers.Displayable) (*displayers.Displayer, error) {
 	dc := &displayers.Displayer{
 		Item: d,
 		Out:  c.Out,
@@ -250,19 +236,55 @@ func (c *CmdConfig) Display(d displayers.Displayable) error {
 
 	columnList, err := c.Doit.GetString(c.NS, doctl.ArgFormat)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	withHeaders, err := c.Doit.GetBool(c.NS, doctl.ArgNoHeader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	dc.NoHeaders = withHeaders
 	dc.ColumnList = columnList
 	dc.OutputType = Output
+	if TemplateFile != "" {
+		dc.OutputType = "go-template-file=" + TemplateFile
+	}
 
-	return dc.Display()
+	return dc, nil
+}
+
+// displayPages displays the pages of an iterator as they are fetched. Output
+// types rendering one row or document per resource are written page by page.
+// Other output types, and text output whose columns are aligned across the
+// whole list, are rendered once every page was read. Pages are only fetched
+// as they are displayed, so a limit set on the iterator stops the list early.
+func displayPages[T any](c *CmdConfig, it *do.Iterator[T], item func([]T) displayers.Displayable) error {
+	dc, err := c.displayer(item(nil))
+	if err != nil {
+		return err
+	}
+
+	if !dc.Streamable() {
+		all, err := do.Collect(it)
+		if err != nil {
+			return err
+		}
+
+		dc.Item = item(all)
+		return dc.Display()
+	}
+
+	for first := true; it.Next(); first = false {
+		dc.Item = item(it.Page())
+		dc.NoHeaders = dc.NoHeaders || !first
+
+		if err := dc.Display(); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
 }
 
 // An urner implements the URN method, which returns a valid uniform resource
@@ -378,7 +378,7 @@ func withTestClient(t *testing.T, tFn testFn) {
 			return viper.GetString(doctl.ArgAccessToken)
 		},
 
-		setContextAccessToken: func(token string) {},
+		setContextAccessToken: func(token string) error { return nil },
 
 		componentBuilderFactory: tm.appBuilderFactory,
 
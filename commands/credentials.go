@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/digitalocean/doctl"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+const (
+	// credentialsPassphraseEnv holds the passphrase of the encrypted
+	// credentials file.
+	credentialsPassphraseEnv = "DIGITALOCEAN_CREDENTIALS_PASSPHRASE"
+
+	defaultCredentialsFileName = "credentials.enc"
+)
+
+// credentialStores caches the stores built for each helper so the
+// passphrase of the encrypted file is only asked for once.
+var credentialStores = map[string]doctl.CredentialStore{}
+
+// credentialStore returns the store of the configured credential helper, or
+// nil when tokens are kept in the config file.
+func credentialStore() (doctl.CredentialStore, error) {
+	helper := viper.GetString(doctl.ArgCredentialHelper)
+	if helper == "" {
+		return nil, nil
+	}
+
+	file := viper.GetString(doctl.ArgCredentialsFile)
+	if file == "" {
+		file = filepath.Join(configHome(), defaultCredentialsFileName)
+	}
+
+	key := helper + "\x00" + file
+	if store, ok := credentialStores[key]; ok {
+		return store, nil
+	}
+
+	store, err := doctl.NewCredentialStore(doctl.CredentialStoreConfig{
+		Helper:     helper,
+		File:       file,
+		Passphrase: credentialsPassphrase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	credentialStores[key] = store
+
+	return store, nil
+}
+
+// credentialsPassphrase reads the passphrase of the encrypted credentials
+// file from the environment, or prompts for it.
+func credentialsPassphrase() (string, error) {
+	if pass := os.Getenv(credentialsPassphraseEnv); pass != "" {
+		return pass, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("set %s to unlock the credentials file", credentialsPassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "Credentials file passphrase: ")
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// contextAccessToken returns the token of an auth context, looking it up in
+// the credential store when one is configured. Tokens still present in the
+// config file are used as a fallback.
+func contextAccessToken(context string) (string, error) {
+	if context == doctl.ArgDefaultContext {
+		// tokens given with the flag or the environment always win
+		if token := viper.GetString(doctl.ArgAccessToken); token != "" && !viper.InConfig(doctl.ArgAccessToken) {
+			return token, nil
+		}
+	}
+
+	store, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+
+	if store != nil {
+		token, err := store.Get(context)
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+
+	if context == doctl.ArgDefaultContext {
+		return viper.GetString(doctl.ArgAccessToken), nil
+	}
+
+	return viper.GetStringMapString("auth-contexts")[context], nil
+}
+
+// setContextAccessToken saves the token of an auth context. With a
+// credential store, only the name of the context is kept in the config file.
+func setContextAccessToken(context, token string) error {
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+
+	if store != nil {
+		if err := store.Store(context, token); err != nil {
+			return err
+		}
+		// never leave a plaintext copy of the token behind
+		token = ""
+	}
+
+	switch context {
+	case doctl.ArgDefaultContext:
+		viper.Set(doctl.ArgAccessToken, token)
+	default:
+		contexts := viper.GetStringMapString("auth-contexts")
+		contexts[context] = token
+
+		viper.Set("auth-contexts", contexts)
+	}
+
+	return nil
+}
+
+// removeContext removes an auth context and its stored token.
+func removeContext(context string) error {
+	if context != doctl.ArgDefaultContext {
+		contexts := viper.GetStringMapString("auth-contexts")
+		if _, ok := contexts[context]; !ok {
+			return errors.New("Context not found")
+		}
+	}
+
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		if err := store.Erase(context); err != nil {
+			return err
+		}
+	}
+
+	if context == doctl.ArgDefaultContext {
+		viper.Set(doctl.ArgAccessToken, "")
+		return nil
+	}
+
+	contexts := viper.GetStringMapString("auth-contexts")
+	delete(contexts, context)
+	viper.Set("auth-contexts", contexts)
+
+	return nil
+}
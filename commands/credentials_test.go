@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextAccessTokenWithCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper")
+	require.NoError(t, os.WriteFile(helper, []byte(`#!/bin/sh
+while IFS= read -r line; do
+	[ -z "$line" ] && break
+	case "$line" in
+		context=*) context="${line#context=}" ;;
+		token=*) token="${line#token=}" ;;
+	esac
+done
+case "$1" in
+	get) [ -f "`+dir+`/$context.token" ] && printf 'token=%s\n' "$(cat "`+dir+`/$context.token")" ;;
+	store) printf '%s' "$token" > "`+dir+`/$context.token" ;;
+	erase) rm -f "`+dir+`/$context.token" ;;
+esac
+exit 0
+`), 0700))
+
+	viper.Set(doctl.ArgCredentialHelper, helper)
+	defer viper.Set(doctl.ArgCredentialHelper, "")
+	contexts := viper.Get("auth-contexts")
+	defer viper.Set("auth-contexts", contexts)
+
+	require.NoError(t, setContextAccessToken("staging", "dop_v1_staging"))
+
+	assert.Equal(t, "", viper.GetStringMapString("auth-contexts")["staging"])
+	b, err := os.ReadFile(filepath.Join(dir, "staging.token"))
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_staging", string(b))
+
+	token, err := contextAccessToken("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_staging", token)
+
+	require.NoError(t, removeContext("staging"))
+	assert.NotContains(t, viper.GetStringMapString("auth-contexts"), "staging")
+	assert.NoFileExists(t, filepath.Join(dir, "staging.token"))
+
+	assert.EqualError(t, removeContext("staging"), "Context not found")
+}
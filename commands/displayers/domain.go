@@ -104,3 +104,48 @@ func (dr *DomainRecord) KV() []map[string]any {
 
 	return out
 }
+
+// RecordPropagation is the state of a domain record on a nameserver.
+type RecordPropagation struct {
+	Nameserver string `json:"nameserver"`
+	RecordID   int    `json:"record_id"`
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Data       string `json:"data"`
+	Status     string `json:"status"`
+}
+
+type RecordPropagations struct {
+	Propagations []RecordPropagation
+}
+
+var _ Displayable = &RecordPropagations{}
+
+func (rp *RecordPropagations) JSON(out io.Writer) error {
+	return writeJSON(rp.Propagations, out)
+}
+
+func (rp *RecordPropagations) Cols() []string {
+	return []string{"Nameserver", "RecordID", "Type", "Name", "Data", "Status"}
+}
+
+func (rp *RecordPropagations) ColMap() map[string]string {
+	return map[string]string{
+		"Nameserver": "Nameserver", "RecordID": "Record ID", "Type": "Type",
+		"Name": "Name", "Data": "Data", "Status": "Status",
+	}
+}
+
+func (rp *RecordPropagations) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(rp.Propagations))
+
+	for _, p := range rp.Propagations {
+		o := map[string]any{
+			"Nameserver": p.Nameserver, "RecordID": p.RecordID, "Type": p.Type,
+			"Name": p.Name, "Data": p.Data, "Status": p.Status,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"io"
+)
+
+// RolloutDroplet is the outcome of a rollout for one Droplet.
+type RolloutDroplet struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RolloutDroplets struct {
+	Droplets []RolloutDroplet
+}
+
+var _ Displayable = &RolloutDroplets{}
+
+func (r *RolloutDroplets) JSON(out io.Writer) error {
+	return writeJSON(r.Droplets, out)
+}
+
+func (r *RolloutDroplets) Cols() []string {
+	return []string{"ID", "Name", "Status", "Error"}
+}
+
+func (r *RolloutDroplets) ColMap() map[string]string {
+	return map[string]string{
+		"ID":     "ID",
+		"Name":   "Name",
+		"Status": "Status",
+		"Error":  "Error",
+	}
+}
+
+func (r *RolloutDroplets) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(r.Droplets))
+
+	for _, d := range r.Droplets {
+		o := map[string]any{
+			"ID":     d.ID,
+			"Name":   d.Name,
+			"Status": d.Status,
+			"Error":  d.Error,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
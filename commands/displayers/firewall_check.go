@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"io"
+)
+
+// FirewallRuleMatch is a firewall rule allowing some traffic.
+type FirewallRuleMatch struct {
+	FirewallID   string `json:"firewall_id"`
+	FirewallName string `json:"firewall_name"`
+	// AppliedBy is how the firewall applies to the Droplet, directly or
+	// through a tag.
+	AppliedBy string `json:"applied_by"`
+	Direction string `json:"direction"`
+	Protocol  string `json:"protocol"`
+	Ports     string `json:"ports"`
+	// Match is the source or destination of the rule matching the traffic.
+	Match string `json:"match"`
+}
+
+type FirewallRuleMatches struct {
+	Matches []FirewallRuleMatch
+}
+
+var _ Displayable = &FirewallRuleMatches{}
+
+func (f *FirewallRuleMatches) JSON(out io.Writer) error {
+	return writeJSON(f.Matches, out)
+}
+
+func (f *FirewallRuleMatches) Cols() []string {
+	return []string{"FirewallID", "FirewallName", "AppliedBy", "Direction", "Protocol", "Ports", "Match"}
+}
+
+func (f *FirewallRuleMatches) ColMap() map[string]string {
+	return map[string]string{
+		"FirewallID":   "Firewall ID",
+		"FirewallName": "Firewall Name",
+		"AppliedBy":    "Applied By",
+		"Direction":    "Direction",
+		"Protocol":     "Protocol",
+		"Ports":        "Ports",
+		"Match":        "Match",
+	}
+}
+
+func (f *FirewallRuleMatches) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(f.Matches))
+
+	for _, m := range f.Matches {
+		o := map[string]any{
+			"FirewallID":   m.FirewallID,
+			"FirewallName": m.FirewallName,
+			"AppliedBy":    m.AppliedBy,
+			"Direction":    m.Direction,
+			"Protocol":     m.Protocol,
+			"Ports":        m.Ports,
+			"Match":        m.Match,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
@@ -385,6 +385,112 @@ func (ar *KubernetesAssociatedResources) KV() []map[string]any {
 	return []map[string]any{o}
 }
 
+// KubernetesRotatedNode is the outcome of a node pool rotation for one node.
+type KubernetesRotatedNode struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Replacement string `json:"replacement,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type KubernetesRotatedNodes struct {
+	Nodes []KubernetesRotatedNode
+}
+
+var _ Displayable = &KubernetesRotatedNodes{}
+
+func (nodes *KubernetesRotatedNodes) JSON(out io.Writer) error {
+	return writeJSON(nodes.Nodes, out)
+}
+
+func (nodes *KubernetesRotatedNodes) Cols() []string {
+	return []string{
+		"ID",
+		"Name",
+		"Status",
+		"Replacement",
+		"Error",
+	}
+}
+
+func (nodes *KubernetesRotatedNodes) ColMap() map[string]string {
+	return map[string]string{
+		"ID":          "ID",
+		"Name":        "Name",
+		"Status":      "Status",
+		"Replacement": "Replacement",
+		"Error":       "Error",
+	}
+}
+
+func (nodes *KubernetesRotatedNodes) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(nodes.Nodes))
+	for _, node := range nodes.Nodes {
+		o := map[string]any{
+			"ID":          node.ID,
+			"Name":        node.Name,
+			"Status":      node.Status,
+			"Replacement": node.Replacement,
+			"Error":       node.Error,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
+
+// KubernetesPreflightCheck is the result of an upgrade preflight check.
+type KubernetesPreflightCheck struct {
+	Check    string `json:"check"`
+	Result   string `json:"result"`
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+type KubernetesPreflightChecks struct {
+	Checks []KubernetesPreflightCheck
+}
+
+var _ Displayable = &KubernetesPreflightChecks{}
+
+func (checks *KubernetesPreflightChecks) JSON(out io.Writer) error {
+	return writeJSON(checks.Checks, out)
+}
+
+func (checks *KubernetesPreflightChecks) Cols() []string {
+	return []string{
+		"Check",
+		"Result",
+		"Resource",
+		"Message",
+	}
+}
+
+func (checks *KubernetesPreflightChecks) ColMap() map[string]string {
+	return map[string]string{
+		"Check":    "Check",
+		"Result":   "Result",
+		"Resource": "Resource",
+		"Message":  "Message",
+	}
+}
+
+func (checks *KubernetesPreflightChecks) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(checks.Checks))
+	for _, check := range checks.Checks {
+		o := map[string]any{
+			"Check":    check.Check,
+			"Result":   check.Result,
+			"Resource": check.Resource,
+			"Message":  check.Message,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
+
 func flattenAssociatedResourceIDs(resources []*godo.AssociatedResource) (out []string) {
 	for _, r := range resources {
 		out = append(out, r.ID)
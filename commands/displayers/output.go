@@ -15,12 +15,16 @@ package displayers
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
 	"strings"
 	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
 )
 
 // Displayable is a displayable entity. These are used for printing results.
@@ -41,8 +45,22 @@ type Displayer struct {
 	Out  io.Writer
 }
 
-// Display ends up rendering the content in one of two formats (text|json)
+// Display ends up rendering the content in one of the supported formats
+// (text|json|yaml|csv|json-lines|go-template=...|go-template-file=...|jsonpath=...)
 func (d *Displayer) Display() error {
+	switch {
+	case strings.HasPrefix(d.OutputType, goTemplateOutputPrefix):
+		return DisplayGoTemplate(d.Item, d.Out, strings.TrimPrefix(d.OutputType, goTemplateOutputPrefix))
+	case strings.HasPrefix(d.OutputType, goTemplateFileOutputPrefix):
+		b, err := os.ReadFile(strings.TrimPrefix(d.OutputType, goTemplateFileOutputPrefix))
+		if err != nil {
+			return fmt.Errorf("reading template file: %v", err)
+		}
+		return DisplayGoTemplate(d.Item, d.Out, string(b))
+	case strings.HasPrefix(d.OutputType, jsonPathOutputPrefix):
+		return DisplayJSONPath(d.Item, d.Out, strings.TrimPrefix(d.OutputType, jsonPathOutputPrefix))
+	}
+
 	switch d.OutputType {
 	case "json":
 		if containsOnlyNilSlice(d.Item) {
@@ -50,41 +68,57 @@ func (d *Displayer) Display() error {
 			return err
 		}
 		return d.Item.JSON(d.Out)
+	case "yaml":
+		return DisplayYAML(d.Item, d.Out)
+	case "json-lines":
+		return DisplayJSONLines(d.Item, d.Out)
+	case "csv":
+		return DisplayCSV(d.Item, d.Out, d.NoHeaders, d.columns())
 	case "text":
-		var cols []string
-		for _, c := range strings.Split(strings.Join(strings.Fields(d.ColumnList), ""), ",") {
-			if c != "" {
-				cols = append(cols, c)
-			}
-		}
-
-		return DisplayText(d.Item, d.Out, d.NoHeaders, cols)
+		return DisplayText(d.Item, d.Out, d.NoHeaders, d.columns())
 	default:
 		return fmt.Errorf("unknown output type")
 	}
 }
 
+// Streamable reports whether the output type renders each resource
+// independently, allowing a list to be displayed one page at a time. Text
+// output is not, as its columns are aligned across every resource.
+func (d *Displayer) Streamable() bool {
+	switch d.OutputType {
+	case "csv", "json-lines":
+		return true
+	}
+
+	return strings.HasPrefix(d.OutputType, goTemplateOutputPrefix) ||
+		strings.HasPrefix(d.OutputType, goTemplateFileOutputPrefix) ||
+		strings.HasPrefix(d.OutputType, jsonPathOutputPrefix)
+}
+
+// columns returns the column names requested with --format.
+func (d *Displayer) columns() []string {
+	var cols []string
+	for _, c := range strings.Split(strings.Join(strings.Fields(d.ColumnList), ""), ",") {
+		if c != "" {
+			cols = append(cols, c)
+		}
+	}
+
+	return cols
+}
+
 // DisplayText writes tabbed content to the passed in io.Writer
 // while potentially adding or removing headers.
 func DisplayText(item Displayable, out io.Writer, noHeaders bool, includeCols []string) error {
 	w := new(tabwriter.Writer)
 	w.Init(out, 0, 0, 4, ' ', 0)
 
-	cols := item.Cols()
-	if len(includeCols) > 0 && includeCols[0] != "" {
-		cols = includeCols
+	cols, headers, err := selectColumns(item, includeCols)
+	if err != nil {
+		return err
 	}
 
 	if !noHeaders {
-		headers := make([]string, 0, len(cols))
-		for _, k := range cols {
-			col := item.ColMap()[k]
-			if col == "" {
-				return fmt.Errorf("unknown column %q", k)
-			}
-
-			headers = append(headers, col)
-		}
 		fmt.Fprintln(w, strings.Join(headers, "\t"))
 	}
 
@@ -117,6 +151,121 @@ func DisplayText(item Displayable, out io.Writer, noHeaders bool, includeCols []
 	return w.Flush()
 }
 
+// DisplayCSV writes comma separated content to the passed in io.Writer. The
+// header row is built from the item's ColMap and only the included columns
+// are written.
+func DisplayCSV(item Displayable, out io.Writer, noHeaders bool, includeCols []string) error {
+	w := csv.NewWriter(out)
+
+	cols, headers, err := selectColumns(item, includeCols)
+	if err != nil {
+		return err
+	}
+
+	if !noHeaders {
+		if err := w.Write(headers); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range item.KV() {
+		record := make([]string, 0, len(cols))
+		for _, col := range cols {
+			v := r[col]
+			if v == nil {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, fmt.Sprintf("%v", v))
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// DisplayYAML writes the JSON representation of an item to the passed in
+// io.Writer as YAML.
+func DisplayYAML(item Displayable, out io.Writer) error {
+	if containsOnlyNilSlice(item) {
+		_, err := out.Write([]byte("[]\n"))
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := item.JSON(&buf); err != nil {
+		return err
+	}
+
+	b, err := yaml.JSONToYAML(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(b)
+	return err
+}
+
+// DisplayJSONLines writes the JSON representation of an item to the passed
+// in io.Writer as newline-delimited JSON, with one line per element when the
+// item is a list.
+func DisplayJSONLines(item Displayable, out io.Writer) error {
+	if containsOnlyNilSlice(item) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := item.JSON(&buf); err != nil {
+		return err
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &elems); err != nil {
+		// not a list, write the single document on one line
+		elems = []json.RawMessage{buf.Bytes()}
+	}
+
+	for _, e := range elems {
+		var line bytes.Buffer
+		if err := json.Compact(&line, e); err != nil {
+			return err
+		}
+		line.WriteByte('\n')
+
+		if _, err := line.WriteTo(out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectColumns returns the column keys to display along with their header
+// names. When includeCols is empty the item's default columns are used.
+func selectColumns(item Displayable, includeCols []string) ([]string, []string, error) {
+	cols := item.Cols()
+	if len(includeCols) > 0 && includeCols[0] != "" {
+		cols = includeCols
+	}
+
+	colMap := item.ColMap()
+	headers := make([]string, 0, len(cols))
+	for _, k := range cols {
+		col := colMap[k]
+		if col == "" {
+			return nil, nil, fmt.Errorf("unknown column %q", k)
+		}
+
+		headers = append(headers, col)
+	}
+
+	return cols, headers, nil
+}
+
 func writeJSON(item any, w io.Writer) error {
 	b, err := json.Marshal(item)
 	if err != nil {
@@ -2,9 +2,13 @@ package displayers
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,3 +50,178 @@ func TestDisplayerDisplay(t *testing.T) {
 		})
 	}
 }
+
+func TestDisplayerDisplayFormats(t *testing.T) {
+	item := &Volume{Volumes: []do.Volume{
+		{Volume: &godo.Volume{ID: "abc", Name: "data", SizeGigaBytes: 10, Tags: []string{"a", "b"}}},
+		{Volume: &godo.Volume{ID: "def", Name: "logs, old", SizeGigaBytes: 20}},
+	}}
+
+	tests := []struct {
+		name       string
+		outputType string
+		columnList string
+		noHeaders  bool
+		expected   string
+	}{
+		{
+			name:       "csv with selected columns",
+			outputType: "csv",
+			columnList: "ID, Name,Tags",
+			expected:   "ID,Name,Tags\nabc,data,\"a,b\"\ndef,\"logs, old\",\n",
+		},
+		{
+			name:       "csv without headers",
+			outputType: "csv",
+			columnList: "ID,Size",
+			noHeaders:  true,
+			expected:   "abc,10 GiB\ndef,20 GiB\n",
+		},
+		{
+			name:       "json-lines",
+			outputType: "json-lines",
+			expected: `{"id":"abc","region":null,"name":"data","size_gigabytes":10,"description":"","droplet_ids":null,"created_at":"0001-01-01T00:00:00Z","filesystem_type":"","filesystem_label":"","tags":["a","b"]}` + "\n" +
+				`{"id":"def","region":null,"name":"logs, old","size_gigabytes":20,"description":"","droplet_ids":null,"created_at":"0001-01-01T00:00:00Z","filesystem_type":"","filesystem_label":"","tags":null}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+
+			displayer := Displayer{
+				OutputType: tt.outputType,
+				ColumnList: tt.columnList,
+				NoHeaders:  tt.noHeaders,
+				Item:       item,
+				Out:        out,
+			}
+
+			err := displayer.Display()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestDisplayerDisplayYAML(t *testing.T) {
+	out := &bytes.Buffer{}
+	displayer := Displayer{
+		OutputType: "yaml",
+		Item:       &Volume{Volumes: []do.Volume{{Volume: &godo.Volume{ID: "abc", Name: "data"}}}},
+		Out:        out,
+	}
+
+	err := displayer.Display()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "- created_at: \"0001-01-01T00:00:00Z\"\n")
+	assert.Contains(t, out.String(), "  id: abc\n")
+	assert.Contains(t, out.String(), "  name: data\n")
+
+	out.Reset()
+	displayer.Item = &Volume{}
+	err = displayer.Display()
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestDisplayerDisplayUnknownColumn(t *testing.T) {
+	displayer := Displayer{
+		OutputType: "csv",
+		ColumnList: "ID,Nope",
+		Item:       &Volume{},
+		Out:        &bytes.Buffer{},
+	}
+
+	err := displayer.Display()
+	assert.EqualError(t, err, `unknown column "Nope"`)
+}
+
+func TestDisplayerDisplayTemplates(t *testing.T) {
+	item := &Volume{Volumes: []do.Volume{
+		{Volume: &godo.Volume{ID: "abc", Name: "data", SizeGigaBytes: 10, Tags: []string{"a", "b"}, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Region: &godo.Region{Slug: "nyc1"}}},
+		{Volume: &godo.Volume{ID: "def", Name: "logs", SizeGigaBytes: 2048}},
+	}}
+
+	tests := []struct {
+		name       string
+		item       Displayable
+		outputType string
+		expected   string
+	}{
+		{
+			name:       "go-template with Go field names",
+			outputType: `go-template={{.ID}} {{.Name}}`,
+			expected:   "abc data\ndef logs\n",
+		},
+		{
+			name:       "go-template helpers",
+			outputType: `go-template={{join .Tags "|"}} {{date "2006-01-02" .CreatedAt}}`,
+			expected:   "a|b 2024-01-02\n 0001-01-01\n",
+		},
+		{
+			name:       "go-template byte sizes",
+			item:       &Registry{Registries: []do.Registry{{Registry: &godo.Registry{Name: "images", StorageUsageBytes: 5368709120}}}},
+			outputType: `go-template={{.Name}} {{humanizeBytesBinary .StorageUsageBytes}} {{humanizeBytes .StorageUsageBytes}}`,
+			expected:   "images 5.00 GiB 5.37 GB\n",
+		},
+		{
+			name:       "jsonpath with JSON field names",
+			outputType: `jsonpath={.id}{"\t"}{.region.slug}`,
+			expected:   "abc\tnyc1\ndef\t\n",
+		},
+		{
+			name:       "jsonpath range and wildcard",
+			outputType: `jsonpath={.name}:{range .tags[*]} [{@}]{end} {.tags[-1]}`,
+			expected:   "data: [a] [b] b\nlogs: \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+
+			displayer := Displayer{
+				OutputType: tt.outputType,
+				Item:       item,
+				Out:        out,
+			}
+			if tt.item != nil {
+				displayer.Item = tt.item
+			}
+
+			err := displayer.Display()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestDisplayerDisplayTemplateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmpl")
+	err := os.WriteFile(path, []byte("{{.Email}} {{upper .Status}}\n"), 0600)
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	displayer := Displayer{
+		OutputType: "go-template-file=" + path,
+		Item:       &Account{Account: &do.Account{Account: &godo.Account{Email: "sammy@example.com", Status: "active"}}},
+		Out:        out,
+	}
+
+	err = displayer.Display()
+	assert.NoError(t, err)
+	assert.Equal(t, "sammy@example.com ACTIVE\n", out.String())
+}
+
+func TestDisplayerDisplayInvalidJSONPath(t *testing.T) {
+	for _, expr := range []string{"{.name", `{"\t}`, "{.tags[x]}", "{.tags[0}"} {
+		displayer := Displayer{
+			OutputType: "jsonpath=" + expr,
+			Item:       &Volume{},
+			Out:        &bytes.Buffer{},
+		}
+
+		assert.Error(t, displayer.Display(), expr)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"io"
+	"sort"
+)
+
+// Profile is a configuration profile defined in the configuration file.
+type Profile struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+}
+
+type Profiles struct {
+	Profiles []Profile
+}
+
+var _ Displayable = &Profiles{}
+
+func (p *Profiles) JSON(out io.Writer) error {
+	return writeJSON(p.Profiles, out)
+}
+
+func (p *Profiles) Cols() []string {
+	return []string{"Name", "Current"}
+}
+
+func (p *Profiles) ColMap() map[string]string {
+	return map[string]string{"Name": "Name", "Current": "Current"}
+}
+
+func (p *Profiles) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(p.Profiles))
+	for _, profile := range p.Profiles {
+		out = append(out, map[string]any{"Name": profile.Name, "Current": profile.Current})
+	}
+
+	return out
+}
+
+// ProfileSettings is the effective configuration, as nested settings.
+type ProfileSettings struct {
+	Settings map[string]any
+}
+
+var _ Displayable = &ProfileSettings{}
+
+func (p *ProfileSettings) JSON(out io.Writer) error {
+	return writeJSON(p.Settings, out)
+}
+
+func (p *ProfileSettings) Cols() []string {
+	return []string{"Key", "Value"}
+}
+
+func (p *ProfileSettings) ColMap() map[string]string {
+	return map[string]string{"Key": "Key", "Value": "Value"}
+}
+
+// KV lists the settings sorted by their dot separated keys.
+func (p *ProfileSettings) KV() []map[string]any {
+	flat := map[string]any{}
+	flattenProfileSettings("", p.Settings, flat)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, map[string]any{"Key": key, "Value": flat[key]})
+	}
+
+	return out
+}
+
+func flattenProfileSettings(prefix string, settings map[string]any, out map[string]any) {
+	for k, v := range settings {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flattenProfileSettings(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"io"
+)
+
+// SSHResult is the outcome of a command run on a Droplet over SSH.
+type SSHResult struct {
+	DropletID int    `json:"droplet_id"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	ExitCode  int    `json:"exit_code"`
+	Error     string `json:"error,omitempty"`
+}
+
+type SSHResults struct {
+	Results []SSHResult
+}
+
+var _ Displayable = &SSHResults{}
+
+func (s *SSHResults) JSON(out io.Writer) error {
+	return writeJSON(s.Results, out)
+}
+
+func (s *SSHResults) Cols() []string {
+	return []string{"ID", "Name", "Address", "ExitCode", "Error"}
+}
+
+func (s *SSHResults) ColMap() map[string]string {
+	return map[string]string{
+		"ID":       "ID",
+		"Name":     "Name",
+		"Address":  "Address",
+		"ExitCode": "Exit Code",
+		"Error":    "Error",
+	}
+}
+
+func (s *SSHResults) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(s.Results))
+
+	for _, r := range s.Results {
+		o := map[string]any{
+			"ID":       r.DropletID,
+			"Name":     r.Name,
+			"Address":  r.Address,
+			"ExitCode": r.ExitCode,
+			"Error":    r.Error,
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"io"
+	"strings"
+)
+
+// StackChange is a change planned or made by doctl apply.
+type StackChange struct {
+	Action  string   `json:"action"`
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+type StackChanges struct {
+	Changes []StackChange
+}
+
+var _ Displayable = &StackChanges{}
+
+func (s *StackChanges) JSON(out io.Writer) error {
+	return writeJSON(s.Changes, out)
+}
+
+func (s *StackChanges) Cols() []string {
+	return []string{"Action", "Kind", "Name", "Changes"}
+}
+
+func (s *StackChanges) ColMap() map[string]string {
+	return map[string]string{
+		"Action":  "Action",
+		"Kind":    "Kind",
+		"Name":    "Name",
+		"Changes": "Changes",
+	}
+}
+
+func (s *StackChanges) KV() []map[string]any {
+	out := make([]map[string]any, 0, len(s.Changes))
+
+	for _, c := range s.Changes {
+		o := map[string]any{
+			"Action":  c.Action,
+			"Kind":    c.Kind,
+			"Name":    c.Name,
+			"Changes": strings.Join(c.Changes, ", "),
+		}
+		out = append(out, o)
+	}
+
+	return out
+}
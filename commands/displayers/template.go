@@ -0,0 +1,222 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package displayers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	goTemplateOutputPrefix     = "go-template="
+	goTemplateFileOutputPrefix = "go-template-file="
+	jsonPathOutputPrefix       = "jsonpath="
+)
+
+// TemplateFuncs are the helper functions available to go-template output.
+var TemplateFuncs = template.FuncMap{
+	"join":                strings.Join,
+	"upper":               strings.ToUpper,
+	"lower":               strings.ToLower,
+	"date":                formatDate,
+	"humanizeBytes":       humanizeBytes(BytesToHumanReadableUnit),
+	"humanizeBytesBinary": humanizeBytes(BytesToHumanReadableUnitBinary),
+	"json":                toJSON,
+}
+
+// DisplayGoTemplate executes a Go template against every resource held by
+// the item and writes one result per line to the passed in io.Writer. The
+// template is evaluated against the underlying Go structs, so fields are
+// referenced by their Go names, e.g. `{{.Name}}` or `{{.Region.Slug}}`.
+func DisplayGoTemplate(item Displayable, out io.Writer, tmpl string) error {
+	t, err := template.New("output").Funcs(TemplateFuncs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing go-template: %v", err)
+	}
+
+	for _, v := range templateData(item) {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, v); err != nil {
+			return fmt.Errorf("executing go-template: %v", err)
+		}
+
+		if err := writeLine(out, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DisplayJSONPath evaluates a JSONPath template against the JSON
+// representation of every resource held by the item and writes one result per
+// line to the passed in io.Writer. Fields are referenced by their JSON names,
+// e.g. `{.name}` or `{.region.slug}`, and the template syntax is the one of
+// kubectl's jsonpath output. As with kubectl, missing fields print nothing.
+func DisplayJSONPath(item Displayable, out io.Writer, expr string) error {
+	if _, err := parseJSONPath(expr); err != nil {
+		return err
+	}
+
+	if containsOnlyNilSlice(item) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := item.JSON(&buf); err != nil {
+		return err
+	}
+
+	var doc any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return err
+	}
+
+	elems, ok := doc.([]any)
+	if !ok {
+		elems = []any{doc}
+	}
+
+	for _, e := range elems {
+		// A parsed template keeps the state of its range blocks between
+		// executions, so each resource gets its own.
+		jp, err := parseJSONPath(expr)
+		if err != nil {
+			return err
+		}
+
+		var line bytes.Buffer
+		if err := jp.Execute(&line, e); err != nil {
+			return fmt.Errorf("executing jsonpath: %v", err)
+		}
+
+		if err := writeLine(out, line.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseJSONPath(expr string) (*jsonpath.JSONPath, error) {
+	jp := jsonpath.New("jsonpath").AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, fmt.Errorf("parsing jsonpath: %v", err)
+	}
+	return jp, nil
+}
+
+// templateData returns the Go values a template is executed against. A
+// Displayable wraps the resources it displays in its first field; when that
+// field is a slice, each element is returned separately.
+func templateData(item Displayable) []any {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || v.NumField() == 0 {
+		return []any{item}
+	}
+
+	field := v.Field(0)
+	if !field.CanInterface() {
+		return []any{item}
+	}
+
+	if field.Kind() != reflect.Slice {
+		return []any{field.Interface()}
+	}
+
+	data := make([]any, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		data = append(data, field.Index(i).Interface())
+	}
+
+	return data
+}
+
+// writeLine writes b to out, terminating it with a newline if needed.
+func writeLine(out io.Writer, b []byte) error {
+	if len(b) == 0 || b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+
+	_, err := out.Write(b)
+	return err
+}
+
+// formatDate formats a time.Time, or a string holding an RFC 3339 timestamp,
+// using the given Go time layout.
+func formatDate(layout string, v any) (string, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case string:
+		if t == "" {
+			return "", nil
+		}
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return "", err
+		}
+		return parsed.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date: unsupported type %T", v)
+	}
+}
+
+func humanizeBytes(fn func(uint64) string) func(any) (string, error) {
+	return func(v any) (string, error) {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.Int() < 0 {
+				return "", fmt.Errorf("cannot humanize negative size %d", rv.Int())
+			}
+			return fn(uint64(rv.Int())), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return fn(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			if rv.Float() < 0 {
+				return "", fmt.Errorf("cannot humanize negative size %v", rv.Float())
+			}
+			return fn(uint64(rv.Float())), nil
+		default:
+			return "", fmt.Errorf("cannot humanize %T", v)
+		}
+	}
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
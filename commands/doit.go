@@ -54,6 +54,8 @@ var (
 	Context string
 	//Output global output format
 	Output string
+	//TemplateFile path to a Go template used to render output
+	TemplateFile string
 	//Token global authorization token
 	Token string
 	//Trace toggles http tracing output
@@ -68,6 +70,10 @@ var (
 	RetryWaitMax int
 	RetryWaitMin int
 
+	// Pagination settings to pass through to do.PaginationConfig
+	PageSize    int
+	PageRetries int
+
 	requiredColor = color.New(color.Bold).SprintfFunc()
 )
 
@@ -85,15 +91,42 @@ func init() {
 	rootPFlagSet.StringVarP(&Token, doctl.ArgAccessToken, "t", "", "API V2 access token")
 	viper.BindPFlag(doctl.ArgAccessToken, rootPFlagSet.Lookup(doctl.ArgAccessToken))
 
-	rootPFlagSet.StringVarP(&Output, doctl.ArgOutput, "o", "text", "Desired output format [text|json]")
+	rootPFlagSet.StringVarP(&Output, doctl.ArgOutput, "o", "text", "Desired output format [text|json|yaml|csv|json-lines|go-template=...|jsonpath=...]")
 	viper.BindPFlag("output", rootPFlagSet.Lookup(doctl.ArgOutput))
 
+	rootPFlagSet.StringVarP(&TemplateFile, doctl.ArgTemplateFile, "", "", "Render output using the Go template in the given file")
+
 	rootPFlagSet.StringVarP(&Context, doctl.ArgContext, "", "", "Specify a custom authentication context name")
 	DoitCmd.RegisterFlagCompletionFunc(doctl.ArgContext, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return getAuthContextList(), cobra.ShellCompDirectiveNoFileComp
 	})
 
+	rootPFlagSet.String(doctl.ArgCredentialHelper, "", "Store auth context tokens with a credential helper instead of the config file [secret-service|file|<helper>]. See the help of doctl auth init")
+	viper.BindPFlag(doctl.ArgCredentialHelper, rootPFlagSet.Lookup(doctl.ArgCredentialHelper))
+
+	rootPFlagSet.String(doctl.ArgCredentialsFile, "", "Path of the encrypted credentials file used by the file credential helper")
+	viper.BindPFlag(doctl.ArgCredentialsFile, rootPFlagSet.Lookup(doctl.ArgCredentialsFile))
+	DoitCmd.PersistentFlags().MarkHidden(doctl.ArgCredentialsFile)
+
+	rootPFlagSet.String(doctl.ArgProfile, "", "Specify a configuration profile to use. See the help of doctl auth profile")
+	viper.BindPFlag(doctl.ArgProfile, rootPFlagSet.Lookup(doctl.ArgProfile))
+	DoitCmd.RegisterFlagCompletionFunc(doctl.ArgProfile, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return profileNames(viper.GetViper()), cobra.ShellCompDirectiveNoFileComp
+	})
+
 	rootPFlagSet.BoolVarP(&Trace, "trace", "", false, "Show a log of network activity while performing a command")
+
+	rootPFlagSet.String("trace-format", "text", "Format of the network activity shown by --trace [text|json]. json writes one object per HTTP exchange with its timing, request ID, rate limit and retry attempt")
+	viper.BindPFlag("trace-format", rootPFlagSet.Lookup("trace-format"))
+
+	rootPFlagSet.Bool("trace-redact", true, "Mask tokens, passwords and other secrets in the network activity shown by --trace. Set to false to show them")
+	viper.BindPFlag("trace-redact", rootPFlagSet.Lookup("trace-redact"))
+
+	rootPFlagSet.String("record", "", "Record the API requests and responses of a command to the given cassette file. Credentials in headers and bodies, such as tokens and passwords, are redacted")
+	viper.BindPFlag("record", rootPFlagSet.Lookup("record"))
+
+	rootPFlagSet.String("replay", "", "Serve API responses from the given cassette file instead of calling the API")
+	viper.BindPFlag("replay", rootPFlagSet.Lookup("replay"))
 	rootPFlagSet.BoolVarP(&Verbose, doctl.ArgVerbose, "v", false, "Enable verbose output")
 
 	interactive := isTerminal(os.Stdout) && isTerminal(os.Stderr)
@@ -115,6 +148,28 @@ func init() {
 	viper.BindPFlag("http-retry-wait-min", rootPFlagSet.Lookup("http-retry-wait-min"))
 	DoitCmd.PersistentFlags().MarkHidden("http-retry-wait-min")
 
+	rootPFlagSet.Bool("http-throttle", true, "Slow down requests when the API rate limit is close to being exhausted")
+	viper.BindPFlag("http-throttle", rootPFlagSet.Lookup("http-throttle"))
+	DoitCmd.PersistentFlags().MarkHidden("http-throttle")
+
+	rootPFlagSet.Float64("http-throttle-rate", 0, "Set the maximum number of requests sent per second. 0 only slows down requests when the API rate limit is close to being exhausted")
+	viper.BindPFlag("http-throttle-rate", rootPFlagSet.Lookup("http-throttle-rate"))
+	DoitCmd.PersistentFlags().MarkHidden("http-throttle-rate")
+
+	rootPFlagSet.Int("http-throttle-burst", 10, "Set the number of requests that may be sent at once before the throttle rate applies")
+	viper.BindPFlag("http-throttle-burst", rootPFlagSet.Lookup("http-throttle-burst"))
+	DoitCmd.PersistentFlags().MarkHidden("http-throttle-burst")
+
+	rootPFlagSet.Int("http-throttle-reserve", 25, "Spread the remaining requests over the rate limit window once fewer than this many remain")
+	viper.BindPFlag("http-throttle-reserve", rootPFlagSet.Lookup("http-throttle-reserve"))
+	DoitCmd.PersistentFlags().MarkHidden("http-throttle-reserve")
+
+	rootPFlagSet.IntVar(&PageSize, "page-size", 200, "Set the number of items requested per page when listing resources. The maximum is 200")
+	viper.BindPFlag("page-size", rootPFlagSet.Lookup("page-size"))
+
+	rootPFlagSet.IntVar(&PageRetries, "page-retries", 0, "Set the number of times a page that failed to load is retried when listing resources")
+	viper.BindPFlag("page-retries", rootPFlagSet.Lookup("page-retries"))
+
 	addCommands()
 
 	cobra.OnInitialize(initConfig)
@@ -138,6 +193,18 @@ func initConfig() {
 			log.Fatalln("Config initialization failed:", err)
 		}
 	}
+
+	if name := viper.GetString(doctl.ArgProfile); name != "" {
+		p, err := applyProfile(viper.GetViper(), strings.ToLower(name))
+		if err != nil {
+			log.Fatalln("Config initialization failed:", err)
+		}
+		activeProfile = p
+
+		if !DoitCmd.PersistentFlags().Changed(doctl.ArgOutput) {
+			Output = viper.GetString("output")
+		}
+	}
 }
 
 // in case we ever want to change this, or let folks configure it...
@@ -175,12 +242,14 @@ func addCommands() {
 	DoitCmd.AddGroup(&cobra.Group{ID: viewBillingGroup, Title: "View Billing:"})
 
 	DoitCmd.AddCommand(Account())
+	Apply(DoitCmd)
 	DoitCmd.AddCommand(Apps())
 	DoitCmd.AddCommand(Auth())
 	DoitCmd.AddCommand(Balance())
 	DoitCmd.AddCommand(BillingHistory())
 	DoitCmd.AddCommand(Invoices())
 	DoitCmd.AddCommand(computeCmd())
+	Export(DoitCmd)
 	DoitCmd.AddCommand(Kubernetes())
 	DoitCmd.AddCommand(Databases())
 	DoitCmd.AddCommand(VectorDatabases())
@@ -236,9 +305,10 @@ func computeCmd() *Command {
 	cmd.AddCommand(Volume())
 	cmd.AddCommand(VolumeAction())
 
-	// SSH is different since it doesn't have any subcommands. In this case, let's
-	// give it a parent at init time.
+	// SSH and SCP are different since they don't have any subcommands. In this
+	// case, let's give them a parent at init time.
 	SSH(cmd)
+	SCP(cmd)
 
 	return cmd
 }
@@ -0,0 +1,319 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/doctl/pkg/dnsquery"
+)
+
+const (
+	propagationStatusPropagated = "propagated"
+	propagationStatusPending    = "pending"
+
+	defaultPropagationTimeout = 5 * time.Minute
+)
+
+var (
+	// defaultNameservers are the authoritative nameservers of the domains
+	// managed by DigitalOcean.
+	defaultNameservers = []string{"ns1.digitalocean.com", "ns2.digitalocean.com", "ns3.digitalocean.com"}
+
+	// queryNameserver and propagationPollInterval are variables so tests
+	// can replace them.
+	queryNameserver         = dnsquery.Query
+	propagationPollInterval = 5 * time.Second
+
+	srvNameRegexp = regexp.MustCompile(`^_[A-Za-z0-9-]+\._[A-Za-z0-9-]+(\..+)?$`)
+	hostLabel     = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?$`)
+)
+
+func domainRecordsVerify(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunRecordVerify, "verify <domain> [<record-id>...]", "Verify that DNS records are served by the nameservers", `Queries the authoritative nameservers of a domain until they all return the given records, or every record of the domain except its SOA record when no record ID is given, and exits with an error if some records are still missing when the timeout passes.
+
+By default, the DigitalOcean nameservers `+"`"+`ns1.digitalocean.com`+"`"+`, `+"`"+`ns2.digitalocean.com`+"`"+` and `+"`"+`ns3.digitalocean.com`+"`"+` are queried. Use `+"`"+`--nameservers`+"`"+` to query other servers, such as a local DNS server.`, Writer, displayerType(&displayers.RecordPropagations{}))
+	addPropagationFlags(cmd)
+	cmd.Example = `The following example waits up to two minutes for the record with the ID ` + "`" + `98858421` + "`" + ` of the domain ` + "`" + `example.com` + "`" + ` to be served by every DigitalOcean nameserver: doctl compute domain records verify example.com 98858421 --timeout 2m`
+
+	return cmd
+}
+
+// addPropagationFlags adds the flags setting the nameservers queried and
+// how long to wait for records to propagate.
+func addPropagationFlags(cmd *Command) {
+	AddStringSliceFlag(cmd, doctl.ArgNameservers, "", defaultNameservers, "The nameservers to query, as host or host:port")
+	AddDurationFlag(cmd, doctl.ArgTimeout, "", defaultPropagationTimeout, "How long to wait for the records to propagate")
+}
+
+// RunRecordVerify waits for the records of a domain to be served by the
+// nameservers.
+func RunRecordVerify(c *CmdConfig) error {
+	if len(c.Args) < 1 {
+		return doctl.NewMissingArgsErr(c.NS)
+	}
+	domain := strings.TrimSuffix(c.Args[0], ".")
+
+	var ids []int
+	for _, arg := range c.Args[1:] {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("Invalid record id %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	ds := c.Domains()
+	var records do.DomainRecords
+	if len(ids) == 0 {
+		all, err := ds.Records(domain)
+		if err != nil {
+			return err
+		}
+		for _, r := range all {
+			if r.Type != "SOA" {
+				records = append(records, r)
+			}
+		}
+	} else {
+		for _, id := range ids {
+			r, err := ds.Record(domain, id)
+			if err != nil {
+				return err
+			}
+			records = append(records, *r)
+		}
+	}
+
+	results, err := waitForPropagation(c, domain, records)
+	if displayErr := c.Display(&displayers.RecordPropagations{Propagations: results}); displayErr != nil {
+		return displayErr
+	}
+	return err
+}
+
+// waitForPropagation queries the nameservers set by the command's flags
+// until they all return records, or the timeout passes.
+func waitForPropagation(c *CmdConfig, domain string, records do.DomainRecords) ([]displayers.RecordPropagation, error) {
+	nameservers, err := c.Doit.GetStringSlice(c.NS, doctl.ArgNameservers)
+	if err != nil {
+		return nil, err
+	}
+	if len(nameservers) == 0 {
+		nameservers = defaultNameservers
+	}
+
+	timeout, err := c.Doit.GetDuration(c.NS, doctl.ArgTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		results := checkPropagation(domain, nameservers, records)
+
+		var pending []string
+		for _, r := range results {
+			if r.Status != propagationStatusPropagated {
+				pending = append(pending, fmt.Sprintf("%s %s on %s", r.Type, r.Name, r.Nameserver))
+			}
+		}
+		if len(pending) == 0 {
+			return results, nil
+		}
+
+		wait := min(propagationPollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return results, fmt.Errorf("records not propagated after %s: %s", timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// checkPropagation queries every nameserver for every record once.
+func checkPropagation(domain string, nameservers []string, records do.DomainRecords) []displayers.RecordPropagation {
+	var results []displayers.RecordPropagation
+	for _, r := range records {
+		fqdn := domain
+		if r.Name != "@" && r.Name != "" {
+			fqdn = strings.TrimSuffix(r.Name, ".")
+			if !strings.HasSuffix(fqdn, "."+domain) && fqdn != domain {
+				fqdn += "." + domain
+			}
+		}
+
+		for _, ns := range nameservers {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			answers, err := queryNameserver(ctx, ns, fqdn, r.Type)
+			cancel()
+
+			status := propagationStatusPending
+			switch {
+			case err != nil && !errors.Is(err, dnsquery.ErrNameError):
+				status = "error: " + err.Error()
+			case slices.ContainsFunc(answers, func(a dnsquery.Record) bool { return recordServed(domain, r, a) }):
+				status = propagationStatusPropagated
+			}
+
+			results = append(results, displayers.RecordPropagation{
+				Nameserver: ns,
+				RecordID:   r.ID,
+				Type:       r.Type,
+				Name:       recordName(r.Name),
+				Data:       r.Data,
+				Status:     status,
+			})
+		}
+	}
+	return results
+}
+
+// recordServed reports whether a nameserver answer matches a record.
+func recordServed(domain string, r do.DomainRecord, a dnsquery.Record) bool {
+	switch r.Type {
+	case "A", "AAAA":
+		want, err := netip.ParseAddr(r.Data)
+		if err != nil {
+			return false
+		}
+		got, err := netip.ParseAddr(a.Data)
+		return err == nil && got == want
+	case "CNAME", "NS":
+		return recordTarget(domain, r.Data) == a.Data
+	case "MX":
+		return r.Priority == a.Priority && recordTarget(domain, r.Data) == a.Data
+	case "SRV":
+		return r.Priority == a.Priority && r.Weight == a.Weight && r.Port == a.Port && recordTarget(domain, r.Data) == a.Data
+	case "CAA":
+		return r.Flags == a.Flags && strings.EqualFold(r.Tag, a.Tag) && r.Data == a.Data
+	default:
+		return r.Data == a.Data
+	}
+}
+
+// recordTarget returns the host name in the data of a record, fully
+// qualified without the trailing dot.
+func recordTarget(domain, data string) string {
+	domain, data = strings.ToLower(domain), strings.ToLower(data)
+	switch {
+	case data == "@":
+		return domain
+	case strings.HasSuffix(data, "."):
+		return strings.TrimSuffix(data, ".")
+	case !strings.Contains(data, "."):
+		return data + "." + domain
+	default:
+		return data
+	}
+}
+
+// validateRecordRequest checks the fields of MX, SRV and CAA records, which
+// the API would otherwise reject with a less helpful message or accept in a
+// form no resolver can use. With partial, as for updates, the name, data and
+// port are only checked when they are set.
+func validateRecordRequest(r *do.DomainRecordEditRequest, partial bool) error {
+	typ := strings.ToUpper(r.Type)
+	inRange := func(field string, v, max int) error {
+		if v < 0 || v > max {
+			return fmt.Errorf("%s record %s must be between 0 and %d", typ, field, max)
+		}
+		return nil
+	}
+
+	switch typ {
+	case "MX":
+		if err := inRange("priority", r.Priority, 65535); err != nil {
+			return err
+		}
+		if (!partial || r.Data != "") && !validHostName(r.Data) {
+			return fmt.Errorf("MX record data %q is not a valid host name", r.Data)
+		}
+	case "SRV":
+		if (!partial || r.Name != "") && !srvNameRegexp.MatchString(r.Name) {
+			return fmt.Errorf("SRV record name %q must start with _service._protocol, as in _sip._tcp", r.Name)
+		}
+		if err := inRange("priority", r.Priority, 65535); err != nil {
+			return err
+		}
+		if err := inRange("weight", r.Weight, 65535); err != nil {
+			return err
+		}
+		if r.Port == nil && !partial {
+			return fmt.Errorf("SRV records need a port, set with --%s", doctl.ArgRecordPort)
+		}
+		if r.Port != nil {
+			if err := inRange("port", *r.Port, 65535); err != nil {
+				return err
+			}
+		}
+		if (!partial || r.Data != "") && !validHostName(r.Data) {
+			return fmt.Errorf("SRV record data %q is not a valid host name", r.Data)
+		}
+	case "CAA":
+		if err := inRange("flags", r.Flags, 255); err != nil {
+			return err
+		}
+		// CAA tags are case-insensitive, RFC 8659 section 4.1.
+		tag := strings.ToLower(r.Tag)
+		checkData := !partial || r.Data != ""
+		switch tag {
+		case "":
+			if !partial {
+				return errors.New("CAA records need a tag, set with --" + doctl.ArgRecordTag)
+			}
+		case "issue", "issuewild":
+			if checkData && r.Data != ";" && !validHostName(strings.TrimSpace(strings.SplitN(r.Data, ";", 2)[0])) {
+				return fmt.Errorf("CAA record data %q must be the domain name of a certificate authority", r.Data)
+			}
+		case "iodef":
+			if checkData && !strings.HasPrefix(r.Data, "mailto:") && !strings.HasPrefix(r.Data, "http://") && !strings.HasPrefix(r.Data, "https://") {
+				return fmt.Errorf("CAA record data %q must be a mailto:, http:// or https:// URL for the iodef tag", r.Data)
+			}
+		default:
+			return fmt.Errorf("CAA record tag %q must be one of: issue, issuewild, iodef", r.Tag)
+		}
+	}
+
+	return nil
+}
+
+// validHostName reports whether name is @, the root (for a null MX or SRV
+// record), or a host name.
+func validHostName(name string) bool {
+	if name == "@" || name == "." {
+		return true
+	}
+
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !hostLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/doctl/pkg/dnsquery"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeNameservers replaces the DNS queries of the propagation checks with
+// the records returned by answers, or a name error when it returns nil.
+func fakeNameservers(t *testing.T, answers func(ns, name, typ string) []dnsquery.Record) {
+	query, interval := queryNameserver, propagationPollInterval
+	t.Cleanup(func() { queryNameserver, propagationPollInterval = query, interval })
+
+	propagationPollInterval = time.Millisecond
+	queryNameserver = func(ctx context.Context, ns, name, typ string) ([]dnsquery.Record, error) {
+		if records := answers(ns, name, typ); records != nil {
+			return records, nil
+		}
+		return nil, dnsquery.ErrNameError
+	}
+}
+
+func TestRunRecordVerify(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		fakeNameservers(t, func(ns, name, typ string) []dnsquery.Record {
+			switch {
+			case name == "www.example.com" && typ == "A":
+				return []dnsquery.Record{{Type: "A", Name: name, Data: "203.0.113.10"}}
+			case name == "example.com" && typ == "MX" && ns == "ns1.test":
+				return []dnsquery.Record{{Type: "MX", Name: name, Data: "mail.example.com", Priority: 10}}
+			}
+			return nil
+		})
+
+		tm.domains.EXPECT().Records("example.com").Return(do.DomainRecords{
+			{DomainRecord: &godo.DomainRecord{ID: 1, Type: "SOA", Name: "@", Data: "1800"}},
+			{DomainRecord: &godo.DomainRecord{ID: 2, Type: "A", Name: "www", Data: "203.0.113.10"}},
+			{DomainRecord: &godo.DomainRecord{ID: 3, Type: "MX", Name: "@", Data: "mail.example.com", Priority: 10}},
+		}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgNameservers, []string{"ns1.test", "ns2.test"})
+
+		err := RunRecordVerify(config)
+		assert.EqualError(t, err, "records not propagated after 0s: MX @ on ns2.test")
+
+		expected := `Nameserver    Record ID    Type    Name    Data                Status
+ns1.test      2            A       www     203.0.113.10        propagated
+ns2.test      2            A       www     203.0.113.10        propagated
+ns1.test      3            MX      @       mail.example.com    propagated
+ns2.test      3            MX      @       mail.example.com    pending
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRecordsCreateWaitPropagation(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		queries := 0
+		fakeNameservers(t, func(ns, name, typ string) []dnsquery.Record {
+			queries++
+			if queries < 3 {
+				return nil
+			}
+			return []dnsquery.Record{{Type: "CNAME", Name: name, Data: "example.com"}}
+		})
+
+		record := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 4, Type: "CNAME", Name: "api", Data: "@"}}
+		tm.domains.EXPECT().CreateRecord("example.com", gomock.Any()).Return(&record, nil)
+
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgRecordType, "CNAME")
+		config.Doit.Set(config.NS, doctl.ArgRecordName, "api")
+		config.Doit.Set(config.NS, doctl.ArgRecordData, "@")
+		config.Doit.Set(config.NS, doctl.ArgWaitPropagation, true)
+		config.Doit.Set(config.NS, doctl.ArgNameservers, []string{"ns1.test"})
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+
+		err := RunRecordCreate(config)
+		require.NoError(t, err)
+		assert.Equal(t, 3, queries)
+	})
+}
+
+func TestValidateRecordRequest(t *testing.T) {
+	port := 5060
+	badPort := 70000
+
+	tests := []struct {
+		name    string
+		req     do.DomainRecordEditRequest
+		partial bool
+		err     string
+	}{
+		{name: "valid mx", req: do.DomainRecordEditRequest{Type: "MX", Data: "mail.example.com.", Priority: 10}},
+		{name: "mx priority", req: do.DomainRecordEditRequest{Type: "MX", Data: "mail", Priority: -1}, err: "MX record priority must be between 0 and 65535"},
+		{name: "mx data", req: do.DomainRecordEditRequest{Type: "MX", Data: "mail server"}, err: `MX record data "mail server" is not a valid host name`},
+		{name: "partial mx", req: do.DomainRecordEditRequest{Type: "MX", Priority: 5}, partial: true},
+		{name: "valid srv", req: do.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com", Port: &port}},
+		{name: "srv name", req: do.DomainRecordEditRequest{Type: "SRV", Name: "sip", Data: "sip.example.com", Port: &port}, err: `SRV record name "sip" must start with _service._protocol, as in _sip._tcp`},
+		{name: "srv port missing", req: do.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com"}, err: "SRV records need a port, set with --record-port"},
+		{name: "srv port range", req: do.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com", Port: &badPort}, err: "SRV record port must be between 0 and 65535"},
+		{name: "valid caa", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "issue", Data: "letsencrypt.org"}},
+		{name: "caa flags", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "issue", Data: "letsencrypt.org", Flags: 256}, err: "CAA record flags must be between 0 and 255"},
+		{name: "caa tag", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "issuer", Data: "letsencrypt.org"}, err: `CAA record tag "issuer" must be one of: issue, issuewild, iodef`},
+		{name: "caa iodef", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "iodef", Data: "security@example.com"}, err: `CAA record data "security@example.com" must be a mailto:, http:// or https:// URL for the iodef tag`},
+		{name: "caa tag case", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "Issuewild", Data: "letsencrypt.org"}},
+		{name: "caa iodef tag case", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "IODEF", Data: "mailto:security@example.com"}},
+		{name: "partial caa", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "issue"}, partial: true},
+		{name: "partial caa data", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "iodef", Data: "security@example.com"}, partial: true, err: `CAA record data "security@example.com" must be a mailto:, http:// or https:// URL for the iodef tag`},
+		{name: "caa issue", req: do.DomainRecordEditRequest{Type: "CAA", Tag: "issue", Data: "not a ca"}, err: `CAA record data "not a ca" must be the domain name of a certificate authority`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRecordRequest(&tt.req, tt.partial)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/doctl/pkg/zonefile"
+)
+
+// txtChunkSize is the maximum length of a character string in TXT record
+// data.
+const txtChunkSize = 255
+
+func domainRecordsImport(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunRecordImport, "import <domain>", "Import DNS records from a zone file", `Creates and updates the DNS records of a domain to match a zone file in the RFC 1035 format, such as one exported from another DNS provider.
+
+Relative names in the zone file are completed with the domain unless a `+"`"+`$ORIGIN`+"`"+` directive sets another origin. The SOA record and the NS records of the domain itself are skipped, as DigitalOcean manages them. Records are identified by their type, name and data, so a record whose TTL, priority, weight, port, flags or tag differ is updated.
+
+Use `+"`"+`--dry-run`+"`"+` to display the changes without making them. With `+"`"+`--sync`+"`"+`, the records of the domain missing from the zone file are deleted.`, Writer, displayerType(&displayers.StackChanges{}))
+	AddStringFlag(cmd, doctl.ArgZoneFile, "", "", "Path to the zone file. Set to - to read from stdin", requiredOpt())
+	AddBoolFlag(cmd, doctl.ArgDryRun, "", false, "Display the changes without making them")
+	AddBoolFlag(cmd, doctl.ArgZoneSync, "", false, "Delete the records missing from the zone file, except for the SOA and NS records of the domain")
+	AddBoolFlag(cmd, doctl.ArgForce, doctl.ArgShortForce, false, "Delete records without a confirmation prompt")
+	cmd.Example = `The following example displays the changes needed for the records of ` + "`" + `example.com` + "`" + ` to match the zone file ` + "`" + `db.example.com` + "`" + `: doctl compute domain records import example.com --zone-file db.example.com --dry-run`
+
+	return cmd
+}
+
+func domainRecordsExport(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunRecordExport, "export <domain>", "Export DNS records to a zone file", `Writes the DNS records of a domain as a zone file in the RFC 1035 format, to stdout or to the file set with `+"`"+`--zone-file`+"`"+`.
+
+The SOA record is left out, as DigitalOcean manages it.`, Writer)
+	AddStringFlag(cmd, doctl.ArgZoneFile, "", "", "Path of the zone file to write instead of stdout")
+	cmd.Example = `The following example writes the records of ` + "`" + `example.com` + "`" + ` to the zone file ` + "`" + `db.example.com` + "`" + `: doctl compute domain records export example.com --zone-file db.example.com`
+
+	return cmd
+}
+
+// RunRecordImport creates, updates and deletes the records of a domain to
+// match a zone file.
+func RunRecordImport(c *CmdConfig) error {
+	err := ensureOneArg(c)
+	if err != nil {
+		return err
+	}
+	domain := strings.TrimSuffix(c.Args[0], ".")
+
+	path, err := c.Doit.GetString(c.NS, doctl.ArgZoneFile)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := c.Doit.GetBool(c.NS, doctl.ArgDryRun)
+	if err != nil {
+		return err
+	}
+
+	sync, err := c.Doit.GetBool(c.NS, doctl.ArgZoneSync)
+	if err != nil {
+		return err
+	}
+
+	force, err := c.Doit.GetBool(c.NS, doctl.ArgForce)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records, err := zonefile.Parse(in, domain)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	desired, err := zoneRecordSpecs(domain, records)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	ds := c.Domains()
+	current, err := ds.Records(domain)
+	if err != nil {
+		return err
+	}
+
+	p := &stackPlanner{domains: ds, prune: sync}
+	changes := p.planRecords(domain, desired, current)
+
+	item := &displayers.StackChanges{}
+	for _, change := range changes {
+		item.Changes = append(item.Changes, change.StackChange)
+	}
+
+	if dryRun {
+		return c.Display(item)
+	}
+
+	deletes := 0
+	for _, change := range changes {
+		if change.Action == stackActionDelete {
+			deletes++
+		}
+	}
+	if deletes > 0 && !force && AskForConfirmDelete("domain record", deletes) != nil {
+		return errOperationAborted
+	}
+
+	for _, change := range changes {
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("%s %s: %w", change.Action, change.Name, err)
+		}
+	}
+
+	return c.Display(item)
+}
+
+// RunRecordExport writes the records of a domain as a zone file.
+func RunRecordExport(c *CmdConfig) error {
+	err := ensureOneArg(c)
+	if err != nil {
+		return err
+	}
+	domain := strings.TrimSuffix(c.Args[0], ".")
+
+	path, err := c.Doit.GetString(c.NS, doctl.ArgZoneFile)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.Domains().Records(domain)
+	if err != nil {
+		return err
+	}
+
+	out := c.Out
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return zonefile.Write(out, domain, zoneRecords(domain, current))
+}
+
+// zoneRecordSpecs converts the records of a zone file to the records of a
+// domain, skipping those managed by DigitalOcean.
+func zoneRecordSpecs(domain string, records []zonefile.Record) ([]domainRecordSpec, error) {
+	origin := zonefile.Fqdn(strings.ToLower(domain))
+
+	var specs []domainRecordSpec
+	for _, r := range records {
+		if r.Class != "IN" {
+			return nil, fmt.Errorf("line %d: records of class %s are not supported", r.Line, r.Class)
+		}
+
+		name := "@"
+		if r.Name != origin {
+			rel, ok := strings.CutSuffix(r.Name, "."+origin)
+			if !ok {
+				return nil, fmt.Errorf("line %d: %s is not part of the domain %s", r.Line, r.Name, domain)
+			}
+			name = rel
+		}
+
+		if r.Type == "SOA" || (r.Type == "NS" && name == "@") {
+			continue
+		}
+
+		spec := domainRecordSpec{Type: r.Type, Name: name, TTL: r.TTL}
+
+		switch r.Type {
+		case "A", "AAAA":
+			spec.Data = r.Data[0]
+		case "CNAME", "NS":
+			spec.Data = zoneTarget(origin, r.Data[0])
+		case "MX":
+			ints, err := zoneInts(r, r.Data[:1])
+			if err != nil {
+				return nil, err
+			}
+			spec.Priority = ints[0]
+			spec.Data = zoneTarget(origin, r.Data[1])
+		case "SRV":
+			ints, err := zoneInts(r, r.Data[:3])
+			if err != nil {
+				return nil, err
+			}
+			spec.Priority, spec.Weight, spec.Port = ints[0], ints[1], ints[2]
+			spec.Data = zoneTarget(origin, r.Data[3])
+		case "CAA":
+			ints, err := zoneInts(r, r.Data[:1])
+			if err != nil {
+				return nil, err
+			}
+			spec.Flags, spec.Tag, spec.Data = ints[0], r.Data[1], r.Data[2]
+		case "TXT":
+			spec.Data = strings.Join(r.Data, "")
+		default:
+			return nil, fmt.Errorf("line %d: %s records are not supported", r.Line, r.Type)
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// zoneInts parses the numeric fields of a record's data.
+func zoneInts(r zonefile.Record, fields []string) ([]int, error) {
+	ints := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s record data %q", r.Line, r.Type, f)
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
+
+// zoneTarget returns the target of a record as the API expects it, with @
+// for the domain itself.
+func zoneTarget(origin, name string) string {
+	if name == origin {
+		return "@"
+	}
+	return name
+}
+
+// zoneRecords converts the records of a domain to zone file records. The
+// API returns fully qualified names without the trailing dot, or @ for the
+// domain itself.
+func zoneRecords(domain string, records do.DomainRecords) []zonefile.Record {
+	origin := zonefile.Fqdn(strings.ToLower(domain))
+
+	absolute := func(name string) string {
+		if name == "@" || name == "" {
+			return origin
+		}
+		return zonefile.Fqdn(name)
+	}
+
+	var out []zonefile.Record
+	for _, r := range records {
+		if r.Type == "SOA" {
+			continue
+		}
+
+		name := origin
+		if r.Name != "@" && r.Name != "" {
+			name = r.Name + "." + origin
+		}
+
+		zr := zonefile.Record{Name: name, TTL: r.TTL, Class: "IN", Type: r.Type}
+		switch r.Type {
+		case "CNAME", "NS":
+			zr.Data = []string{absolute(r.Data)}
+		case "MX":
+			zr.Data = []string{strconv.Itoa(r.Priority), absolute(r.Data)}
+		case "SRV":
+			zr.Data = []string{strconv.Itoa(r.Priority), strconv.Itoa(r.Weight), strconv.Itoa(r.Port), absolute(r.Data)}
+		case "CAA":
+			zr.Data = []string{strconv.Itoa(r.Flags), r.Tag, r.Data}
+		case "TXT":
+			data := r.Data
+			for len(data) > txtChunkSize {
+				zr.Data = append(zr.Data, data[:txtChunkSize])
+				data = data[txtChunkSize:]
+			}
+			zr.Data = append(zr.Data, data)
+		default:
+			zr.Data = []string{r.Data}
+		}
+
+		out = append(out, zr)
+	}
+
+	return out
+}
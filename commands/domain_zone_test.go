@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testZoneFile = `$TTL 3600
+@	IN	SOA	ns1.other.net. hostmaster ( 1 7200 3600 1209600 300 )
+	IN	NS	ns1.other.net.
+	300	IN	MX	10 mail
+www		IN	A	203.0.113.10
+api		IN	CNAME	@
+_sip._tcp	IN	SRV	10 60 5060 sip.example.net.
+`
+
+func liveZoneRecords() do.DomainRecords {
+	return do.DomainRecords{
+		{DomainRecord: &godo.DomainRecord{ID: 1, Type: "SOA", Name: "@", Data: "1800", TTL: 1800}},
+		{DomainRecord: &godo.DomainRecord{ID: 2, Type: "NS", Name: "@", Data: "ns1.digitalocean.com", TTL: 1800}},
+		{DomainRecord: &godo.DomainRecord{ID: 3, Type: "A", Name: "www", Data: "203.0.113.10", TTL: 1800}},
+		{DomainRecord: &godo.DomainRecord{ID: 4, Type: "MX", Name: "@", Data: "mail.example.com", Priority: 10, TTL: 300}},
+		{DomainRecord: &godo.DomainRecord{ID: 5, Type: "A", Name: "old", Data: "203.0.113.99", TTL: 1800}},
+	}
+}
+
+func writeTestZone(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "db.example.com")
+	require.NoError(t, os.WriteFile(path, []byte(testZoneFile), 0644))
+	return path
+}
+
+func TestRunRecordImportDryRun(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.EXPECT().Records("example.com").Return(liveZoneRecords(), nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgZoneFile, writeTestZone(t))
+		config.Doit.Set(config.NS, doctl.ArgDryRun, true)
+		config.Doit.Set(config.NS, doctl.ArgZoneSync, true)
+
+		err := RunRecordImport(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind             Name                         Changes
+update    domain_record    example.com A www            ttl: 1800 -> 3600
+create    domain_record    example.com CNAME api        data: @
+create    domain_record    example.com SRV _sip._tcp    data: sip.example.net.
+delete    domain_record    example.com A old            data: 203.0.113.99
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRunRecordImportSync(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.EXPECT().Records("example.com").Return(liveZoneRecords(), nil)
+		tm.domains.EXPECT().EditRecord("example.com", 3, &do.DomainRecordEditRequest{Type: "A", Name: "www", Data: "203.0.113.10", TTL: 3600}).Return(&testRecord, nil)
+		tm.domains.EXPECT().CreateRecord("example.com", &do.DomainRecordEditRequest{Type: "CNAME", Name: "api", Data: "@", TTL: 3600}).Return(&testRecord, nil)
+		port := 5060
+		tm.domains.EXPECT().CreateRecord("example.com", &do.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.net.", Priority: 10, Weight: 60, Port: &port, TTL: 3600}).Return(&testRecord, nil)
+		tm.domains.EXPECT().DeleteRecord("example.com", 5).Return(nil)
+
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgZoneFile, writeTestZone(t))
+		config.Doit.Set(config.NS, doctl.ArgZoneSync, true)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunRecordImport(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRunRecordImportUnsupported(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		path := filepath.Join(t.TempDir(), "db.example.com")
+		require.NoError(t, os.WriteFile(path, []byte("1 IN PTR host.example.com.\n"), 0644))
+
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgZoneFile, path)
+
+		err := RunRecordImport(config)
+		assert.EqualError(t, err, path+": line 1: PTR records are not supported")
+	})
+}
+
+func TestRunRecordExport(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		records := append(liveZoneRecords(),
+			do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 6, Type: "CNAME", Name: "api", Data: "@", TTL: 3600}},
+			do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 7, Type: "CAA", Name: "@", Data: "letsencrypt.org", Flags: 0, Tag: "issue", TTL: 3600}},
+			do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 8, Type: "TXT", Name: "@", Data: "v=spf1 -all", TTL: 3600}},
+		)
+		tm.domains.EXPECT().Records("example.com").Return(records, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+
+		err := RunRecordExport(config)
+		require.NoError(t, err)
+
+		expected := `$ORIGIN example.com.
+@   1800 IN NS    ns1.digitalocean.com.
+www 1800 IN A     203.0.113.10
+@   300  IN MX    10 mail
+old 1800 IN A     203.0.113.99
+api 3600 IN CNAME @
+@   3600 IN CAA   0 issue "letsencrypt.org"
+@   3600 IN TXT   "v=spf1 -all"
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -63,6 +64,7 @@ func Domain() *Command {
 
 	cmdRecordList := CmdBuilder(cmdRecord, RunRecordList, "list <domain>", "List the DNS records for a domain", `Lists the DNS records for a domain.`, Writer,
 		aliasOpt("ls"), displayerType(&displayers.DomainRecord{}))
+	AddIntFlag(cmdRecordList, doctl.ArgMaxItems, "", 0, "Stop listing once this many records were displayed, without fetching the remaining pages. 0 lists every record")
 	cmdRecordList.Example = `The following command lists the DNS records for the domain example.com. The command also uses the ` + "`" + `--format` + "`" + ` flag to only return each record's ID, type, and TTL: doctl compute domain records list example.com --format ID,Type,TTL`
 
 	cmdRecordCreate := CmdBuilder(cmdRecord, RunRecordCreate, "create <domain>", "Create a DNS record", `Create DNS records for a domain.`, Writer,
@@ -76,6 +78,8 @@ func Domain() *Command {
 	AddIntFlag(cmdRecordCreate, doctl.ArgRecordWeight, "", 0, "The weight value for an SRV record")
 	AddIntFlag(cmdRecordCreate, doctl.ArgRecordFlags, "", 0, "The flag value of a CAA record. A valid is an unsigned integer between 0-255.")
 	AddStringFlag(cmdRecordCreate, doctl.ArgRecordTag, "", "", "The parameter tag for CAA records. Valid values are `issue`, `issuewild`, or `iodef`")
+	AddBoolFlag(cmdRecordCreate, doctl.ArgWaitPropagation, "", false, "Wait until the nameservers return the record")
+	addPropagationFlags(cmdRecordCreate)
 
 	cmdRecordCreate.Example = `The following command creates an A record for the domain example.com: doctl compute domain records create example.com --record-type A --record-name example.com --record-data 198.51.100.215`
 
@@ -96,9 +100,15 @@ func Domain() *Command {
 	AddIntFlag(cmdRecordUpdate, doctl.ArgRecordWeight, "", 0, "The weight value for an SRV record")
 	AddIntFlag(cmdRecordUpdate, doctl.ArgRecordFlags, "", 0, "The flag value of a CAA record. A valid is an unsigned integer between 0-255.")
 	AddStringFlag(cmdRecordUpdate, doctl.ArgRecordTag, "", "", "The parameter tag for CAA records. Valid values are `issue`, `issuewild`, or `iodef`")
+	AddBoolFlag(cmdRecordUpdate, doctl.ArgWaitPropagation, "", false, "Wait until the nameservers return the updated record")
+	addPropagationFlags(cmdRecordUpdate)
 
 	cmdRecordUpdate.Example = `The following command updates the record with the ID ` + "`" + `98858421` + "`" + ` for the domain ` + "`" + `example.com` + "`" + `: doctl compute domain records update example.com --record-id 98858421 --record-name example.com --record-data 198.51.100.215`
 
+	domainRecordsImport(cmdRecord)
+	domainRecordsExport(cmdRecord)
+	domainRecordsVerify(cmdRecord)
+
 	return cmd
 }
 
@@ -208,12 +218,22 @@ func RunRecordList(c *CmdConfig) error {
 		return errors.New("Domain name is missing.")
 	}
 
-	list, err := ds.Records(name)
+	short, err := shortDomainRecords(c)
 	if err != nil {
 		return err
 	}
 
-	return displayDomainRecords(c, list...)
+	maxItems, err := c.Doit.GetInt(c.NS, doctl.ArgMaxItems)
+	if err != nil {
+		return err
+	}
+	if maxItems < 0 {
+		return fmt.Errorf("--%s must not be negative", doctl.ArgMaxItems)
+	}
+
+	return displayPages(c, ds.RecordsIter(context.TODO(), name).Limit(maxItems), func(records []do.DomainRecord) displayers.Displayable {
+		return &displayers.DomainRecord{DomainRecords: do.DomainRecords(records), Short: short}
+	})
 }
 
 // RunRecordCreate creates a domain record.
@@ -287,13 +307,28 @@ func RunRecordCreate(c *CmdConfig) error {
 		return errors.New("Record request is missing type.")
 	}
 
+	if err := validateRecordRequest(drcr, false); err != nil {
+		return err
+	}
+
+	wait, err := c.Doit.GetBool(c.NS, doctl.ArgWaitPropagation)
+	if err != nil {
+		return err
+	}
+
 	r, err := ds.CreateRecord(name, drcr)
 	if err != nil {
 		return err
 	}
 
-	return displayDomainRecords(c, *r)
+	if err := displayDomainRecords(c, *r); err != nil {
+		return err
+	}
 
+	if wait {
+		_, err = waitForPropagation(c, name, do.DomainRecords{*r})
+	}
+	return err
 }
 
 // RunRecordDelete deletes a domain record.
@@ -406,27 +441,51 @@ func RunRecordUpdate(c *CmdConfig) error {
 		Tag:      rTag,
 	}
 
+	if drcr.Type != "" {
+		if err := validateRecordRequest(drcr, true); err != nil {
+			return err
+		}
+	}
+
+	wait, err := c.Doit.GetBool(c.NS, doctl.ArgWaitPropagation)
+	if err != nil {
+		return err
+	}
+
 	r, err := ds.EditRecord(domainName, recordID, drcr)
 	if err != nil {
 		return err
 	}
 
-	return displayDomainRecords(c, *r)
+	if err := displayDomainRecords(c, *r); err != nil {
+		return err
+	}
+
+	if wait {
+		_, err = waitForPropagation(c, domainName, do.DomainRecords{*r})
+	}
+	return err
 }
 
 func displayDomainRecords(c *CmdConfig, records ...do.DomainRecord) error {
-	// Check the format flag to determine if the displayer should use the short
-	// layout of the record display.The short version is used by default, but to format
-	// output that includes columns not in the short layout we need the full version.
-	var short = true
-	format, err := c.Doit.GetStringSlice(c.NS, doctl.ArgFormat)
+	short, err := shortDomainRecords(c)
 	if err != nil {
 		return err
 	}
-	if len(format) > 0 {
-		short = false
-	}
 
 	item := &displayers.DomainRecord{DomainRecords: do.DomainRecords(records), Short: short}
 	return c.Display(item)
 }
+
+// shortDomainRecords checks the format flag to determine if the displayer
+// should use the short layout of the record display. The short version is used
+// by default, but to format output that includes columns not in the short
+// layout we need the full version.
+func shortDomainRecords(c *CmdConfig) (bool, error) {
+	format, err := c.Doit.GetStringSlice(c.NS, doctl.ArgFormat)
+	if err != nil {
+		return false, err
+	}
+
+	return len(format) == 0, nil
+}
@@ -14,12 +14,15 @@ limitations under the License.
 package commands
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
 	"github.com/digitalocean/doctl"
 	"github.com/digitalocean/doctl/do"
 	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
 )
 
 var (
@@ -97,7 +100,10 @@ func TestDomainsGet_RequiredArguments(t *testing.T) {
 
 func TestRecordsList(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
-		tm.domains.EXPECT().Records("example.com").Return(testRecordList, nil)
+		it := do.NewIterator(context.Background(), func(context.Context, *godo.ListOptions) ([]do.DomainRecord, *godo.Response, error) {
+			return testRecordList, &godo.Response{}, nil
+		})
+		tm.domains.EXPECT().RecordsIter(gomock.Any(), "example.com").Return(it)
 
 		config.Args = append(config.Args, "example.com")
 
@@ -106,6 +112,73 @@ func TestRecordsList(t *testing.T) {
 	})
 }
 
+func TestRecordsList_StreamsPages(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=2"}}}
+		it := do.NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]do.DomainRecord, *godo.Response, error) {
+			r := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: opt.Page, Type: "A", Name: "www", Data: "1.2.3.4"}}
+			return []do.DomainRecord{r}, resp, nil
+		})
+		tm.domains.EXPECT().RecordsIter(gomock.Any(), "example.com").Return(it)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgFormat, "ID,Type")
+		config.Doit.Set(config.NS, doctl.ArgNoHeader, true)
+
+		err := RunRecordList(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "1    A\n2    A\n", buf.String())
+	})
+}
+
+func TestRecordsList_AlignsPages(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		names := []string{"www", "a-much-longer-name"}
+		resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=2"}}}
+		it := do.NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]do.DomainRecord, *godo.Response, error) {
+			r := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: opt.Page, Type: "A", Name: names[opt.Page-1], Data: "1.2.3.4"}}
+			return []do.DomainRecord{r}, resp, nil
+		})
+		tm.domains.EXPECT().RecordsIter(gomock.Any(), "example.com").Return(it)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgFormat, "Name,Type")
+
+		err := RunRecordList(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "Name                  Type\nwww                   A\na-much-longer-name    A\n", buf.String())
+	})
+}
+
+func TestRecordsList_MaxItems(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		var fetched []int
+		resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=2"}}}
+		it := do.NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]do.DomainRecord, *godo.Response, error) {
+			fetched = append(fetched, opt.Page)
+			r := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: opt.Page, Type: "A", Name: "www", Data: "1.2.3.4"}}
+			return []do.DomainRecord{r}, resp, nil
+		})
+		tm.domains.EXPECT().RecordsIter(gomock.Any(), "example.com").Return(it)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doctl.ArgFormat, "ID,Type")
+		config.Doit.Set(config.NS, doctl.ArgNoHeader, true)
+		config.Doit.Set(config.NS, doctl.ArgMaxItems, 1)
+
+		err := RunRecordList(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "1    A\n", buf.String())
+		assert.Equal(t, []int{1}, fetched)
+	})
+}
+
 func TestRecordList_RequiredArguments(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		err := RunRecordList(config)
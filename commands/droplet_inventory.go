@@ -0,0 +1,432 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/fatih/color"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	inventoryFormatSSHConfig   = "ssh-config"
+	inventoryFormatAnsibleINI  = "ansible-ini"
+	inventoryFormatAnsibleYAML = "ansible-yaml"
+	inventoryFormatJSON        = "json"
+
+	inventoryGroupTag    = "tag"
+	inventoryGroupRegion = "region"
+	inventoryGroupVPC    = "vpc"
+
+	// inventoryGroup is the Ansible group of every Droplet.
+	inventoryGroup = "digitalocean"
+
+	inventoryBegin = "# BEGIN doctl compute droplet inventory"
+	inventoryEnd   = "# END doctl compute droplet inventory"
+)
+
+var (
+	inventoryFormats = []string{inventoryFormatSSHConfig, inventoryFormatAnsibleINI, inventoryFormatAnsibleYAML, inventoryFormatJSON}
+	inventoryGroups  = []string{inventoryGroupTag, inventoryGroupRegion, inventoryGroupVPC}
+
+	ansibleGroupRE = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// inventoryHost is a Droplet in an inventory.
+type inventoryHost struct {
+	Name    string
+	Address string
+	User    string
+	Groups  []string
+}
+
+func dropletInventory(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunDropletInventory, "inventory", "Generate an SSH config or Ansible inventory of your Droplets", `Generates an inventory of your Droplets, for use as an SSH config file or an Ansible inventory. The `+"`"+`--format`+"`"+` flag selects between:
+
+- `+"`"+`ssh-config`+"`"+`: a `+"`"+`Host`+"`"+` entry for each Droplet
+- `+"`"+`ansible-ini`+"`"+`: an Ansible inventory in the INI format
+- `+"`"+`ansible-yaml`+"`"+`: an Ansible inventory in the YAML format
+- `+"`"+`json`+"`"+`: the JSON format of Ansible dynamic inventory scripts
+
+In Ansible inventories, every Droplet is in the `+"`"+`digitalocean`+"`"+` group, and in groups named after its tags, region and VPC, such as `+"`"+`tag_web`+"`"+`, `+"`"+`region_nyc3`+"`"+` and `+"`"+`vpc_default_nyc3`+"`"+`.
+
+With `+"`"+`--file`+"`"+`, the inventory is written to a block of the file delimited by `+"`"+`# BEGIN doctl compute droplet inventory`+"`"+` and `+"`"+`# END doctl compute droplet inventory`+"`"+` comments. The block is added to the end of the file the first time, and replaced on later runs, leaving the rest of the file untouched.`, Writer)
+	AddStringFlag(cmd, doctl.ArgFormat, "", inventoryFormatSSHConfig, "The format of the inventory. Possible values: "+strings.Join(inventoryFormats, ", "))
+	AddStringFlag(cmd, doctl.ArgInventoryFile, "", "", "The file to update with the inventory, instead of writing it to stdout")
+	AddStringSliceFlag(cmd, doctl.ArgInventoryGroupBy, "", inventoryGroups, "The attributes to group Droplets by in Ansible inventories. Possible values: "+strings.Join(inventoryGroups, ", "))
+	AddStringFlag(cmd, doctl.ArgTag, "", "", "Only include the Droplets with this tag")
+	AddBoolFlag(cmd, doctl.ArgsSSHPrivateIP, "", false, "Use the private IP addresses of the Droplets")
+	AddStringFlag(cmd, doctl.ArgSSHUser, "", "", "The SSH user of the Droplets. By default, root, or core for Fedora CoreOS")
+	AddStringFlag(cmd, doctl.ArgsSSHKeyPath, "", "", "The path to the SSH private key to use")
+	AddIntFlag(cmd, doctl.ArgsSSHPort, "", 22, "The port sshd is running on")
+	cmd.Example = `The following example adds the Droplets tagged ` + "`" + `web` + "`" + ` to your SSH config, using their private IP addresses: doctl compute droplet inventory --tag web --ssh-private-ip --file ~/.ssh/config`
+
+	return cmd
+}
+
+// RunDropletInventory generates an inventory of Droplets.
+func RunDropletInventory(c *CmdConfig) error {
+	format, err := c.Doit.GetString(c.NS, doctl.ArgFormat)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(inventoryFormats, format) {
+		return fmt.Errorf("unsupported format %q, must be one of: %s", format, strings.Join(inventoryFormats, ", "))
+	}
+
+	path, err := c.Doit.GetString(c.NS, doctl.ArgInventoryFile)
+	if err != nil {
+		return err
+	}
+	if path != "" && format == inventoryFormatJSON {
+		return errors.New("the json format cannot be written to a file with --file, redirect the output instead")
+	}
+
+	groupBy, err := c.Doit.GetStringSlice(c.NS, doctl.ArgInventoryGroupBy)
+	if err != nil {
+		return err
+	}
+	for _, g := range groupBy {
+		if !slices.Contains(inventoryGroups, g) {
+			return fmt.Errorf("unsupported group %q, must be one of: %s", g, strings.Join(inventoryGroups, ", "))
+		}
+	}
+
+	tag, err := c.Doit.GetString(c.NS, doctl.ArgTag)
+	if err != nil {
+		return err
+	}
+
+	privateIP, err := c.Doit.GetBool(c.NS, doctl.ArgsSSHPrivateIP)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.Doit.GetString(c.NS, doctl.ArgSSHUser)
+	if err != nil {
+		return err
+	}
+
+	keyPath, err := c.Doit.GetString(c.NS, doctl.ArgsSSHKeyPath)
+	if err != nil {
+		return err
+	}
+
+	port, err := c.Doit.GetInt(c.NS, doctl.ArgsSSHPort)
+	if err != nil {
+		return err
+	}
+
+	var droplets do.Droplets
+	if tag != "" {
+		droplets, err = c.Droplets().ListByTag(tag)
+	} else {
+		droplets, err = c.Droplets().List()
+	}
+	if err != nil {
+		return err
+	}
+
+	vpcNames := map[string]string{}
+	if slices.Contains(groupBy, inventoryGroupVPC) {
+		vpcs, err := c.VPCs().List()
+		if err != nil {
+			return err
+		}
+		for _, v := range vpcs {
+			vpcNames[v.ID] = v.Name
+		}
+	}
+
+	hosts := inventoryHosts(droplets, groupBy, vpcNames, privateIP, user)
+
+	var b bytes.Buffer
+	switch format {
+	case inventoryFormatSSHConfig:
+		writeSSHConfig(&b, hosts, port, keyPath)
+	case inventoryFormatAnsibleINI:
+		writeAnsibleINI(&b, hosts, port, keyPath)
+	case inventoryFormatAnsibleYAML:
+		err = writeAnsibleYAML(&b, hosts, port, keyPath)
+	case inventoryFormatJSON:
+		err = writeAnsibleJSON(&b, hosts, port, keyPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err := c.Out.Write(b.Bytes())
+		return err
+	}
+
+	return updateInventoryFile(path, b.String())
+}
+
+// inventoryHosts builds the hosts of an inventory, sorted by name.
+func inventoryHosts(droplets do.Droplets, groupBy []string, vpcNames map[string]string, privateIP bool, user string) []inventoryHost {
+	names := map[string]int{}
+	for _, d := range droplets {
+		names[d.Name]++
+	}
+
+	var hosts []inventoryHost
+	for _, d := range droplets {
+		address, _ := privateIPElsePub(&d, privateIP)
+		if address == "" {
+			kind := "public"
+			if privateIP {
+				kind = "private"
+			}
+			// warnings go to stderr to keep the inventory on stdout usable
+			fmt.Fprintf(color.Error, "%s: Skipping Droplet %s, which has no %s IPv4 address.\n", colorWarn, d.Name, kind)
+			continue
+		}
+
+		h := inventoryHost{Name: d.Name, Address: address, User: user}
+		if names[d.Name] > 1 {
+			// Droplets sharing a name are told apart by their ID
+			h.Name = d.Name + "-" + strconv.Itoa(d.ID)
+		}
+		if h.User == "" && d.Image != nil {
+			h.User = defaultSSHUser(&d)
+		}
+
+		for _, g := range groupBy {
+			switch g {
+			case inventoryGroupTag:
+				for _, t := range d.Tags {
+					h.Groups = append(h.Groups, ansibleGroup("tag", t))
+				}
+			case inventoryGroupRegion:
+				if d.Region != nil {
+					h.Groups = append(h.Groups, ansibleGroup("region", d.Region.Slug))
+				}
+			case inventoryGroupVPC:
+				if name := vpcNames[d.VPCUUID]; name != "" {
+					h.Groups = append(h.Groups, ansibleGroup("vpc", name))
+				}
+			}
+		}
+
+		hosts = append(hosts, h)
+	}
+
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	return hosts
+}
+
+// ansibleGroup names a group with the characters Ansible accepts.
+func ansibleGroup(kind, name string) string {
+	return ansibleGroupRE.ReplaceAllString(kind+"_"+name, "_")
+}
+
+// inventoryGroupHosts returns the names of the hosts of each group.
+func inventoryGroupHosts(hosts []inventoryHost) map[string][]string {
+	groups := map[string][]string{}
+	for _, h := range hosts {
+		groups[inventoryGroup] = append(groups[inventoryGroup], h.Name)
+		for _, g := range h.Groups {
+			groups[g] = append(groups[g], h.Name)
+		}
+	}
+	return groups
+}
+
+func ansibleHostVars(h inventoryHost, port int, keyPath string) map[string]any {
+	vars := map[string]any{"ansible_host": h.Address}
+	if h.User != "" {
+		vars["ansible_user"] = h.User
+	}
+	if port != 0 && port != 22 {
+		vars["ansible_port"] = port
+	}
+	if keyPath != "" {
+		vars["ansible_ssh_private_key_file"] = keyPath
+	}
+	return vars
+}
+
+func writeSSHConfig(w io.Writer, hosts []inventoryHost, port int, keyPath string) {
+	for i, h := range hosts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Host %s\n", h.Name)
+		fmt.Fprintf(w, "  HostName %s\n", h.Address)
+		if h.User != "" {
+			fmt.Fprintf(w, "  User %s\n", h.User)
+		}
+		if port != 0 && port != 22 {
+			fmt.Fprintf(w, "  Port %d\n", port)
+		}
+		if keyPath != "" {
+			fmt.Fprintf(w, "  IdentityFile %s\n", keyPath)
+		}
+	}
+}
+
+func writeAnsibleINI(w io.Writer, hosts []inventoryHost, port int, keyPath string) {
+	fmt.Fprintf(w, "[%s]\n", inventoryGroup)
+	for _, h := range hosts {
+		vars := ansibleHostVars(h, port, keyPath)
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		line := []string{h.Name}
+		for _, k := range keys {
+			line = append(line, fmt.Sprintf("%s=%v", k, vars[k]))
+		}
+		fmt.Fprintln(w, strings.Join(line, " "))
+	}
+
+	groups := inventoryGroupHosts(hosts)
+	for _, g := range sortedKeys(groups) {
+		if g == inventoryGroup {
+			continue
+		}
+		fmt.Fprintf(w, "\n[%s]\n", g)
+		for _, h := range groups[g] {
+			fmt.Fprintln(w, h)
+		}
+	}
+}
+
+func writeAnsibleYAML(w io.Writer, hosts []inventoryHost, port int, keyPath string) error {
+	hostVars := map[string]any{}
+	for _, h := range hosts {
+		hostVars[h.Name] = ansibleHostVars(h, port, keyPath)
+	}
+	group := map[string]any{"hosts": hostVars}
+
+	children := map[string]any{}
+	for g, names := range inventoryGroupHosts(hosts) {
+		if g == inventoryGroup {
+			continue
+		}
+		members := map[string]any{}
+		for _, name := range names {
+			members[name] = map[string]any{}
+		}
+		children[g] = map[string]any{"hosts": members}
+	}
+	if len(children) > 0 {
+		group["children"] = children
+	}
+
+	b, err := yaml.Marshal(map[string]any{inventoryGroup: group})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func writeAnsibleJSON(w io.Writer, hosts []inventoryHost, port int, keyPath string) error {
+	hostVars := map[string]any{}
+	for _, h := range hosts {
+		hostVars[h.Name] = ansibleHostVars(h, port, keyPath)
+	}
+
+	inventory := map[string]any{
+		"_meta": map[string]any{"hostvars": hostVars},
+	}
+	for g, names := range inventoryGroupHosts(hosts) {
+		inventory[g] = map[string]any{"hosts": names}
+	}
+
+	b, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// updateInventoryFile writes an inventory to the managed block of a file,
+// creating the file if needed.
+func updateInventoryFile(path, inventory string) error {
+	mode := os.FileMode(0600)
+	existing, err := os.ReadFile(path)
+	if err == nil {
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	content, err := updateManagedBlock(string(existing), inventory)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return os.WriteFile(path, []byte(content), mode)
+}
+
+// updateManagedBlock replaces the managed block of a file's content with
+// block, or appends it when the content has none.
+func updateManagedBlock(content, block string) (string, error) {
+	if block != "" && !strings.HasSuffix(block, "\n") {
+		block += "\n"
+	}
+	managed := inventoryBegin + "\n" + block + inventoryEnd + "\n"
+
+	start := strings.Index(content, inventoryBegin)
+	if start < 0 {
+		if content != "" {
+			if !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			content += "\n"
+		}
+		return content + managed, nil
+	}
+
+	end := strings.Index(content[start:], inventoryEnd)
+	if end < 0 {
+		return "", fmt.Errorf("found %q without a matching %q", inventoryBegin, inventoryEnd)
+	}
+	end += start + len(inventoryEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+
+	return content[:start] + managed + content[end:], nil
+}
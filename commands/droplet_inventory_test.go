@@ -0,0 +1,282 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var inventoryDroplets = do.Droplets{
+	{Droplet: &godo.Droplet{
+		ID:      2,
+		Name:    "web-2",
+		Image:   &godo.Image{Slug: "ubuntu-24-04-x64"},
+		Region:  &godo.Region{Slug: "nyc3"},
+		Tags:    []string{"web", "team:blue"},
+		VPCUUID: "vpc-1",
+		Networks: &godo.Networks{V4: []godo.NetworkV4{
+			{IPAddress: "203.0.113.2", Type: "public"},
+			{IPAddress: "10.10.0.2", Type: "private"},
+		}},
+	}},
+	{Droplet: &godo.Droplet{
+		ID:      1,
+		Name:    "db-1",
+		Image:   &godo.Image{Slug: "fedora-coreos"},
+		Region:  &godo.Region{Slug: "sfo3"},
+		VPCUUID: "vpc-1",
+		Networks: &godo.Networks{V4: []godo.NetworkV4{
+			{IPAddress: "203.0.113.1", Type: "public"},
+			{IPAddress: "10.10.0.1", Type: "private"},
+		}},
+	}},
+}
+
+func runInventory(t *testing.T, format string, set func(config *CmdConfig)) string {
+	var out string
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.EXPECT().List().Return(inventoryDroplets, nil)
+		tm.vpcs.EXPECT().List().Return(do.VPCs{{VPC: &godo.VPC{ID: "vpc-1", Name: "prod-net"}}}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgFormat, format)
+		config.Doit.Set(config.NS, doctl.ArgInventoryGroupBy, inventoryGroups)
+		if set != nil {
+			set(config)
+		}
+
+		require.NoError(t, RunDropletInventory(config))
+		out = buf.String()
+	})
+	return out
+}
+
+func TestDropletInventorySSHConfig(t *testing.T) {
+	out := runInventory(t, inventoryFormatSSHConfig, func(config *CmdConfig) {
+		config.Doit.Set(config.NS, doctl.ArgsSSHPrivateIP, true)
+		config.Doit.Set(config.NS, doctl.ArgsSSHPort, 2222)
+	})
+
+	expected := `Host db-1
+  HostName 10.10.0.1
+  User core
+  Port 2222
+
+Host web-2
+  HostName 10.10.0.2
+  User root
+  Port 2222
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventoryAnsibleINI(t *testing.T) {
+	out := runInventory(t, inventoryFormatAnsibleINI, nil)
+
+	expected := `[digitalocean]
+db-1 ansible_host=203.0.113.1 ansible_user=core
+web-2 ansible_host=203.0.113.2 ansible_user=root
+
+[region_nyc3]
+web-2
+
+[region_sfo3]
+db-1
+
+[tag_team_blue]
+web-2
+
+[tag_web]
+web-2
+
+[vpc_prod_net]
+db-1
+web-2
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventoryAnsibleYAML(t *testing.T) {
+	out := runInventory(t, inventoryFormatAnsibleYAML, func(config *CmdConfig) {
+		config.Doit.Set(config.NS, doctl.ArgSSHUser, "sammy")
+		config.Doit.Set(config.NS, doctl.ArgsSSHKeyPath, "~/.ssh/do")
+	})
+
+	expected := `digitalocean:
+  children:
+    region_nyc3:
+      hosts:
+        web-2: {}
+    region_sfo3:
+      hosts:
+        db-1: {}
+    tag_team_blue:
+      hosts:
+        web-2: {}
+    tag_web:
+      hosts:
+        web-2: {}
+    vpc_prod_net:
+      hosts:
+        db-1: {}
+        web-2: {}
+  hosts:
+    db-1:
+      ansible_host: 203.0.113.1
+      ansible_ssh_private_key_file: ~/.ssh/do
+      ansible_user: sammy
+    web-2:
+      ansible_host: 203.0.113.2
+      ansible_ssh_private_key_file: ~/.ssh/do
+      ansible_user: sammy
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventoryJSON(t *testing.T) {
+	out := runInventory(t, inventoryFormatJSON, nil)
+
+	expected := `{
+  "_meta": {
+    "hostvars": {
+      "db-1": {
+        "ansible_host": "203.0.113.1",
+        "ansible_user": "core"
+      },
+      "web-2": {
+        "ansible_host": "203.0.113.2",
+        "ansible_user": "root"
+      }
+    }
+  },
+  "digitalocean": {
+    "hosts": [
+      "db-1",
+      "web-2"
+    ]
+  },
+  "region_nyc3": {
+    "hosts": [
+      "web-2"
+    ]
+  },
+  "region_sfo3": {
+    "hosts": [
+      "db-1"
+    ]
+  },
+  "tag_team_blue": {
+    "hosts": [
+      "web-2"
+    ]
+  },
+  "tag_web": {
+    "hosts": [
+      "web-2"
+    ]
+  },
+  "vpc_prod_net": {
+    "hosts": [
+      "db-1",
+      "web-2"
+    ]
+  }
+}
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte("Host *\n  ServerAliveInterval 30\n"), 0644))
+
+	for i := 0; i < 2; i++ {
+		out := runInventory(t, inventoryFormatSSHConfig, func(config *CmdConfig) {
+			config.Doit.Set(config.NS, doctl.ArgInventoryFile, path)
+		})
+		assert.Empty(t, out)
+	}
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	expected := `Host *
+  ServerAliveInterval 30
+
+# BEGIN doctl compute droplet inventory
+Host db-1
+  HostName 203.0.113.1
+  User core
+
+Host web-2
+  HostName 203.0.113.2
+  User root
+# END doctl compute droplet inventory
+`
+	assert.Equal(t, expected, string(b))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestUpdateManagedBlock(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "empty file",
+			expected: inventoryBegin + "\nnew\n" + inventoryEnd + "\n",
+		},
+		{
+			name:     "no block",
+			content:  "user",
+			expected: "user\n\n" + inventoryBegin + "\nnew\n" + inventoryEnd + "\n",
+		},
+		{
+			name:     "block between user entries",
+			content:  "before\n" + inventoryBegin + "\nold\n" + inventoryEnd + "\nafter\n",
+			expected: "before\n" + inventoryBegin + "\nnew\n" + inventoryEnd + "\nafter\n",
+		},
+		{
+			name:    "unterminated block",
+			content: inventoryBegin + "\nold\n",
+			err:     `found "# BEGIN doctl compute droplet inventory" without a matching "# END doctl compute droplet inventory"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			content, err := updateManagedBlock(c.content, "new")
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, content)
+		})
+	}
+}
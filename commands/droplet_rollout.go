@@ -0,0 +1,296 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+)
+
+const (
+	rolloutStatusPending  = "pending"
+	rolloutStatusReplaced = "replaced"
+	rolloutStatusFailed   = "failed"
+
+	defaultRolloutHealthTimeout = 5 * time.Minute
+)
+
+var (
+	// rolloutPollInterval and actionPollSeconds are variables so tests can
+	// replace them.
+	rolloutPollInterval = 5 * time.Second
+	actionPollSeconds   = 5
+
+	rolloutHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+func dropletRollout(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunDropletRollout, "rollout", "Rebuild the Droplets with a tag from an image, a few at a time", `Rebuilds every Droplet with the tag given with `+"`"+`--tag`+"`"+` from the image given with `+"`"+`--image`+"`"+`, `+"`"+`--max-unavailable`+"`"+` Droplets at a time. For each group of Droplets, the command:
+
+- removes the Droplets from the load balancer given with `+"`"+`--lb`+"`"+`, if any
+- rebuilds the Droplets and waits for the rebuild actions to complete
+- waits for `+"`"+`--health-url`+"`"+`, if set, to answer with a 2xx status for each Droplet, replacing `+"`"+`{ip}`+"`"+` in the URL with the Droplet's public IPv4 address
+- adds the Droplets back to the load balancer
+
+The rollout stops at the first failure. A Droplet that fails is left out of the load balancer, and the Droplets not yet rebuilt are left untouched. The status of each Droplet is printed when the rollout ends.
+
+Rebuilding a Droplet erases its disk. Load balancers that select their Droplets by tag cannot be used, since Droplets cannot be removed from them individually.`, Writer, displayerType(&displayers.RolloutDroplets{}))
+	AddStringFlag(cmd, doctl.ArgTag, "", "", "The tag of the Droplets to rebuild", requiredOpt())
+	AddStringFlag(cmd, doctl.ArgImage, "", "", "The ID or slug of the image to rebuild the Droplets from", requiredOpt())
+	AddStringFlag(cmd, doctl.ArgRolloutLoadBalancer, "", "", "The ID of a load balancer to remove each Droplet from while it is rebuilt")
+	AddIntFlag(cmd, doctl.ArgRolloutMaxUnavailable, "", 1, "The number of Droplets to rebuild at once")
+	AddStringFlag(cmd, doctl.ArgRolloutHealthURL, "", "", "A URL to check after each rebuild, such as `http://{ip}/healthz`. `{ip}` is replaced with the Droplet's public IPv4 address")
+	AddDurationFlag(cmd, doctl.ArgTimeout, "", defaultRolloutHealthTimeout, "How long to wait for each Droplet to pass the health check")
+	AddBoolFlag(cmd, doctl.ArgForce, doctl.ArgShortForce, false, "Rebuild the Droplets without a confirmation prompt")
+	cmd.Example = `The following example rebuilds the Droplets tagged ` + "`" + `web` + "`" + ` from a snapshot with the ID ` + "`" + `155372345` + "`" + ` one at a time, draining each from a load balancer and checking its ` + "`" + `/healthz` + "`" + ` endpoint before putting it back: doctl compute droplet rollout --tag web --image 155372345 --lb 0a5d2f4c-4d6b-4b5e-8a0e-6b0f0c7d2f01 --health-url http://{ip}/healthz`
+
+	return cmd
+}
+
+// RunDropletRollout rebuilds the Droplets with a tag, a few at a time.
+func RunDropletRollout(c *CmdConfig) error {
+	tag, err := c.Doit.GetString(c.NS, doctl.ArgTag)
+	if err != nil {
+		return err
+	}
+	image, err := c.Doit.GetString(c.NS, doctl.ArgImage)
+	if err != nil {
+		return err
+	}
+	lbID, err := c.Doit.GetString(c.NS, doctl.ArgRolloutLoadBalancer)
+	if err != nil {
+		return err
+	}
+	maxUnavailable, err := c.Doit.GetInt(c.NS, doctl.ArgRolloutMaxUnavailable)
+	if err != nil {
+		return err
+	}
+	if maxUnavailable < 1 {
+		return fmt.Errorf("--%s must be at least 1", doctl.ArgRolloutMaxUnavailable)
+	}
+	healthURL, err := c.Doit.GetString(c.NS, doctl.ArgRolloutHealthURL)
+	if err != nil {
+		return err
+	}
+	timeout, err := c.Doit.GetDuration(c.NS, doctl.ArgTimeout)
+	if err != nil {
+		return err
+	}
+	force, err := c.Doit.GetBool(c.NS, doctl.ArgForce)
+	if err != nil {
+		return err
+	}
+
+	droplets, err := c.Droplets().ListByTag(tag)
+	if err != nil {
+		return err
+	}
+	if len(droplets) == 0 {
+		return fmt.Errorf("no Droplets are tagged %s", tag)
+	}
+
+	var lbDroplets []int
+	if lbID != "" {
+		lb, err := c.LoadBalancers().Get(lbID)
+		if err != nil {
+			return err
+		}
+		if lb.Tag != "" {
+			return fmt.Errorf("load balancer %s selects its Droplets by the tag %s, so they cannot be removed from it individually", lbID, lb.Tag)
+		}
+		lbDroplets = lb.DropletIDs
+	}
+
+	if !force && AskForConfirm(fmt.Sprintf("rebuild %d Droplets tagged %s from the image %s? Their disks will be erased.", len(droplets), tag, image)) != nil {
+		return errOperationAborted
+	}
+
+	r := &rollout{
+		c:          c,
+		image:      image,
+		lbID:       lbID,
+		lbDroplets: lbDroplets,
+		healthURL:  healthURL,
+		timeout:    timeout,
+	}
+	for _, d := range droplets {
+		r.results = append(r.results, displayers.RolloutDroplet{ID: d.ID, Name: d.Name, Status: rolloutStatusPending})
+	}
+
+	for i := 0; i < len(droplets) && err == nil; i += maxUnavailable {
+		err = r.replace(droplets[i:min(i+maxUnavailable, len(droplets))], i)
+	}
+
+	if displayErr := c.Display(&displayers.RolloutDroplets{Droplets: r.results}); displayErr != nil {
+		return displayErr
+	}
+	if err != nil {
+		return fmt.Errorf("rollout stopped: %w", err)
+	}
+	return nil
+}
+
+// rollout is the state of a rollout across batches of Droplets.
+type rollout struct {
+	c          *CmdConfig
+	image      string
+	lbID       string
+	lbDroplets []int
+	healthURL  string
+	timeout    time.Duration
+	results    []displayers.RolloutDroplet
+}
+
+// replace drains, rebuilds, checks and restores a batch of Droplets, whose
+// results start at offset. It returns the first failure, after the Droplets
+// of the batch that did not fail are back in the load balancer.
+func (r *rollout) replace(batch do.Droplets, offset int) error {
+	fail := func(i int, err error) error {
+		r.results[offset+i].Status = rolloutStatusFailed
+		r.results[offset+i].Error = err.Error()
+		return fmt.Errorf("Droplet %s (%d): %w", batch[i].Name, batch[i].ID, err)
+	}
+
+	var drained []int
+	for _, d := range batch {
+		if slices.Contains(r.lbDroplets, d.ID) {
+			drained = append(drained, d.ID)
+		}
+	}
+	if len(drained) > 0 {
+		notice("Removing Droplets %s from load balancer %s", joinInts(drained), r.lbID)
+		if err := r.c.LoadBalancers().RemoveDroplets(r.lbID, drained...); err != nil {
+			for i := range batch {
+				fail(i, fmt.Errorf("removing from load balancer: %w", err))
+			}
+			return fmt.Errorf("removing Droplets %s from load balancer %s: %w", joinInts(drained), r.lbID, err)
+		}
+	}
+
+	das := r.c.DropletActions()
+	actions := make([]*do.Action, len(batch))
+	var firstErr error
+	for i, d := range batch {
+		notice("Rebuilding Droplet %s (%d)", d.Name, d.ID)
+		var err error
+		if imageID, aerr := strconv.Atoi(r.image); aerr == nil {
+			actions[i], err = das.RebuildByImageID(d.ID, imageID)
+		} else {
+			actions[i], err = das.RebuildByImageSlug(d.ID, r.image)
+		}
+		if err != nil {
+			firstErr = fail(i, fmt.Errorf("rebuilding: %w", err))
+			break
+		}
+	}
+
+	for i, d := range batch {
+		if actions[i] == nil {
+			continue
+		}
+
+		a, err := actionWait(r.c, actions[i].ID, actionPollSeconds)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("waiting for rebuild: %w", err)
+		case a.Status != "completed":
+			err = fmt.Errorf("rebuild action %d %s", a.ID, a.Status)
+		default:
+			err = r.checkHealth(d)
+		}
+		if err != nil {
+			if ferr := fail(i, err); firstErr == nil {
+				firstErr = ferr
+			}
+			continue
+		}
+
+		r.results[offset+i].Status = rolloutStatusReplaced
+	}
+
+	// The Droplets not rebuilt after a failure are unchanged, and go back in
+	// the load balancer with the replaced ones.
+	var healthy []int
+	for i, d := range batch {
+		if r.results[offset+i].Status != rolloutStatusFailed && slices.Contains(drained, d.ID) {
+			healthy = append(healthy, d.ID)
+		}
+	}
+	if len(healthy) > 0 {
+		notice("Adding Droplets %s back to load balancer %s", joinInts(healthy), r.lbID)
+		if err := r.c.LoadBalancers().AddDroplets(r.lbID, healthy...); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("adding Droplets %s back to load balancer %s: %w", joinInts(healthy), r.lbID, err)
+		}
+	}
+
+	return firstErr
+}
+
+// checkHealth waits for the health URL of a Droplet to answer with a 2xx
+// status, when one is set.
+func (r *rollout) checkHealth(d do.Droplet) error {
+	if r.healthURL == "" {
+		return nil
+	}
+
+	url := r.healthURL
+	if strings.Contains(url, "{ip}") {
+		ip, err := d.PublicIPv4()
+		if err != nil {
+			return err
+		}
+		if ip == "" {
+			return errors.New("no public IPv4 address for the health check")
+		}
+		url = strings.ReplaceAll(url, "{ip}", ip)
+	}
+
+	deadline := time.Now().Add(r.timeout)
+	for {
+		var lastErr error
+		resp, err := rolloutHTTPClient.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			lastErr = fmt.Errorf("status %s", resp.Status)
+		} else {
+			lastErr = err
+		}
+
+		wait := min(rolloutPollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return fmt.Errorf("health check %s failed after %s: %v", url, r.timeout, lastErr)
+		}
+		time.Sleep(wait)
+	}
+}
+
+func joinInts(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rolloutDroplets(names ...string) do.Droplets {
+	var droplets do.Droplets
+	for i, name := range names {
+		droplets = append(droplets, do.Droplet{Droplet: &godo.Droplet{
+			ID:   i + 1,
+			Name: name,
+			Networks: &godo.Networks{
+				V4: []godo.NetworkV4{{IPAddress: "127.0.0.1", Type: "public"}},
+			},
+		}})
+	}
+	return droplets
+}
+
+func rolloutLoadBalancer(dropletIDs ...int) *do.LoadBalancer {
+	return &do.LoadBalancer{LoadBalancer: &godo.LoadBalancer{ID: "lb-id", DropletIDs: dropletIDs}}
+}
+
+func TestRunDropletRollout(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		var checked int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checked++
+			if r.URL.Path != "/healthz" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		tm.droplets.EXPECT().ListByTag("web").Return(rolloutDroplets("web-1", "web-2", "web-3"), nil)
+		tm.loadBalancers.EXPECT().Get("lb-id").Return(rolloutLoadBalancer(1, 2, 3), nil)
+
+		tm.loadBalancers.EXPECT().RemoveDroplets("lb-id", 1, 2).Return(nil)
+		tm.dropletActions.EXPECT().RebuildByImageID(1, 155372345).Return(&do.Action{Action: &godo.Action{ID: 101, Status: "in-progress"}}, nil)
+		tm.dropletActions.EXPECT().RebuildByImageID(2, 155372345).Return(&do.Action{Action: &godo.Action{ID: 102, Status: "in-progress"}}, nil)
+		tm.actions.EXPECT().Get(101).Return(&do.Action{Action: &godo.Action{ID: 101, Status: "completed"}}, nil)
+		tm.actions.EXPECT().Get(102).Return(&do.Action{Action: &godo.Action{ID: 102, Status: "completed"}}, nil)
+		tm.loadBalancers.EXPECT().AddDroplets("lb-id", 1, 2).Return(nil)
+
+		tm.loadBalancers.EXPECT().RemoveDroplets("lb-id", 3).Return(nil)
+		tm.dropletActions.EXPECT().RebuildByImageID(3, 155372345).Return(&do.Action{Action: &godo.Action{ID: 103, Status: "in-progress"}}, nil)
+		tm.actions.EXPECT().Get(103).Return(&do.Action{Action: &godo.Action{ID: 103, Status: "completed"}}, nil)
+		tm.loadBalancers.EXPECT().AddDroplets("lb-id", 3).Return(nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgImage, "155372345")
+		config.Doit.Set(config.NS, doctl.ArgRolloutLoadBalancer, "lb-id")
+		config.Doit.Set(config.NS, doctl.ArgRolloutMaxUnavailable, 2)
+		config.Doit.Set(config.NS, doctl.ArgRolloutHealthURL, "http://{ip}:"+serverURL.Port()+"/healthz")
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err = RunDropletRollout(config)
+		require.NoError(t, err)
+		assert.Equal(t, 3, checked)
+
+		expected := `ID    Name     Status      Error
+1     web-1    replaced    
+2     web-2    replaced    
+3     web-3    replaced    
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRunDropletRolloutFailure(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.EXPECT().ListByTag("web").Return(rolloutDroplets("web-1", "web-2", "web-3"), nil)
+		tm.loadBalancers.EXPECT().Get("lb-id").Return(rolloutLoadBalancer(1, 2, 3), nil)
+
+		tm.loadBalancers.EXPECT().RemoveDroplets("lb-id", 1, 2).Return(nil)
+		tm.dropletActions.EXPECT().RebuildByImageSlug(1, "ubuntu-24-04-x64").Return(&do.Action{Action: &godo.Action{ID: 101, Status: "in-progress"}}, nil)
+		tm.dropletActions.EXPECT().RebuildByImageSlug(2, "ubuntu-24-04-x64").Return(&do.Action{Action: &godo.Action{ID: 102, Status: "in-progress"}}, nil)
+		tm.actions.EXPECT().Get(101).Return(&do.Action{Action: &godo.Action{ID: 101, Status: "completed"}}, nil)
+		tm.actions.EXPECT().Get(102).Return(&do.Action{Action: &godo.Action{ID: 102, Status: "errored"}}, nil)
+		tm.loadBalancers.EXPECT().AddDroplets("lb-id", 1).Return(nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgImage, "ubuntu-24-04-x64")
+		config.Doit.Set(config.NS, doctl.ArgRolloutLoadBalancer, "lb-id")
+		config.Doit.Set(config.NS, doctl.ArgRolloutMaxUnavailable, 2)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunDropletRollout(config)
+		assert.EqualError(t, err, "rollout stopped: Droplet web-2 (2): rebuild action 102 errored")
+
+		expected := `ID    Name     Status      Error
+1     web-1    replaced    
+2     web-2    failed      rebuild action 102 errored
+3     web-3    pending     
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRunDropletRolloutHealthCheck(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		interval := rolloutPollInterval
+		rolloutPollInterval = time.Millisecond
+		defer func() { rolloutPollInterval = interval }()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		tm.droplets.EXPECT().ListByTag("web").Return(rolloutDroplets("web-1", "web-2"), nil)
+		tm.dropletActions.EXPECT().RebuildByImageID(1, 155372345).Return(&do.Action{Action: &godo.Action{ID: 101, Status: "in-progress"}}, nil)
+		tm.actions.EXPECT().Get(101).Return(&do.Action{Action: &godo.Action{ID: 101, Status: "completed"}}, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgImage, "155372345")
+		config.Doit.Set(config.NS, doctl.ArgRolloutMaxUnavailable, 1)
+		config.Doit.Set(config.NS, doctl.ArgRolloutHealthURL, server.URL)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, 10*time.Millisecond)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunDropletRollout(config)
+		assert.EqualError(t, err, "rollout stopped: Droplet web-1 (1): health check "+server.URL+" failed after 10ms: status 503 Service Unavailable")
+	})
+}
+
+func TestRunDropletRolloutTaggedLoadBalancer(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		lb := rolloutLoadBalancer()
+		lb.Tag = "web"
+		tm.droplets.EXPECT().ListByTag("web").Return(rolloutDroplets("web-1"), nil)
+		tm.loadBalancers.EXPECT().Get("lb-id").Return(lb, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgImage, "155372345")
+		config.Doit.Set(config.NS, doctl.ArgRolloutLoadBalancer, "lb-id")
+		config.Doit.Set(config.NS, doctl.ArgRolloutMaxUnavailable, 1)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := RunDropletRollout(config)
+		assert.EqualError(t, err, "load balancer lb-id selects its Droplets by the tag web, so they cannot be removed from it individually")
+	})
+}
@@ -140,6 +140,9 @@ If you do not specify a region, the Droplet is created in the default region for
 	AddStringSliceFlag(cmdRunDropletUntag, doctl.ArgTagName, "", []string{}, "The tag name to remove from Droplet")
 	cmdRunDropletUntag.Example = `The following example removes the tag ` + "`" + `frontend` + "`" + ` from a Droplet with the ID ` + "`" + `386734086` + "`" + `: doctl compute droplet untag 386734086 --tag-name frontend`
 
+	dropletInventory(cmd)
+	dropletRollout(cmd)
+
 	cmd.AddCommand(dropletOneClicks())
 	cmd.AddCommand(dropletBackupPolicies())
 
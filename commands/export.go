@@ -0,0 +1,395 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+)
+
+// exportDocument is a document of an exported stack file.
+type exportDocument struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Spec any    `json:"spec"`
+}
+
+// Export creates the export command and adds it to parent.
+func Export(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunExport, "export", "Export existing resources to a stack file", `Writes the VPCs, volumes, Droplets, cloud firewalls, load balancers and domains of your account to a stack file that `+"`"+`doctl apply`+"`"+` can read, so you can keep an environment in version control or reproduce it in another account.
+
+Fields generated by DigitalOcean, such as IDs, IP addresses, statuses and creation dates, are left out. Resources reference their VPC and Droplets by name instead of ID, so resources of a kind must have unique names to be exported. Default VPCs, and the SOA and NS records of domains, are not exported as they are created automatically.
+
+With `+"`"+`--project-id`+"`"+`, only the resources assigned to the project are exported, along with the firewalls applied to its Droplets and the VPCs they use.
+
+The stack file is written in YAML, or as a JSON list of documents with `+"`"+`--output json`+"`"+`. `+"`"+`doctl apply`+"`"+` reads both.`, Writer)
+	cmd.GroupID = manageResourcesGroup
+	AddStringFlag(cmd, doctl.ArgProjectID, "", "", "The ID of a project to export the resources of")
+	cmd.Example = `The following example exports the resources of your account to ` + "`" + `stack.yaml` + "`" + `: doctl export > stack.yaml`
+
+	return cmd
+}
+
+// RunExport writes the resources of an account to a stack file.
+func RunExport(c *CmdConfig) error {
+	projectID, err := c.Doit.GetString(c.NS, doctl.ArgProjectID)
+	if err != nil {
+		return err
+	}
+
+	e := &exporter{
+		vpcs:          c.VPCs(),
+		volumes:       c.Volumes(),
+		droplets:      c.Droplets(),
+		firewalls:     c.Firewalls(),
+		loadBalancers: c.LoadBalancers(),
+		domains:       c.Domains(),
+	}
+
+	if projectID != "" {
+		resources, err := c.Projects().ListResources(projectID)
+		if err != nil {
+			return err
+		}
+
+		e.urns = map[string]bool{}
+		for _, r := range resources {
+			e.urns[r.URN] = true
+		}
+	}
+
+	docs, err := e.export()
+	if err != nil {
+		return err
+	}
+
+	return writeStack(c.Out, docs, viper.GetString("output") == "json")
+}
+
+// exporter builds stack documents from the live state of an account.
+type exporter struct {
+	vpcs          do.VPCsService
+	volumes       do.VolumesService
+	droplets      do.DropletsService
+	firewalls     do.FirewallsService
+	loadBalancers do.LoadBalancersService
+	domains       do.DomainsService
+
+	// urns limits the export to the resources of a project when set.
+	urns map[string]bool
+}
+
+func (e *exporter) inProject(kind, id string) bool {
+	return e.urns == nil || e.urns["do:"+kind+":"+id]
+}
+
+func (e *exporter) export() ([]exportDocument, error) {
+	vpcs, err := e.vpcs.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// resources reference VPCs by name, which unlike IDs are kept in
+	// another account. Default VPCs are created automatically, resources
+	// using them do not need to reference them.
+	vpcNames := map[string]string{}
+	for _, v := range vpcs {
+		if !v.Default {
+			vpcNames[v.ID] = v.Name
+		}
+	}
+	usedVPCs := map[string]bool{}
+	useVPC := func(id string) string {
+		if vpcNames[id] == "" {
+			return ""
+		}
+		usedVPCs[id] = true
+		return vpcNames[id]
+	}
+
+	var docs []exportDocument
+
+	volumes, err := e.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		if !e.inProject("volume", v.ID) {
+			continue
+		}
+
+		spec := volumeSpec{
+			SizeGigaBytes:   v.SizeGigaBytes,
+			FilesystemType:  v.FilesystemType,
+			FilesystemLabel: v.FilesystemLabel,
+			Description:     v.Description,
+			Tags:            v.Tags,
+		}
+		if v.Region != nil {
+			spec.Region = v.Region.Slug
+		}
+		docs = append(docs, exportDocument{Kind: stackKindVolume, Name: v.Name, Spec: spec})
+	}
+
+	droplets, err := e.droplets.List()
+	if err != nil {
+		return nil, err
+	}
+	// firewalls and load balancers reference the exported Droplets by name
+	dropletNames := map[int]string{}
+	dropletTags := map[string]bool{}
+	for _, d := range droplets {
+		if !e.inProject("droplet", strconv.Itoa(d.ID)) {
+			continue
+		}
+		dropletNames[d.ID] = d.Name
+		for _, t := range d.Tags {
+			dropletTags[t] = true
+		}
+
+		spec := dropletSpec{
+			Size:       d.SizeSlug,
+			Tags:       d.Tags,
+			VPC:        useVPC(d.VPCUUID),
+			Backups:    slices.Contains(d.Features, "backups"),
+			IPv6:       slices.Contains(d.Features, "ipv6"),
+			Monitoring: slices.Contains(d.Features, "monitoring"),
+		}
+		if d.Region != nil {
+			spec.Region = d.Region.Slug
+		}
+		if d.Image != nil {
+			spec.Image = d.Image.Slug
+			if spec.Image == "" {
+				spec.Image = strconv.Itoa(d.Image.ID)
+			}
+		}
+		docs = append(docs, exportDocument{Kind: stackKindDroplet, Name: d.Name, Spec: spec})
+	}
+
+	firewalls, err := e.firewalls.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range firewalls {
+		names := exportedDroplets(f.DropletIDs, dropletNames)
+		if e.urns != nil && len(names) == 0 && !slices.ContainsFunc(f.Tags, func(t string) bool { return dropletTags[t] }) {
+			continue
+		}
+
+		spec, err := specMap(f.Firewall, "id", "name", "status", "created_at", "pending_changes", "droplet_ids")
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 {
+			spec["droplets"] = names
+		}
+		docs = append(docs, exportDocument{Kind: stackKindFirewall, Name: f.Name, Spec: spec})
+	}
+
+	lbs, err := e.loadBalancers.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range lbs {
+		if !e.inProject("loadbalancer", lb.ID) {
+			continue
+		}
+
+		spec, err := specMap(lb.AsRequest(), "name", "project_id", "vpc_uuid", "droplet_ids")
+		if err != nil {
+			return nil, err
+		}
+		if vpc := useVPC(lb.VPCUUID); vpc != "" {
+			spec["vpc"] = vpc
+		}
+		// with a tag, the Droplets are selected by it
+		if names := exportedDroplets(lb.DropletIDs, dropletNames); lb.Tag == "" && len(names) > 0 {
+			spec["droplets"] = names
+		}
+		docs = append(docs, exportDocument{Kind: stackKindLoadBalancer, Name: lb.Name, Spec: spec})
+	}
+
+	domains, err := e.domains.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if !e.inProject("domain", d.Name) {
+			continue
+		}
+
+		records, err := e.domains.Records(d.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var spec domainSpec
+		for _, r := range records {
+			if r.Type == "SOA" || (r.Type == "NS" && r.Name == "@") {
+				continue
+			}
+			spec.Records = append(spec.Records, domainRecordSpec{
+				Type:     r.Type,
+				Name:     r.Name,
+				Data:     r.Data,
+				Priority: r.Priority,
+				Port:     r.Port,
+				TTL:      r.TTL,
+				Weight:   r.Weight,
+				Flags:    r.Flags,
+				Tag:      r.Tag,
+			})
+		}
+		docs = append(docs, exportDocument{Kind: stackKindDomain, Name: d.Name, Spec: spec})
+	}
+
+	for _, v := range vpcs {
+		if v.Default || (e.urns != nil && !usedVPCs[v.ID] && !e.inProject("vpc", v.ID)) {
+			continue
+		}
+		docs = append(docs, exportDocument{
+			Kind: stackKindVPC,
+			Name: v.Name,
+			Spec: vpcSpec{Region: v.RegionSlug, IPRange: v.IPRange, Description: v.Description},
+		})
+	}
+
+	// apply identifies resources by name
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		key := doc.Kind + "/" + doc.Name
+		if seen[key] {
+			return nil, fmt.Errorf("%s %s: more than one resource of this kind has this name, rename them before exporting", doc.Kind, doc.Name)
+		}
+		seen[key] = true
+	}
+
+	kindOrder := func(kind string) int { return slices.Index(stackKinds, kind) }
+	sort.SliceStable(docs, func(i, j int) bool {
+		if docs[i].Kind != docs[j].Kind {
+			return kindOrder(docs[i].Kind) < kindOrder(docs[j].Kind)
+		}
+		return docs[i].Name < docs[j].Name
+	})
+
+	return docs, nil
+}
+
+// exportedDroplets returns the names of the exported Droplets among ids.
+func exportedDroplets(ids []int, names map[int]string) []string {
+	var exported []string
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			exported = append(exported, name)
+		}
+	}
+	return exported
+}
+
+// specMap converts a resource to a spec, leaving out the given fields and
+// every empty value.
+func specMap(v any, strip ...string) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	for _, key := range strip {
+		delete(m, key)
+	}
+
+	pruned, _ := pruneEmpty(m).(map[string]any)
+	if pruned == nil {
+		pruned = map[string]any{}
+	}
+	return pruned, nil
+}
+
+func pruneEmpty(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if p := pruneEmpty(item); p == nil {
+				delete(val, k)
+			} else {
+				val[k] = p
+			}
+		}
+		if len(val) == 0 {
+			return nil
+		}
+		return val
+	case []any:
+		var out []any
+		for _, item := range val {
+			if p := pruneEmpty(item); p != nil {
+				out = append(out, p)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	default:
+		if isZeroValue(val) {
+			return nil
+		}
+		return val
+	}
+}
+
+// writeStack writes stack documents as multi-document YAML, or as a JSON
+// list.
+func writeStack(out io.Writer, docs []exportDocument, asJSON bool) error {
+	if docs == nil {
+		docs = []exportDocument{}
+	}
+
+	if asJSON {
+		b, err := json.MarshalIndent(docs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+
+	parts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, string(b))
+	}
+
+	_, err := io.WriteString(out, strings.Join(parts, "---\n"))
+	return err
+}
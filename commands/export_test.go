@@ -0,0 +1,217 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectExportLists(tm *tcMocks) {
+	droplets, firewalls, lbs, records := liveStack()
+	droplets[0].VPCUUID = "vpc-default"
+	droplets[0].Features = []string{"monitoring"}
+	droplets = append(droplets, do.Droplet{Droplet: &godo.Droplet{
+		ID:       2,
+		Name:     "db-1",
+		SizeSlug: "s-2vcpu-4gb",
+		Region:   &godo.Region{Slug: "nyc3"},
+		Image:    &godo.Image{ID: 4242},
+		VPCUUID:  "vpc-private",
+	}})
+	firewalls[0].DropletIDs = []int{2}
+	lbs[0].VPCUUID = "vpc-private"
+
+	tm.vpcs.EXPECT().List().Return(do.VPCs{
+		{VPC: &godo.VPC{ID: "vpc-default", Name: "default-nyc3", RegionSlug: "nyc3", Default: true}},
+		{VPC: &godo.VPC{ID: "vpc-private", Name: "private", RegionSlug: "nyc3", IPRange: "10.10.0.0/20"}},
+	}, nil)
+	tm.volumes.EXPECT().List().Return([]do.Volume{
+		{Volume: &godo.Volume{ID: "vol-1", Name: "data", Region: &godo.Region{Slug: "nyc3"}, SizeGigaBytes: 100, FilesystemType: "ext4", DropletIDs: []int{2}}},
+	}, nil)
+	tm.droplets.EXPECT().List().Return(droplets, nil)
+	tm.firewalls.EXPECT().List().Return(firewalls, nil)
+	tm.loadBalancers.EXPECT().List().Return(lbs, nil)
+	tm.domains.EXPECT().List().Return(do.Domains{{Domain: &godo.Domain{Name: "example.com"}}}, nil)
+	tm.domains.EXPECT().Records("example.com").Return(records, nil)
+}
+
+func TestExportCommand(t *testing.T) {
+	cmd := Export(&Command{Command: &cobra.Command{Use: "doctl"}})
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "export", cmd.Name())
+	assert.Equal(t, cmdNS(cmd)+"."+doctl.ArgProjectID, flagName(cmd, doctl.ArgProjectID))
+}
+
+func TestRunExport(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		expectExportLists(tm)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+
+		err := RunExport(config)
+		require.NoError(t, err)
+
+		expected := `kind: vpc
+name: private
+spec:
+  ip_range: 10.10.0.0/20
+  region: nyc3
+---
+kind: volume
+name: data
+spec:
+  filesystem_type: ext4
+  region: nyc3
+  size_gigabytes: 100
+---
+kind: droplet
+name: db-1
+spec:
+  image: "4242"
+  region: nyc3
+  size: s-2vcpu-4gb
+  vpc: private
+---
+kind: droplet
+name: web-1
+spec:
+  image: ubuntu-24-04-x64
+  monitoring: true
+  region: nyc3
+  size: s-2vcpu-2gb
+  tags:
+  - web
+  - old
+---
+kind: firewall
+name: web
+spec:
+  droplets:
+  - db-1
+  inbound_rules:
+  - ports: "80"
+    protocol: tcp
+    sources:
+      addresses:
+      - 0.0.0.0/0
+  outbound_rules:
+  - destinations:
+      addresses:
+      - 0.0.0.0/0
+    ports: "0"
+    protocol: tcp
+  tags:
+  - web
+---
+kind: load_balancer
+name: web
+spec:
+  region: nyc3
+  tag: web
+  vpc: private
+---
+kind: domain
+name: example.com
+spec:
+  records:
+  - data: 203.0.113.10
+    name: www
+    ttl: 3600
+    type: A
+  - data: 203.0.113.11
+    name: old
+    ttl: 3600
+    type: A
+`
+		assert.Equal(t, expected, buf.String())
+
+		resources, err := parseStack(buf.Bytes())
+		require.NoError(t, err)
+		assert.Len(t, resources, 7)
+	})
+}
+
+func TestRunExportProject(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		expectExportLists(tm)
+		tm.projects.EXPECT().ListResources("project-1").Return(do.ProjectResources{
+			{ProjectResource: &godo.ProjectResource{URN: "do:droplet:2"}},
+			{ProjectResource: &godo.ProjectResource{URN: "do:volume:vol-1"}},
+			{ProjectResource: &godo.ProjectResource{URN: "do:domain:example.com"}},
+		}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgProjectID, "project-1")
+
+		err := RunExport(config)
+		require.NoError(t, err)
+
+		resources, err := parseStack(buf.Bytes())
+		require.NoError(t, err)
+
+		var names []string
+		for _, r := range resources {
+			names = append(names, r.Kind+"/"+r.Name)
+		}
+		assert.Equal(t, []string{"vpc/private", "volume/data", "droplet/db-1", "firewall/web", "domain/example.com"}, names)
+	})
+}
+
+func TestRunExportJSON(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		expectExportLists(tm)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		viper.Set("output", "json")
+		t.Cleanup(func() { viper.Set("output", "") })
+
+		err := RunExport(config)
+		require.NoError(t, err)
+
+		resources, err := parseStack(buf.Bytes())
+		require.NoError(t, err)
+		require.Len(t, resources, 7)
+		assert.Equal(t, "vpc/private", resources[0].Kind+"/"+resources[0].Name)
+		assert.JSONEq(t, `{"image":"4242","region":"nyc3","size":"s-2vcpu-4gb","vpc":"private"}`, string(resources[2].Spec))
+	})
+}
+
+func TestRunExportDuplicateNames(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.vpcs.EXPECT().List().Return(do.VPCs{}, nil)
+		tm.volumes.EXPECT().List().Return([]do.Volume{
+			{Volume: &godo.Volume{ID: "vol-1", Name: "data", Region: &godo.Region{Slug: "nyc3"}, SizeGigaBytes: 100}},
+			{Volume: &godo.Volume{ID: "vol-2", Name: "data", Region: &godo.Region{Slug: "sfo3"}, SizeGigaBytes: 100}},
+		}, nil)
+		tm.droplets.EXPECT().List().Return(do.Droplets{}, nil)
+		tm.firewalls.EXPECT().List().Return(do.Firewalls{}, nil)
+		tm.loadBalancers.EXPECT().List().Return(do.LoadBalancers{}, nil)
+		tm.domains.EXPECT().List().Return(do.Domains{}, nil)
+
+		err := RunExport(config)
+		assert.EqualError(t, err, "volume data: more than one resource of this kind has this name, rename them before exporting")
+	})
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+)
+
+func firewallCheck(parent *Command) *Command {
+	cmd := CmdBuilder(parent, RunFirewallCheck, "check", "Check whether cloud firewalls allow some traffic", `Evaluates the rules of every cloud firewall applying to a Droplet, directly or through one of its tags, and reports the rules allowing the traffic described by the flags. The command exits with an error when no rule allows it.
+
+Inbound traffic is checked with `+"`"+`--from`+"`"+`, and outbound traffic with `+"`"+`--to`+"`"+`. Rules whose sources or destinations are Droplets, tags, load balancers or Kubernetes clusters match when the address belongs to one of them.
+
+A Droplet without any cloud firewall accepts all traffic, so no rules are reported for it and the command succeeds.`, Writer, displayerType(&displayers.FirewallRuleMatches{}))
+	AddStringFlag(cmd, doctl.ArgFirewallCheckDroplet, "", "", "The ID or name of the Droplet to check the firewalls of", requiredOpt())
+	AddStringFlag(cmd, doctl.ArgFirewallCheckFrom, "", "", "The IP address inbound traffic comes from")
+	AddStringFlag(cmd, doctl.ArgFirewallCheckTo, "", "", "The IP address outbound traffic goes to")
+	AddIntFlag(cmd, doctl.ArgFirewallCheckPort, "", 0, "The port the traffic goes to. Required for the tcp and udp protocols")
+	AddStringFlag(cmd, doctl.ArgFirewallCheckProtocol, "", "tcp", "The protocol of the traffic. Possible values: tcp, udp, icmp")
+	cmd.Example = `The following example checks whether the Droplet ` + "`" + `db-1` + "`" + ` accepts PostgreSQL connections from ` + "`" + `203.0.113.10` + "`" + `: doctl compute firewall check --droplet db-1 --from 203.0.113.10 --port 5432 --protocol tcp`
+
+	return cmd
+}
+
+// RunFirewallCheck reports the firewall rules allowing traffic to or from a
+// Droplet.
+func RunFirewallCheck(c *CmdConfig) error {
+	dropletArg, err := c.Doit.GetString(c.NS, doctl.ArgFirewallCheckDroplet)
+	if err != nil {
+		return err
+	}
+
+	from, err := c.Doit.GetString(c.NS, doctl.ArgFirewallCheckFrom)
+	if err != nil {
+		return err
+	}
+
+	to, err := c.Doit.GetString(c.NS, doctl.ArgFirewallCheckTo)
+	if err != nil {
+		return err
+	}
+
+	port, err := c.Doit.GetInt(c.NS, doctl.ArgFirewallCheckPort)
+	if err != nil {
+		return err
+	}
+
+	protocol, err := c.Doit.GetString(c.NS, doctl.ArgFirewallCheckProtocol)
+	if err != nil {
+		return err
+	}
+
+	if (from == "") == (to == "") {
+		return errors.New("exactly one of --from and --to must be set")
+	}
+	inbound := from != ""
+	peer := from
+	if !inbound {
+		peer = to
+	}
+
+	addr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return fmt.Errorf("invalid IP address %q", peer)
+	}
+
+	protocol = strings.ToLower(protocol)
+	switch protocol {
+	case "tcp", "udp":
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("--%s must be between 1 and 65535 for the %s protocol", doctl.ArgFirewallCheckPort, protocol)
+		}
+	case "icmp":
+		port = 0
+	default:
+		return fmt.Errorf("unsupported protocol %q, must be one of: tcp, udp, icmp", protocol)
+	}
+
+	droplet, err := findDroplet(c.Droplets(), dropletArg)
+	if err != nil {
+		return err
+	}
+
+	firewalls, err := c.Firewalls().ListByDroplet(droplet.ID)
+	if err != nil {
+		return err
+	}
+
+	traffic := describeTraffic(inbound, addr, port, protocol)
+	if len(firewalls) == 0 {
+		// No rule matches, but nothing filters the traffic either.
+		notice("No cloud firewall applies to Droplet %s, %s is allowed", droplet.Name, traffic)
+		return c.Display(&displayers.FirewallRuleMatches{Matches: []displayers.FirewallRuleMatch{}})
+	}
+
+	checker := &firewallChecker{
+		droplets:      c.Droplets(),
+		loadBalancers: c.LoadBalancers(),
+		addr:          addr,
+	}
+
+	var matches []displayers.FirewallRuleMatch
+	for _, fw := range firewalls {
+		direction := "inbound"
+		var rules []firewallRuleTargets
+		if inbound {
+			for _, r := range fw.InboundRules {
+				t := firewallRuleTargets{protocol: r.Protocol, ports: r.PortRange}
+				if r.Sources != nil {
+					t.addresses, t.dropletIDs, t.tags, t.loadBalancerUIDs, t.kubernetesIDs = r.Sources.Addresses, r.Sources.DropletIDs, r.Sources.Tags, r.Sources.LoadBalancerUIDs, r.Sources.KubernetesIDs
+				}
+				rules = append(rules, t)
+			}
+		} else {
+			direction = "outbound"
+			for _, r := range fw.OutboundRules {
+				t := firewallRuleTargets{protocol: r.Protocol, ports: r.PortRange}
+				if r.Destinations != nil {
+					t.addresses, t.dropletIDs, t.tags, t.loadBalancerUIDs, t.kubernetesIDs = r.Destinations.Addresses, r.Destinations.DropletIDs, r.Destinations.Tags, r.Destinations.LoadBalancerUIDs, r.Destinations.KubernetesIDs
+				}
+				rules = append(rules, t)
+			}
+		}
+
+		for _, r := range rules {
+			if !strings.EqualFold(r.protocol, protocol) || !firewallPortsMatch(r.ports, port) {
+				continue
+			}
+
+			match, err := checker.match(r)
+			if err != nil {
+				return err
+			}
+			if match == "" {
+				continue
+			}
+
+			ports := r.ports
+			if protocol == "icmp" {
+				ports = ""
+			} else if ports == "" || ports == "0" {
+				ports = "all"
+			}
+			matches = append(matches, displayers.FirewallRuleMatch{
+				FirewallID:   fw.ID,
+				FirewallName: fw.Name,
+				AppliedBy:    firewallAppliedBy(fw, droplet),
+				Direction:    direction,
+				Protocol:     protocol,
+				Ports:        ports,
+				Match:        match,
+			})
+		}
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("no rule of the %d cloud firewalls applying to Droplet %s allows %s", len(firewalls), droplet.Name, traffic)
+	}
+
+	return c.Display(&displayers.FirewallRuleMatches{Matches: matches})
+}
+
+func describeTraffic(inbound bool, addr netip.Addr, port int, protocol string) string {
+	desc := protocol + " traffic"
+	if inbound {
+		desc += " from " + addr.String()
+	} else {
+		desc += " to " + addr.String()
+	}
+	if port != 0 {
+		desc += " on port " + strconv.Itoa(port)
+	}
+	return desc
+}
+
+// firewallAppliedBy describes how a firewall applies to a Droplet.
+func firewallAppliedBy(fw do.Firewall, droplet *do.Droplet) string {
+	if slices.Contains(fw.DropletIDs, droplet.ID) {
+		return "droplet"
+	}
+	for _, t := range fw.Tags {
+		if slices.Contains(droplet.Tags, t) {
+			return "tag " + t
+		}
+	}
+	return ""
+}
+
+// firewallPortsMatch reports whether the ports of a rule, a single port, a
+// range or all ports, include port.
+func firewallPortsMatch(ports string, port int) bool {
+	if port == 0 || ports == "" || ports == "0" || strings.EqualFold(ports, "all") {
+		return true
+	}
+
+	low, high, isRange := strings.Cut(ports, "-")
+	lowPort, err := strconv.Atoi(low)
+	if err != nil {
+		return false
+	}
+	if !isRange {
+		return port == lowPort
+	}
+	highPort, err := strconv.Atoi(high)
+	if err != nil {
+		return false
+	}
+	return port >= lowPort && port <= highPort
+}
+
+// firewallRuleTargets are the sources of an inbound rule or the
+// destinations of an outbound rule.
+type firewallRuleTargets struct {
+	protocol         string
+	ports            string
+	addresses        []string
+	dropletIDs       []int
+	tags             []string
+	loadBalancerUIDs []string
+	kubernetesIDs    []string
+}
+
+// firewallChecker matches an address against the targets of firewall
+// rules, looking up the Droplets and load balancers it belongs to when a
+// rule refers to them.
+type firewallChecker struct {
+	droplets      do.DropletsService
+	loadBalancers do.LoadBalancersService
+	addr          netip.Addr
+
+	dropletsResolved bool
+	dropletIDs       []int
+	dropletTags      []string
+
+	loadBalancersResolved bool
+	loadBalancerIDs       []string
+}
+
+// match returns the target matching the address, or an empty string when
+// none does.
+func (f *firewallChecker) match(r firewallRuleTargets) (string, error) {
+	for _, a := range r.addresses {
+		if prefix, err := netip.ParsePrefix(a); err == nil {
+			if prefix.Contains(f.addr) {
+				return "address " + a, nil
+			}
+		} else if ip, err := netip.ParseAddr(a); err == nil && ip == f.addr {
+			return "address " + a, nil
+		}
+	}
+
+	if len(r.dropletIDs) > 0 || len(r.tags) > 0 || len(r.kubernetesIDs) > 0 {
+		if err := f.resolveDroplets(); err != nil {
+			return "", err
+		}
+		for _, id := range r.dropletIDs {
+			if slices.Contains(f.dropletIDs, id) {
+				return "droplet " + strconv.Itoa(id), nil
+			}
+		}
+		for _, t := range r.tags {
+			if slices.Contains(f.dropletTags, t) {
+				return "tag " + t, nil
+			}
+		}
+		for _, id := range r.kubernetesIDs {
+			// the nodes of a cluster carry the k8s:<cluster-id> tag
+			if slices.Contains(f.dropletTags, "k8s:"+id) {
+				return "kubernetes cluster " + id, nil
+			}
+		}
+	}
+
+	if len(r.loadBalancerUIDs) > 0 {
+		if err := f.resolveLoadBalancers(); err != nil {
+			return "", err
+		}
+		for _, id := range r.loadBalancerUIDs {
+			if slices.Contains(f.loadBalancerIDs, id) {
+				return "load balancer " + id, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func (f *firewallChecker) resolveDroplets() error {
+	if f.dropletsResolved {
+		return nil
+	}
+
+	droplets, err := f.droplets.List()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range droplets {
+		if !dropletHasAddress(d, f.addr) {
+			continue
+		}
+		f.dropletIDs = append(f.dropletIDs, d.ID)
+		f.dropletTags = append(f.dropletTags, d.Tags...)
+	}
+	f.dropletsResolved = true
+
+	return nil
+}
+
+func (f *firewallChecker) resolveLoadBalancers() error {
+	if f.loadBalancersResolved {
+		return nil
+	}
+
+	lbs, err := f.loadBalancers.List()
+	if err != nil {
+		return err
+	}
+
+	for _, lb := range lbs {
+		for _, ip := range []string{lb.IP, lb.IPv6} {
+			if a, err := netip.ParseAddr(ip); err == nil && a == f.addr {
+				f.loadBalancerIDs = append(f.loadBalancerIDs, lb.ID)
+			}
+		}
+	}
+	f.loadBalancersResolved = true
+
+	return nil
+}
+
+func dropletHasAddress(d do.Droplet, addr netip.Addr) bool {
+	if d.Networks == nil {
+		return false
+	}
+
+	for _, n := range d.Networks.V4 {
+		if a, err := netip.ParseAddr(n.IPAddress); err == nil && a == addr {
+			return true
+		}
+	}
+	for _, n := range d.Networks.V6 {
+		if a, err := netip.ParseAddr(n.IPAddress); err == nil && a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirewallCheck(t *testing.T) {
+	firewalls := do.Firewalls{
+		{Firewall: &godo.Firewall{
+			ID:         "fw-1",
+			Name:       "ssh",
+			DropletIDs: []int{1},
+			InboundRules: []godo.InboundRule{
+				{Protocol: "tcp", PortRange: "22", Sources: &godo.Sources{Addresses: []string{"0.0.0.0/0"}}},
+			},
+		}},
+		{Firewall: &godo.Firewall{
+			ID:   "fw-2",
+			Name: "db",
+			Tags: []string{"db"},
+			InboundRules: []godo.InboundRule{
+				{Protocol: "tcp", PortRange: "5000-6000", Sources: &godo.Sources{Addresses: []string{"10.0.0.0/8"}}},
+				{Protocol: "tcp", PortRange: "5432", Sources: &godo.Sources{DropletIDs: []int{3}}},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		from   string
+		port   int
+		expect string
+		err    string
+	}{
+		{
+			name: "address range",
+			from: "10.1.2.3",
+			port: 5432,
+			expect: `Firewall ID    Firewall Name    Applied By    Direction    Protocol    Ports        Match
+fw-2           db               tag db        inbound      tcp         5000-6000    address 10.0.0.0/8
+`,
+		},
+		{
+			name: "droplet source",
+			from: "172.16.1.4",
+			port: 5432,
+			expect: `Firewall ID    Firewall Name    Applied By    Direction    Protocol    Ports    Match
+fw-2           db               tag db        inbound      tcp         5432     droplet 3
+`,
+		},
+		{
+			name: "applied to droplet",
+			from: "198.51.100.1",
+			port: 22,
+			expect: `Firewall ID    Firewall Name    Applied By    Direction    Protocol    Ports    Match
+fw-1           ssh              droplet       inbound      tcp         22       address 0.0.0.0/0
+`,
+		},
+		{
+			name: "no rule",
+			from: "198.51.100.1",
+			port: 5432,
+			err:  "no rule of the 2 cloud firewalls applying to Droplet a-droplet allows tcp traffic from 198.51.100.1 on port 5432",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				droplet := testDroplet
+				droplet.Tags = []string{"db"}
+				tm.droplets.EXPECT().Get(1).Return(&droplet, nil)
+				tm.droplets.EXPECT().List().Return(testDropletList, nil).AnyTimes()
+				tm.firewalls.EXPECT().ListByDroplet(1).Return(firewalls, nil)
+
+				var buf bytes.Buffer
+				config.Out = &buf
+				config.Doit.Set(config.NS, doctl.ArgFirewallCheckDroplet, "1")
+				config.Doit.Set(config.NS, doctl.ArgFirewallCheckFrom, tt.from)
+				config.Doit.Set(config.NS, doctl.ArgFirewallCheckPort, tt.port)
+				config.Doit.Set(config.NS, doctl.ArgFirewallCheckProtocol, "tcp")
+
+				err := RunFirewallCheck(config)
+				if tt.err != "" {
+					assert.EqualError(t, err, tt.err)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, buf.String())
+			})
+		})
+	}
+}
+
+func TestFirewallCheckOutbound(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		firewalls := do.Firewalls{
+			{Firewall: &godo.Firewall{
+				ID:         "fw-1",
+				Name:       "egress",
+				DropletIDs: []int{1},
+				OutboundRules: []godo.OutboundRule{
+					{Protocol: "icmp", Destinations: &godo.Destinations{LoadBalancerUIDs: []string{"lb-1"}}},
+				},
+			}},
+		}
+
+		tm.droplets.EXPECT().Get(1).Return(&testDroplet, nil)
+		tm.firewalls.EXPECT().ListByDroplet(1).Return(firewalls, nil)
+		tm.loadBalancers.EXPECT().List().Return(do.LoadBalancers{
+			{LoadBalancer: &godo.LoadBalancer{ID: "lb-1", IP: "203.0.113.5"}},
+		}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckDroplet, "1")
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckTo, "203.0.113.5")
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckProtocol, "icmp")
+
+		err := RunFirewallCheck(config)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "load balancer lb-1")
+	})
+}
+
+func TestFirewallCheckNoFirewalls(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.EXPECT().Get(1).Return(&testDroplet, nil)
+		tm.firewalls.EXPECT().ListByDroplet(1).Return(do.Firewalls{}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckDroplet, "1")
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckFrom, "198.51.100.1")
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckPort, 80)
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckProtocol, "tcp")
+
+		Output = "json"
+		defer func() { Output = "text" }()
+
+		err := RunFirewallCheck(config)
+		assert.NoError(t, err)
+		assert.JSONEq(t, "[]", buf.String())
+	})
+}
+
+func TestFirewallCheckValidation(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckDroplet, "1")
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckProtocol, "tcp")
+
+		err := RunFirewallCheck(config)
+		assert.EqualError(t, err, "exactly one of --from and --to must be set")
+
+		config.Doit.Set(config.NS, doctl.ArgFirewallCheckFrom, "198.51.100.1")
+		err = RunFirewallCheck(config)
+		assert.EqualError(t, err, "--port must be between 1 and 65535 for the tcp protocol")
+	})
+}
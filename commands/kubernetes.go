@@ -22,6 +22,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -221,7 +222,7 @@ type KubernetesCommandService struct {
 	KubeconfigProvider KubeconfigProvider
 
 	// to be used for stubbing in testss
-	ssoLogin func(ctx context.Context, clientID, issuerURL string, opts ...sso.LocalOIDCLoginOption) (string, time.Time, error)
+	ssoLogin func(ctx context.Context, clientID, issuerURL string, opts ...sso.LocalOIDCLoginOption) (*sso.Token, error)
 }
 
 func kubernetesCommandService() *KubernetesCommandService {
@@ -272,6 +273,8 @@ Retrieves the following details about a Kubernetes cluster: `+clusterDetails+`
 - When the Kubernetes cluster was last updated, in ISO8601 combined date and time format
 `+nodePoolDetails,
 		Writer, aliasOpt("g"), displayerType(&displayers.KubernetesClusters{}))
+	AddStringFlag(cmdKubernetesClusterGet, doctl.ArgClusterSpecOutput, "", "",
+		"Print the cluster's configuration as a spec in the given format: `yaml` or `json`. The spec is accepted by `doctl kubernetes cluster create --spec` and `doctl kubernetes cluster apply --spec`")
 	cmdKubernetesClusterGet.Example = `The following example retrieve details about a Kubernetes cluster named ` + "`" + `example-cluster` + "`" + `: doctl kubernetes cluster get example-cluster`
 
 	KubernetesClusterList := CmdBuilder(cmd, k8sCmdService.RunKubernetesClusterList, "list", "Retrieve the list of Kubernetes clusters for your account", `
@@ -291,10 +294,14 @@ Creates a Kubernetes cluster given the specified options and using the specified
 
 If no configuration flags are used, a three-node cluster with a single node pool is created in the `+"`"+`nyc1`+"`"+` region, using the latest Kubernetes version.
 
-After creating a cluster, a configuration context is added to kubectl and made active so that you can begin managing your new cluster immediately.`,
+After creating a cluster, a configuration context is added to kubectl and made active so that you can begin managing your new cluster immediately.
+
+Instead of flags, the cluster's full configuration can be given with `+"`"+`--spec`+"`"+` as a YAML or JSON file with the fields of the API's cluster create request, such as the one printed by `+"`"+`doctl kubernetes cluster get --spec-output yaml`+"`"+`. The name argument can then be omitted if the spec sets a name, and overrides it otherwise.`,
 		Writer, aliasOpt("c"))
+	AddStringFlag(cmdKubeClusterCreate, doctl.ArgClusterSpec, "", "",
+		"Path to a cluster spec in YAML or JSON format. Set to `-` to read from stdin. Cannot be combined with the other configuration flags.")
 	AddStringFlag(cmdKubeClusterCreate, doctl.ArgRegionSlug, "", defaultKubernetesRegion,
-		"A `slug` indicating which region to create the cluster in. Use the `doctl kubernetes options regions` command for a list of options", requiredOpt())
+		"A `slug` indicating which region to create the cluster in. Use the `doctl kubernetes options regions` command for a list of options. Required unless --spec is set")
 	AddStringFlag(cmdKubeClusterCreate, doctl.ArgClusterVersionSlug, "", "latest",
 		"A `slug` indicating which Kubernetes version to use when creating the cluster. Use the `doctl kubernetes options versions` command for a list of options")
 	AddStringFlag(cmdKubeClusterCreate, doctl.ArgClusterVPCUUID, "", "",
@@ -441,12 +448,24 @@ Updates the configuration values for a Kubernetes cluster. The cluster must be r
 	cmdKubeClusterUpgrade := CmdBuilder(cmd, k8sCmdService.RunKubernetesClusterUpgrade,
 		"upgrade <id|name>", "Upgrades a cluster to a new Kubernetes version", `
 
-Upgrades a Kubernetes cluster. By default, this upgrades the cluster to the latest available release, but you can also specify any version listed for your cluster by using `+"`"+`doctl k8s cluster get-upgrades`+"`"+`.`, Writer)
+Upgrades a Kubernetes cluster. By default, this upgrades the cluster to the latest available release, but you can also specify any version listed for your cluster by using `+"`"+`doctl k8s cluster get-upgrades`+"`"+`.
+
+With `+"`"+`--preflight`+"`"+`, the command checks the cluster instead of upgrading it, through the cluster's Kubernetes API with the cluster's credentials in your local kubeconfig. It reports:
+
+- resources last written with an API version that the target version no longer serves, which block the upgrade
+- PodDisruptionBudgets that allow no disruptions, which block the drain of nodes during the upgrade
+- whether surge upgrades are enabled, and the capacity of each node pool
+
+The command exits with an error if any check finds a blocker.`, Writer, displayerType(&displayers.KubernetesPreflightChecks{}))
 	AddStringFlag(cmdKubeClusterUpgrade, doctl.ArgClusterVersionSlug, "", "latest",
 		`The Kubernetes version to upgrade to. Use the `+"`"+`doctl k8s cluster get-upgrades <cluster>`+"`"+` command for a list of available versions.
 The special value `+"`"+`latest`+"`"+` selects the most recent patch version for your cluster's minor version.
 For example, if a cluster is on 1.12.1 and upgrades are available to 1.12.3 and 1.13.1, the `+"`"+`latest`+"`"+` flag upgrades the cluster to 1.12.3.`)
-	cmdKubeClusterUpgrade.Example = `The following example upgrades a cluster named ` + "`" + `example-cluster` + "`" + ` to version 1.28.2: doctl kubernetes cluster upgrade example-cluster --version 1.28.2-do.0`
+	AddBoolFlag(cmdKubeClusterUpgrade, doctl.ArgClusterUpgradePreflight, "", false,
+		"Checks the cluster for what would block or disrupt the upgrade instead of upgrading it")
+	cmdKubeClusterUpgrade.Example = `The following example upgrades a cluster named ` + "`" + `example-cluster` + "`" + ` to version 1.28.2: doctl kubernetes cluster upgrade example-cluster --version 1.28.2-do.0
+
+The following example checks whether anything would block the upgrade of a cluster named ` + "`" + `example-cluster` + "`" + ` to version 1.29.1: doctl kubernetes cluster upgrade example-cluster --version 1.29.1-do.0 --preflight`
 
 	cmdKubeClusterDelete := CmdBuilder(cmd, k8sCmdService.RunKubernetesClusterDelete,
 		"delete <id|name>...", "Delete Kubernetes clusters ", `
@@ -484,6 +503,18 @@ Retrieves the following details for a Kubernetes cluster:
 		Writer, aliasOpt("ar"), displayerType(&displayers.KubernetesAssociatedResources{}))
 	cmdKubeClusterListAssociatedResources.Example = `The following example retrieves the associated resources for a cluster named ` + "`" + `example-cluster` + "`" + ` and uses the ` + "`" + `--format` + "`" + ` flag to return only the associated volumes: doctl kubernetes cluster list-associated-resources example-cluster --format Volumes`
 
+	cmdKubeClusterApply := CmdBuilder(cmd, k8sCmdService.RunKubernetesClusterApply, "apply", "Create or update a Kubernetes cluster to match a spec file", `
+Reads a cluster spec, compares it to the cluster with the same name and shows the changes needed for the cluster to match it, then applies them after a confirmation prompt. The cluster is created if it does not exist.
+
+The spec is a YAML or JSON file with the fields of the API's cluster create request, such as the one printed by `+"`"+`doctl kubernetes cluster get --spec-output yaml`+"`"+`. Only the fields present in the spec are compared to the cluster. The tags, maintenance window, autoscaler configuration and other settings that `+"`"+`doctl kubernetes cluster update`+"`"+` can change are updated in place. Node pools are matched by name: pools missing from the cluster are created, pools that differ are updated, and, when the spec lists node pools, the cluster's other pools are deleted. The count of a pool with auto-scaling enabled is left to the autoscaler.
+
+Differences that cannot be applied, such as the cluster's region, the size of a node pool's nodes, or turning off surge upgrades and removing every tag, which the update API ignores, are reported as drift. To change the cluster's Kubernetes version, use `+"`"+`doctl kubernetes cluster upgrade`+"`"+`.`,
+		Writer, displayerType(&displayers.StackChanges{}))
+	AddStringFlag(cmdKubeClusterApply, doctl.ArgClusterSpec, "", "", "Path to a cluster spec in YAML or JSON format. Set to `-` to read from stdin", requiredOpt())
+	AddBoolFlag(cmdKubeClusterApply, doctl.ArgDryRun, "", false, "Display the changes without making them")
+	AddBoolFlag(cmdKubeClusterApply, doctl.ArgForce, doctl.ArgShortForce, false, "Apply the changes without a confirmation prompt")
+	cmdKubeClusterApply.Example = `The following example displays the changes needed for the cluster described in ` + "`" + `cluster.yaml` + "`" + ` to match it: doctl kubernetes cluster apply --spec cluster.yaml --dry-run`
+
 	return cmd
 }
 
@@ -513,6 +544,13 @@ Returns the raw YAML for the specified cluster's kubeconfig.`, Writer, aliasOpt(
 	AddStringFlag(cmdExecCredential, doctl.ArgKubernetesSSOIssuerURL, "", "", "")
 	AddStringFlag(cmdExecCredential, doctl.ArgKubernetesSSOClientID, "", "", "")
 	AddIntFlag(cmdExecCredential, doctl.ArgKubernetesSSOLocalServerPort, "", 8080, "")
+	// The exec-credential arguments come from the cluster's kubeconfig, so
+	// the flow can also be set in the environment.
+	ssoFlow := os.Getenv("DIGITALOCEAN_SSO_FLOW")
+	if ssoFlow == "" {
+		ssoFlow = string(sso.FlowAuto)
+	}
+	AddStringFlag(cmdExecCredential, doctl.ArgKubernetesSSOFlow, "", ssoFlow, "")
 
 	cmdSaveConfig := CmdBuilder(cmd, k8sCmdService.RunKubernetesKubeconfigSave, "save <cluster-id|cluster-name>", "Save a cluster's credentials to your local kubeconfig", `
 Adds the credentials for the specified cluster to your local kubeconfig. After this, your kubectl installation can directly manage the specified cluster.
@@ -530,6 +568,23 @@ This command removes the specified cluster's credentials from your local kubecon
 `, Writer, aliasOpt("d", "rm"))
 	cmdKubeKubeconfigRemove.Example = `The following example removes the credentials for a cluster named ` + "`" + `example-cluster` + "`" + ` from your local kubeconfig: doctl kubernetes cluster kubeconfig remove example-cluster`
 
+	cmdKubeKubeconfigSync := CmdBuilder(cmd, k8sCmdService.RunKubernetesKubeconfigSync, "sync", "Save the credentials of every cluster to your local kubeconfig", `
+Adds the credentials of every cluster in your account to your local kubeconfig, as `+"`"+`doctl kubernetes cluster kubeconfig save`+"`"+` does for a single cluster. Use `+"`"+`--tag`+"`"+` and `+"`"+`--region`+"`"+` to only save some clusters. The current context is left unchanged.
+
+With `+"`"+`--prune`+"`"+`, the contexts of clusters that no longer exist are also removed. Only contexts whose credentials are provided by doctl for the current authentication context are considered, so contexts of other accounts and other tools are kept, as are contexts whose credentials do not name an authentication context.
+
+By default, the kubeconfig file used by kubectl is updated. Use `+"`"+`--kubeconfig`+"`"+` to update another file, or `+"`"+`--output-dir`+"`"+` to write a separate file for each cluster, named after its context, such as `+"`"+`do-nyc1-example-cluster.yaml`+"`"+`.`, Writer)
+	AddStringFlag(cmdKubeKubeconfigSync, doctl.ArgTag, "", "", "Only save the clusters with this tag")
+	AddStringFlag(cmdKubeKubeconfigSync, doctl.ArgRegionSlug, "", "", "Only save the clusters in this region")
+	AddBoolFlag(cmdKubeKubeconfigSync, doctl.ArgKubeConfigPrune, "", false, "Remove the contexts of clusters that no longer exist")
+	AddStringFlag(cmdKubeKubeconfigSync, doctl.ArgKubeConfigPath, "", "", "The kubeconfig file to update instead of the one used by kubectl")
+	AddStringFlag(cmdKubeKubeconfigSync, doctl.ArgKubeConfigOutputDir, "", "", "A directory to write a kubeconfig file for each cluster to, instead of updating a single file")
+	AddIntFlag(cmdKubeKubeconfigSync, doctl.ArgKubeConfigExpirySeconds, "", 0,
+		"The length of time the cluster credentials are valid for, in seconds. By default, the credentials are automatically renewed as needed. If set, `token` kubeconfig type is implied.")
+	AddStringFlag(cmdKubeKubeconfigSync, doctl.ArgKubeConfigType, "", "",
+		"Kubeconfig authentication type: 'token', 'sso', or omit for the API default.")
+	cmdKubeKubeconfigSync.Example = `The following example saves the credentials of the clusters in the ` + "`" + `nyc1` + "`" + ` region and removes the contexts of deleted clusters: doctl kubernetes cluster kubeconfig sync --region nyc1 --prune`
+
 	return cmd
 }
 
@@ -650,6 +705,19 @@ Deletes the specified node in the specified node pool, and then creates a new no
 	AddBoolFlag(cmdKubeNodeReplace, doctl.ArgForce, doctl.ArgShortForce, false, "Replaces node without confirmation prompt")
 	AddBoolFlag(cmdKubeNodeReplace, "skip-drain", "", false, "Skips draining the node before replacement")
 	cmdKubeNodeReplace.Example = `The following example replaces a node named ` + "`" + `example-node` + "`" + ` in a node pool named ` + "`" + `example-pool` + "`" + `: doctl kubernetes cluster node-pool replace-node example-cluster example-pool example-node`
+
+	cmdKubeNodePoolRotate := CmdBuilder(cmd, k8sCmdService.RunKubernetesNodePoolRotate, "rotate <cluster-id|cluster-name> <pool-id|pool-name>", "Replace every node of a node pool, a few at a time", `
+Replaces every node in the specified node pool, `+"`"+`--batch`+"`"+` nodes at a time. For each batch of nodes, the command replaces the nodes and waits for the node pool to report as many new nodes as `+"`"+`running`+"`"+` before moving on to the next batch.
+
+By default, DigitalOcean drains each node before replacing it. With `+"`"+`--drain`+"`"+`, the command instead cordons every node of a batch, then evicts the pods of each node through the cluster's Kubernetes API, using the cluster's credentials in your local kubeconfig, and retries the evictions that a PodDisruptionBudget does not allow yet. DaemonSet pods and mirror pods are not evicted. Save the credentials first with `+"`"+`doctl kubernetes cluster kubeconfig save`+"`"+`.
+
+The rotation stops at the first failure. The nodes of the batch that were cordoned but not replaced, such as a node that failed to drain, are uncordoned, and the nodes of later batches are left untouched. The status of each node is printed when the rotation ends.
+`, Writer, displayerType(&displayers.KubernetesRotatedNodes{}))
+	AddIntFlag(cmdKubeNodePoolRotate, doctl.ArgNodePoolRotateBatch, "", 1, "The number of nodes to replace at once")
+	AddBoolFlag(cmdKubeNodePoolRotate, doctl.ArgNodePoolRotateDrain, "", false, "Cordons and drains each node through the cluster's Kubernetes API before replacing it")
+	AddDurationFlag(cmdKubeNodePoolRotate, doctl.ArgTimeout, "", defaultNodePoolRotateTimeout, "How long to wait for each node to drain and for the replacements of each batch to be running")
+	AddBoolFlag(cmdKubeNodePoolRotate, doctl.ArgForce, doctl.ArgShortForce, false, "Replaces the nodes without a confirmation prompt")
+	cmdKubeNodePoolRotate.Example = `The following example replaces the nodes of a node pool named ` + "`" + `example-pool` + "`" + ` one at a time, draining each through the Kubernetes API first: doctl kubernetes cluster node-pool rotate example-cluster example-pool --batch 1 --drain`
 	return cmd
 
 }
@@ -773,6 +841,15 @@ func (s *KubernetesCommandService) RunKubernetesClusterGet(c *CmdConfig) error {
 	if err != nil {
 		return err
 	}
+
+	specOutput, err := c.Doit.GetString(c.NS, doctl.ArgClusterSpecOutput)
+	if err != nil {
+		return err
+	}
+	if specOutput != "" {
+		return writeSpec(c.Out, specOutput, clusterSpec(cluster.KubernetesCluster))
+	}
+
 	return displayClusters(c, false, *cluster)
 }
 
@@ -825,15 +902,11 @@ func (s *KubernetesCommandService) RunKubernetesClusterGetUpgrades(c *CmdConfig)
 // RunKubernetesClusterCreate creates a new kubernetes with a given configuration.
 func (s *KubernetesCommandService) RunKubernetesClusterCreate(defaultNodeSize string, defaultNodeCount int) func(*CmdConfig) error {
 	return func(c *CmdConfig) error {
-		err := ensureOneArg(c)
+		r, err := clusterCreateRequest(c, defaultNodeSize, defaultNodeCount)
 		if err != nil {
 			return err
 		}
-		clusterName := c.Args[0]
-		r := &godo.KubernetesClusterCreateRequest{Name: clusterName}
-		if err := buildClusterCreateRequestFromArgs(c, r, defaultNodeSize, defaultNodeCount); err != nil {
-			return err
-		}
+		clusterName := r.Name
 		wait, err := c.Doit.GetBool(c.NS, doctl.ArgCommandWait)
 		if err != nil {
 			return err
@@ -960,6 +1033,10 @@ func (s *KubernetesCommandService) RunKubernetesClusterUpgrade(c *CmdConfig) err
 	if err != nil {
 		return err
 	}
+	preflight, err := c.Doit.GetBool(c.NS, doctl.ArgClusterUpgradePreflight)
+	if err != nil {
+		return err
+	}
 
 	version, available, err := getUpgradeVersionOrLatest(c, clusterID)
 	if err != nil {
@@ -971,6 +1048,14 @@ func (s *KubernetesCommandService) RunKubernetesClusterUpgrade(c *CmdConfig) err
 	}
 
 	kube := c.Kubernetes()
+	if preflight {
+		cluster, err := kube.Get(clusterID)
+		if err != nil {
+			return err
+		}
+		return s.runKubernetesUpgradePreflight(c, cluster, version)
+	}
+
 	err = kube.Upgrade(clusterID, version)
 	if err != nil {
 		return err
@@ -1306,6 +1391,48 @@ func cachedSSOExecCredentialPath(id string) string {
 	return filepath.Join(kubeconfigCachePath(), id+"_sso.json")
 }
 
+func cachedSSORefreshTokenPath(id string) string {
+	return filepath.Join(kubeconfigCachePath(), id+"_sso_refresh.json")
+}
+
+// ssoRefreshToken is the cached refresh token of a cluster's SSO login.
+type ssoRefreshToken struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// loadCachedSSORefreshToken loads the cached SSO refresh token from disk. It returns an empty token if there's
+// none.
+func loadCachedSSORefreshToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var token ssoRefreshToken
+	if err := json.Unmarshal(b, &token); err != nil {
+		return "", err
+	}
+	return token.RefreshToken, nil
+}
+
+// cacheSSORefreshToken caches an SSO refresh token to the doctl cache directory, readable only by the user.
+func cacheSSORefreshToken(path, refreshToken string) error {
+	if err := os.MkdirAll(kubeconfigCachePath(), os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(ssoRefreshToken{RefreshToken: refreshToken})
+}
+
 // loadCachedExecCredential attempts to load the cached exec credential from disk. Never errors
 // Returns nil if there's no credential, if it failed to load it, or if it's expired.
 func loadCachedExecCredential(path string) (*clientauthentication.ExecCredential, error) {
@@ -1429,10 +1556,39 @@ func (s *KubernetesCommandService) RunKubernetesKubeconfigExecCredential(c *CmdC
 			return fmt.Errorf("Invalid %s flag: %d", doctl.ArgKubernetesSSOLocalServerPort, ssoLocalServerPort)
 		}
 
-		token, expiry, err = s.ssoLogin(context.Background(), ssoClientID, ssoIssuerURL, sso.WithLocalServerPort(uint16(ssoLocalServerPort)), sso.WithLogger(logger))
+		ssoFlow, err := c.Doit.GetString(c.NS, doctl.ArgKubernetesSSOFlow)
+		if err != nil {
+			return fmt.Errorf("Checking %s flag: %v", doctl.ArgKubernetesSSOFlow, err)
+		}
+		if ssoFlow == "" {
+			ssoFlow = string(sso.FlowAuto)
+		}
+		if !slices.Contains(sso.Flows, sso.Flow(ssoFlow)) {
+			return fmt.Errorf("Invalid %s flag: %q, must be one of: auto, browser, device", doctl.ArgKubernetesSSOFlow, ssoFlow)
+		}
+
+		refreshTokenPath := cachedSSORefreshTokenPath(clusterID)
+		refreshToken, err := loadCachedSSORefreshToken(refreshTokenPath)
+		if err != nil && Verbose {
+			warn("%v", err)
+		}
+
+		ssoToken, err := s.ssoLogin(context.Background(), ssoClientID, ssoIssuerURL,
+			sso.WithLocalServerPort(uint16(ssoLocalServerPort)),
+			sso.WithLogger(logger),
+			sso.WithFlow(sso.Flow(ssoFlow)),
+			sso.WithRefreshToken(refreshToken),
+		)
 		if err != nil {
 			return fmt.Errorf("Failed to get ID token: %w", err)
 		}
+		token, expiry = ssoToken.IDToken, ssoToken.Expiry
+
+		if ssoToken.RefreshToken != "" && ssoToken.RefreshToken != refreshToken {
+			if err := cacheSSORefreshToken(refreshTokenPath, ssoToken.RefreshToken); err != nil && Verbose {
+				warn("%v", err)
+			}
+		}
 	} else {
 		logger.Println("DO PAT login")
 		credentials, err := kube.GetCredentials(clusterID)
@@ -1493,15 +1649,10 @@ func (s *KubernetesCommandService) RunKubernetesKubeconfigSave(c *CmdConfig) err
 		return err
 	}
 
-	for _, path := range []string{
+	removeCachedCredentials(
 		cachedExecCredentialPath(kubeconfigParams.clusterID),
 		cachedSSOExecCredentialPath(kubeconfigParams.clusterID),
-	} {
-		_, err = os.Stat(path)
-		if err == nil {
-			os.Remove(path)
-		}
-	}
+	)
 
 	return s.writeOrAddToKubeconfig(kubeconfigParams, remoteKubeconfig, setCurrentContext)
 }
@@ -2953,10 +3104,20 @@ func getVersionOrLatest(c *CmdConfig) (string, error) {
 	if version != "" && version != defaultKubernetesLatestVersion {
 		return version, nil
 	}
-	versions, err := c.Kubernetes().GetVersions()
+	version, err = latestKubernetesVersion(c.Kubernetes())
 	if err != nil {
 		return "", fmt.Errorf("No version flag provided. Unable to lookup the latest version from the API: %v", err)
 	}
+	return version, nil
+}
+
+// latestKubernetesVersion returns the slug of the latest Kubernetes version
+// available for new clusters.
+func latestKubernetesVersion(kube do.KubernetesService) (string, error) {
+	versions, err := kube.GetVersions()
+	if err != nil {
+		return "", err
+	}
 	if len(versions) > 0 {
 		return versions[0].Slug, nil
 	}
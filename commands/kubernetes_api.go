@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/digitalocean/doctl/do"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// clusterRESTConfig returns the configuration of a client for the Kubernetes
+// API of a cluster, from the credentials of the cluster saved in the local
+// kubeconfig.
+func clusterRESTConfig(provider KubeconfigProvider, cluster *do.KubernetesCluster) (*rest.Config, error) {
+	local, err := provider.Local()
+	if err != nil {
+		return nil, err
+	}
+
+	name := clusterContext(local, cluster)
+	if name == "" {
+		return nil, fmt.Errorf("no credentials for cluster %s in kubeconfig file found in %q; save them with `doctl kubernetes cluster kubeconfig save %s`", cluster.Name, provider.ConfigPath(), cluster.Name)
+	}
+
+	return clientcmd.NewNonInteractiveClientConfig(*local, name, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+}
+
+// clusterContext returns the name of the context of a cluster in a
+// kubeconfig: a context whose credentials doctl provides for the cluster in
+// the current authentication context, or else the context named as in the
+// kubeconfig of the cluster. It returns an empty string if there is none.
+func clusterContext(config *clientcmdapi.Config, cluster *do.KubernetesCluster) string {
+	authContext := getCurrentAuthContextFn()
+
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		clusterID, clusterAuthContext, ok := execCredentialCluster(config.AuthInfos[config.Contexts[name].AuthInfo])
+		if ok && clusterID == cluster.ID && (clusterAuthContext == "" || clusterAuthContext == authContext) {
+			return name
+		}
+	}
+
+	name := fmt.Sprintf("do-%s-%s", cluster.RegionSlug, cluster.Name)
+	if _, ok := config.Contexts[name]; ok {
+		return name
+	}
+	return ""
+}
+
+// coreV1Client returns a client for the core/v1 group of a Kubernetes API.
+func coreV1Client(config *rest.Config) (*rest.RESTClient, error) {
+	config = rest.CopyConfig(config)
+	config.APIPath = "/api"
+	config.GroupVersion = &corev1.SchemeGroupVersion
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return rest.RESTClientFor(config)
+}
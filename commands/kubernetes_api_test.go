@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// fakeKubernetesAPI serves handler as the Kubernetes API of the cluster web
+// in nyc1, and returns a service whose local kubeconfig holds the cluster's
+// credentials, the token "secret".
+func fakeKubernetesAPI(t *testing.T, handler http.Handler) *KubernetesCommandService {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	// Client credentials are only sent over TLS.
+	kubeconfig := syncTestKubeconfig("do-nyc1-web")
+	kubeconfig.Clusters["do-nyc1-web"] = &clientcmdapi.Cluster{
+		Server:                   server.URL,
+		CertificateAuthorityData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+	}
+	kubeconfig.AuthInfos["do-nyc1-web-admin"] = &clientcmdapi.AuthInfo{Token: "secret"}
+
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, clientcmd.WriteToFile(*kubeconfig, path))
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = path
+	return &KubernetesCommandService{KubeconfigProvider: &kubeconfigProvider{pathOptions: pathOptions}}
+}
+
+func TestClusterContext(t *testing.T) {
+	getCurrentAuthContextFn = func() string { return "default" }
+	defer func() { getCurrentAuthContextFn = defaultGetCurrentAuthContextFn }()
+
+	cluster := &do.KubernetesCluster{KubernetesCluster: &godo.KubernetesCluster{ID: "web-id", Name: "web", RegionSlug: "nyc1"}}
+
+	config := mergeTestKubeconfigs(
+		syncTestKubeconfig("do-nyc1-web"),
+		execCredentialKubeconfig("web-other-account", "web-id", "work"),
+		execCredentialKubeconfig("web", "web-id", "default"),
+	)
+	assert.Equal(t, "web", clusterContext(config, cluster))
+
+	delete(config.Contexts, "web")
+	assert.Equal(t, "do-nyc1-web", clusterContext(config, cluster))
+
+	delete(config.Contexts, "do-nyc1-web")
+	assert.Empty(t, clusterContext(config, cluster))
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// syncedKubeconfig is the kubeconfig of a cluster saved by kubeconfig sync.
+type syncedKubeconfig struct {
+	params kubeconfigParams
+	config *clientcmdapi.Config
+}
+
+// RunKubernetesKubeconfigSync saves the credentials of the clusters in the
+// account to a kubeconfig and, with --prune, removes the contexts of
+// clusters that no longer exist.
+func (s *KubernetesCommandService) RunKubernetesKubeconfigSync(c *CmdConfig) error {
+	tag, err := c.Doit.GetString(c.NS, doctl.ArgTag)
+	if err != nil {
+		return err
+	}
+	region, err := c.Doit.GetString(c.NS, doctl.ArgRegionSlug)
+	if err != nil {
+		return err
+	}
+	prune, err := c.Doit.GetBool(c.NS, doctl.ArgKubeConfigPrune)
+	if err != nil {
+		return err
+	}
+	path, err := c.Doit.GetString(c.NS, doctl.ArgKubeConfigPath)
+	if err != nil {
+		return err
+	}
+	dir, err := c.Doit.GetString(c.NS, doctl.ArgKubeConfigOutputDir)
+	if err != nil {
+		return err
+	}
+	if path != "" && dir != "" {
+		return fmt.Errorf("--%s cannot be combined with --%s", doctl.ArgKubeConfigPath, doctl.ArgKubeConfigOutputDir)
+	}
+	cfgType, err := c.Doit.GetString(c.NS, doctl.ArgKubeConfigType)
+	if err != nil {
+		return err
+	}
+	expirySeconds, err := c.Doit.GetInt(c.NS, doctl.ArgKubeConfigExpirySeconds)
+	if err != nil {
+		return err
+	}
+	if expirySeconds > 0 && cfgType != "" {
+		return fmt.Errorf("cannot use %s flag with %s flag", doctl.ArgKubeConfigType, doctl.ArgKubeConfigExpirySeconds)
+	}
+
+	kube := c.Kubernetes()
+	clusters, err := kube.List()
+	if err != nil {
+		return err
+	}
+
+	// Contexts are only pruned when their cluster is missing from the whole
+	// account, not just from the clusters selected by the filters. List
+	// returns every cluster of the account or an error, never a truncated
+	// list, so a missing cluster was deleted.
+	existing := map[string]bool{}
+	var synced []syncedKubeconfig
+	for _, cluster := range clusters {
+		existing[cluster.ID] = true
+		if region != "" && cluster.RegionSlug != region {
+			continue
+		}
+		if tag != "" && !slices.Contains(cluster.Tags, tag) {
+			continue
+		}
+
+		params := kubeconfigParams{clusterID: cluster.ID, expirySeconds: expirySeconds, cfgType: cfgType}
+		remote, err := s.KubeconfigProvider.Remote(kube, params)
+		if err != nil {
+			return fmt.Errorf("getting the credentials of cluster %s: %w", cluster.Name, err)
+		}
+		removeCachedCredentials(cachedExecCredentialPath(cluster.ID), cachedSSOExecCredentialPath(cluster.ID))
+
+		synced = append(synced, syncedKubeconfig{params: params, config: remote})
+	}
+
+	if dir != "" {
+		return syncKubeconfigDir(dir, synced, existing, prune)
+	}
+
+	target := s.KubeconfigProvider
+	if path != "" {
+		pathOptions := clientcmd.NewDefaultPathOptions()
+		pathOptions.LoadingRules.ExplicitPath = path
+		target = &kubeconfigProvider{pathOptions: pathOptions}
+	}
+
+	local, err := target.Local()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range synced {
+		if err := mergeKubeconfig(k.params, k.config, local, false); err != nil {
+			return fmt.Errorf("Couldn't use the kubeconfig info received, %v", err)
+		}
+	}
+	notice("Adding the credentials of %d clusters to kubeconfig file found in %q", len(synced), target.ConfigPath())
+
+	if prune {
+		pruneKubeconfig(local, existing)
+	}
+
+	return target.Write(local)
+}
+
+// syncKubeconfigDir writes the kubeconfig of each cluster to a file named
+// after its context in dir. With prune, the contexts of clusters that no
+// longer exist are removed from the other files of dir, and files left
+// without contexts are deleted.
+func syncKubeconfigDir(dir string, synced []syncedKubeconfig, existing map[string]bool, prune bool) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for _, k := range synced {
+		config := clientcmdapi.NewConfig()
+		if err := mergeKubeconfig(k.params, k.config, config, true); err != nil {
+			return fmt.Errorf("Couldn't use the kubeconfig info received, %v", err)
+		}
+		if err := clientcmd.WriteToFile(*config, filepath.Join(dir, k.config.CurrentContext+".yaml")); err != nil {
+			return err
+		}
+	}
+	notice("Writing the credentials of %d clusters to kubeconfig files in %q", len(synced), dir)
+
+	if !prune {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		config, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			warn("Skipping %q: %v", path, err)
+			continue
+		}
+		if len(pruneKubeconfig(config, existing)) == 0 {
+			continue
+		}
+
+		if len(config.Contexts) == 0 {
+			err = os.Remove(path)
+		} else {
+			err = clientcmd.WriteToFile(*config, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneKubeconfig removes the contexts whose credentials doctl provides for
+// a cluster of the current authentication context that is not in existing,
+// along with their cluster and user entries when no other context uses them.
+// Contexts saved without an authentication context are kept, since the
+// account of their cluster is unknown. It returns the names of the removed
+// contexts.
+func pruneKubeconfig(config *clientcmdapi.Config, existing map[string]bool) []string {
+	authContext := getCurrentAuthContextFn()
+
+	var removed []string
+	var stale []*clientcmdapi.Context
+	for name, kctx := range config.Contexts {
+		clusterID, clusterAuthContext, ok := execCredentialCluster(config.AuthInfos[kctx.AuthInfo])
+		if !ok || existing[clusterID] || clusterAuthContext == "" || clusterAuthContext != authContext {
+			continue
+		}
+
+		delete(config.Contexts, name)
+		removed = append(removed, name)
+		stale = append(stale, kctx)
+		removeCachedCredentials(cachedExecCredentialPath(clusterID), cachedSSOExecCredentialPath(clusterID), cachedSSORefreshTokenPath(clusterID))
+		if config.CurrentContext == name {
+			config.CurrentContext = ""
+		}
+	}
+
+	for _, kctx := range stale {
+		clusterUsed, authInfoUsed := false, false
+		for _, other := range config.Contexts {
+			clusterUsed = clusterUsed || other.Cluster == kctx.Cluster
+			authInfoUsed = authInfoUsed || other.AuthInfo == kctx.AuthInfo
+		}
+		if !clusterUsed {
+			delete(config.Clusters, kctx.Cluster)
+		}
+		if !authInfoUsed {
+			delete(config.AuthInfos, kctx.AuthInfo)
+		}
+	}
+
+	sort.Strings(removed)
+	for _, name := range removed {
+		notice("Removing context %s of a cluster that no longer exists", name)
+	}
+	return removed
+}
+
+// execCredentialCluster returns the ID of the cluster a user entry gets its
+// credentials for from doctl's exec-credential command, and the doctl
+// authentication context it uses, if any.
+func execCredentialCluster(authInfo *clientcmdapi.AuthInfo) (string, string, bool) {
+	if authInfo == nil || authInfo.Exec == nil {
+		return "", "", false
+	}
+
+	args := authInfo.Exec.Args
+	i := slices.Index(args, "exec-credential")
+	if i < 0 {
+		return "", "", false
+	}
+
+	var clusterID, authContext string
+	for _, arg := range args[i+1:] {
+		if v, ok := strings.CutPrefix(arg, "--"+doctl.ArgContext+"="); ok {
+			authContext = v
+		} else if !strings.HasPrefix(arg, "-") {
+			clusterID = arg
+		}
+	}
+
+	return clusterID, authContext, clusterID != ""
+}
+
+// removeCachedCredentials removes cached credential files, ignoring those
+// that do not exist.
+func removeCachedCredentials(paths ...string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// syncTestKubeconfig returns a kubeconfig with a single context named name,
+// as the API returns for a cluster.
+func syncTestKubeconfig(name string) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.CurrentContext = name
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name + "-admin"}
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name + ".k8s.ondigitalocean.com"}
+	config.AuthInfos[name+"-admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	return config
+}
+
+// execCredentialKubeconfig returns a kubeconfig with a context named name
+// whose credentials doctl provides for a cluster, with the authentication
+// context authContext if it is not empty.
+func execCredentialKubeconfig(name, clusterID, authContext string) *clientcmdapi.Config {
+	args := []string{"kubernetes", "cluster", "kubeconfig", "exec-credential", "--version=v1beta1"}
+	if authContext != "" {
+		args = append(args, "--context="+authContext)
+	}
+
+	config := syncTestKubeconfig(name)
+	config.AuthInfos[name+"-admin"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "doctl",
+		Args:       append(args, clusterID),
+	}}
+	return config
+}
+
+func mergeTestKubeconfigs(configs ...*clientcmdapi.Config) *clientcmdapi.Config {
+	merged := clientcmdapi.NewConfig()
+	for _, config := range configs {
+		for k, v := range config.Contexts {
+			merged.Contexts[k] = v
+		}
+		for k, v := range config.Clusters {
+			merged.Clusters[k] = v
+		}
+		for k, v := range config.AuthInfos {
+			merged.AuthInfos[k] = v
+		}
+	}
+	return merged
+}
+
+func withSyncTestClusters(t *testing.T, tm *tcMocks) {
+	getCurrentAuthContextFn = func() string { return "default" }
+	t.Cleanup(func() { getCurrentAuthContextFn = defaultGetCurrentAuthContextFn })
+
+	tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{
+		{KubernetesCluster: &godo.KubernetesCluster{ID: "web-id", Name: "web", RegionSlug: "nyc1", Tags: []string{"k8s", "prod"}}},
+		{KubernetesCluster: &godo.KubernetesCluster{ID: "api-id", Name: "api", RegionSlug: "sfo3", Tags: []string{"k8s"}}},
+	}, nil)
+
+	web, err := clientcmd.Write(*syncTestKubeconfig("do-nyc1-web"))
+	require.NoError(t, err)
+	tm.kubernetes.EXPECT().GetKubeConfig("web-id", &godo.KubernetesClusterKubeconfigGetRequest{}).Return(web, nil)
+}
+
+func TestKubernetesKubeconfigSync(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		withSyncTestClusters(t, tm)
+
+		path := filepath.Join(t.TempDir(), "config")
+		local := mergeTestKubeconfigs(
+			syncTestKubeconfig("minikube"),
+			execCredentialKubeconfig("do-nyc1-old", "old-id", "default"),
+			execCredentialKubeconfig("do-nyc1-other-account", "other-id", "work"),
+			execCredentialKubeconfig("do-nyc1-unknown-account", "unknown-id", ""),
+		)
+		local.CurrentContext = "do-nyc1-old"
+		require.NoError(t, clientcmd.WriteToFile(*local, path))
+
+		config.Doit.Set(config.NS, doctl.ArgRegionSlug, "nyc1")
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigPrune, true)
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigPath, path)
+
+		s := &KubernetesCommandService{KubeconfigProvider: &kubeconfigProvider{pathOptions: clientcmd.NewDefaultPathOptions()}}
+		err := s.RunKubernetesKubeconfigSync(config)
+		require.NoError(t, err)
+
+		written, err := clientcmd.LoadFromFile(path)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"minikube", "do-nyc1-other-account", "do-nyc1-unknown-account", "do-nyc1-web"}, mapKeys(written.Contexts))
+		assert.ElementsMatch(t, []string{"minikube", "do-nyc1-other-account", "do-nyc1-unknown-account", "do-nyc1-web"}, mapKeys(written.Clusters))
+		assert.ElementsMatch(t, []string{"minikube-admin", "do-nyc1-other-account-admin", "do-nyc1-unknown-account-admin", "do-nyc1-web-admin"}, mapKeys(written.AuthInfos))
+		assert.Empty(t, written.CurrentContext)
+
+		clusterID, authContext, ok := execCredentialCluster(written.AuthInfos["do-nyc1-web-admin"])
+		assert.True(t, ok)
+		assert.Equal(t, "web-id", clusterID)
+		assert.Equal(t, "default", authContext)
+	})
+}
+
+func TestKubernetesKubeconfigSyncOutputDir(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		withSyncTestClusters(t, tm)
+
+		dir := t.TempDir()
+		stale := filepath.Join(dir, "do-nyc1-old.yaml")
+		require.NoError(t, clientcmd.WriteToFile(*execCredentialKubeconfig("do-nyc1-old", "old-id", "default"), stale))
+
+		config.Doit.Set(config.NS, doctl.ArgTag, "prod")
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigPrune, true)
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigOutputDir, dir)
+
+		s := &KubernetesCommandService{KubeconfigProvider: &kubeconfigProvider{pathOptions: clientcmd.NewDefaultPathOptions()}}
+		err := s.RunKubernetesKubeconfigSync(config)
+		require.NoError(t, err)
+
+		_, err = os.Stat(stale)
+		assert.True(t, os.IsNotExist(err))
+
+		written, err := clientcmd.LoadFromFile(filepath.Join(dir, "do-nyc1-web.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "do-nyc1-web", written.CurrentContext)
+		assert.ElementsMatch(t, []string{"do-nyc1-web"}, mapKeys(written.Contexts))
+	})
+}
+
+func TestKubernetesKubeconfigSyncFlags(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigPath, "config")
+		config.Doit.Set(config.NS, doctl.ArgKubeConfigOutputDir, "configs")
+
+		err := testK8sCmdService().RunKubernetesKubeconfigSync(config)
+		assert.EqualError(t, err, "--kubeconfig cannot be combined with --output-dir")
+	})
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
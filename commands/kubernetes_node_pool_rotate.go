@@ -0,0 +1,385 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	kubernetesNodeStateRunning = "running"
+
+	defaultNodePoolRotateTimeout = 30 * time.Minute
+)
+
+// nodePoolRotatePollInterval is a variable so tests can replace it.
+var nodePoolRotatePollInterval = 10 * time.Second
+
+// RunKubernetesNodePoolRotate replaces the nodes of a node pool, a batch at a
+// time, waiting for the replacements of each batch to be running.
+func (s *KubernetesCommandService) RunKubernetesNodePoolRotate(c *CmdConfig) error {
+	if len(c.Args) != 2 {
+		return doctl.NewMissingArgsErr(c.NS)
+	}
+	batch, err := c.Doit.GetInt(c.NS, doctl.ArgNodePoolRotateBatch)
+	if err != nil {
+		return err
+	}
+	if batch < 1 {
+		return fmt.Errorf("--%s must be at least 1", doctl.ArgNodePoolRotateBatch)
+	}
+	drain, err := c.Doit.GetBool(c.NS, doctl.ArgNodePoolRotateDrain)
+	if err != nil {
+		return err
+	}
+	timeout, err := c.Doit.GetDuration(c.NS, doctl.ArgTimeout)
+	if err != nil {
+		return err
+	}
+	force, err := c.Doit.GetBool(c.NS, doctl.ArgForce)
+	if err != nil {
+		return err
+	}
+
+	kube := c.Kubernetes()
+	cluster, err := clusterByIDorName(kube, c.Args[0])
+	if err != nil {
+		return err
+	}
+	pool, err := poolByIDorName(kube, cluster.ID, c.Args[1])
+	if err != nil {
+		return err
+	}
+	if len(pool.Nodes) == 0 {
+		return fmt.Errorf("node pool %s has no nodes", pool.Name)
+	}
+
+	var drainer *nodeDrainer
+	if drain {
+		config, err := clusterRESTConfig(s.KubeconfigProvider, cluster)
+		if err != nil {
+			return err
+		}
+		client, err := coreV1Client(config)
+		if err != nil {
+			return err
+		}
+		drainer = &nodeDrainer{client: client, timeout: timeout}
+	}
+
+	if !force && AskForConfirm(fmt.Sprintf("replace the %d nodes of node pool %s, %d at a time?", len(pool.Nodes), pool.Name, batch)) != nil {
+		return errOperationAborted
+	}
+
+	r := &nodePoolRotation{
+		kube:      kube,
+		clusterID: cluster.ID,
+		poolID:    pool.ID,
+		drainer:   drainer,
+		timeout:   timeout,
+		known:     map[string]bool{},
+	}
+	for _, node := range pool.Nodes {
+		r.known[node.ID] = true
+		r.results = append(r.results, displayers.KubernetesRotatedNode{ID: node.ID, Name: node.Name, Status: rolloutStatusPending})
+	}
+
+	for i := 0; i < len(pool.Nodes) && err == nil; i += batch {
+		err = r.replace(pool.Nodes[i:min(i+batch, len(pool.Nodes))], i)
+	}
+
+	if displayErr := c.Display(&displayers.KubernetesRotatedNodes{Nodes: r.results}); displayErr != nil {
+		return displayErr
+	}
+	if err != nil {
+		return fmt.Errorf("rotation stopped: %w", err)
+	}
+	return nil
+}
+
+// nodePoolRotation is the state of a node pool rotation across batches of
+// nodes.
+type nodePoolRotation struct {
+	kube      do.KubernetesService
+	clusterID string
+	poolID    string
+	drainer   *nodeDrainer
+	timeout   time.Duration
+
+	// known holds the IDs of the nodes that are not replacements of the
+	// current batch: the nodes of the pool when the rotation started and the
+	// replacements of the previous batches.
+	known   map[string]bool
+	results []displayers.KubernetesRotatedNode
+}
+
+// replace drains, when a drainer is set, and replaces a batch of nodes, whose
+// results start at offset, then waits for their replacements to be running.
+// The whole batch is cordoned before any node is drained, so that the pods
+// evicted from one node are not scheduled on another node of the batch, and
+// the nodes left unreplaced after a failure are uncordoned. After a failure,
+// it still waits for the replacements of the nodes already replaced, and
+// returns the first failure.
+func (r *nodePoolRotation) replace(batch []*godo.KubernetesNode, offset int) error {
+	fail := func(i int, err error) error {
+		r.results[offset+i].Status = rolloutStatusFailed
+		r.results[offset+i].Error = err.Error()
+		return fmt.Errorf("node %s: %w", batch[i].Name, err)
+	}
+
+	if r.drainer != nil {
+		for i, node := range batch {
+			notice("Cordoning node %s", node.Name)
+			if err := r.drainer.cordon(node.Name); err != nil {
+				r.uncordon(batch[:i])
+				return fail(i, fmt.Errorf("cordoning: %w", err))
+			}
+		}
+	}
+
+	var replaced []int
+	var firstErr error
+	for i, node := range batch {
+		if r.drainer != nil {
+			notice("Draining node %s", node.Name)
+			if err := r.drainer.drain(node.Name); err != nil {
+				firstErr = fail(i, err)
+				r.uncordon(batch[i:])
+				break
+			}
+		}
+
+		notice("Replacing node %s", node.Name)
+		err := r.kube.DeleteNode(r.clusterID, r.poolID, node.ID, &godo.KubernetesNodeDeleteRequest{
+			Replace: true,
+			// A node drained through the Kubernetes API needs no other drain.
+			SkipDrain: r.drainer != nil,
+		})
+		if err != nil {
+			firstErr = fail(i, fmt.Errorf("replacing: %w", err))
+			r.uncordon(batch[i:])
+			break
+		}
+		replaced = append(replaced, i)
+	}
+	if len(replaced) == 0 {
+		return firstErr
+	}
+
+	ids := make([]string, len(replaced))
+	names := make([]string, len(replaced))
+	for j, i := range replaced {
+		ids[j] = batch[i].ID
+		names[j] = batch[i].Name
+	}
+
+	notice("Waiting for %d replacement nodes to be running", len(replaced))
+	replacements, err := r.waitForReplacements(ids)
+	if err != nil {
+		for _, i := range replaced {
+			fail(i, err)
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("nodes %s: %w", strings.Join(names, ","), err)
+		}
+		return firstErr
+	}
+
+	for j, i := range replaced {
+		r.known[replacements[j].ID] = true
+		r.results[offset+i].Status = rolloutStatusReplaced
+		r.results[offset+i].Replacement = replacements[j].Name
+	}
+	return firstErr
+}
+
+// uncordon makes nodes that were cordoned but not replaced schedulable again,
+// when a drainer is set.
+func (r *nodePoolRotation) uncordon(nodes []*godo.KubernetesNode) {
+	if r.drainer == nil {
+		return
+	}
+	for _, node := range nodes {
+		notice("Uncordoning node %s", node.Name)
+		if err := r.drainer.uncordon(node.Name); err != nil {
+			warn("Could not uncordon node %s: %v", node.Name, err)
+		}
+	}
+}
+
+// waitForReplacements polls the node pool until the replaced nodes are gone
+// from it and as many new nodes are running, and returns the new nodes.
+func (r *nodePoolRotation) waitForReplacements(replaced []string) ([]*godo.KubernetesNode, error) {
+	deadline := time.Now().Add(r.timeout)
+	failCount := 0
+	for {
+		pool, err := r.kube.GetNodePool(r.clusterID, r.poolID)
+		if err == nil {
+			failCount = 0
+
+			gone := true
+			var running []*godo.KubernetesNode
+			for _, node := range pool.Nodes {
+				switch {
+				case slices.Contains(replaced, node.ID):
+					gone = false
+				case !r.known[node.ID] && node.Status != nil && node.Status.State == kubernetesNodeStateRunning:
+					running = append(running, node)
+				}
+			}
+			if gone && len(running) >= len(replaced) {
+				return running[:len(replaced)], nil
+			}
+		} else {
+			// Allow for transient API failures
+			failCount++
+			if failCount >= maxAPIFailures {
+				return nil, err
+			}
+		}
+
+		wait := min(nodePoolRotatePollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return nil, fmt.Errorf("timed out after %s waiting for the replacement nodes to be running", r.timeout)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// nodeDrainer cordons and drains nodes through the Kubernetes API of a
+// cluster.
+type nodeDrainer struct {
+	client  *rest.RESTClient
+	timeout time.Duration
+}
+
+// cordon marks a node unschedulable.
+func (d *nodeDrainer) cordon(name string) error {
+	return d.setUnschedulable(name, true)
+}
+
+// uncordon marks a node schedulable again.
+func (d *nodeDrainer) uncordon(name string) error {
+	return d.setUnschedulable(name, false)
+}
+
+func (d *nodeDrainer) setUnschedulable(name string, unschedulable bool) error {
+	return d.client.Patch(types.StrategicMergePatchType).
+		Resource("nodes").
+		Name(name).
+		Body([]byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))).
+		Do(context.TODO()).
+		Error()
+}
+
+// drain evicts the pods of a cordoned node, retrying the evictions that a
+// PodDisruptionBudget does not allow yet, until the pods are gone.
+func (d *nodeDrainer) drain(name string) error {
+	ctx := context.TODO()
+
+	deadline := time.Now().Add(d.timeout)
+	for {
+		pods, err := d.evictablePods(ctx, name)
+		if err != nil {
+			return fmt.Errorf("listing pods: %w", err)
+		}
+		if len(pods) == 0 {
+			return nil
+		}
+
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			err := d.evict(ctx, pod)
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				// A PodDisruptionBudget does not allow the eviction yet.
+			default:
+				return fmt.Errorf("evicting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+		}
+
+		wait := min(nodePoolRotatePollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return fmt.Errorf("timed out after %s waiting for %d pods to be evicted", d.timeout, len(pods))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// evictablePods returns the pods of a node that a drain evicts: those that
+// are not mirror pods, DaemonSet pods or finished.
+func (d *nodeDrainer) evictablePods(ctx context.Context, node string) ([]corev1.Pod, error) {
+	var list corev1.PodList
+	err := d.client.Get().
+		Resource("pods").
+		Param("fieldSelector", "spec.nodeName="+node).
+		Do(ctx).
+		Into(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+func (d *nodeDrainer) evict(ctx context.Context, pod corev1.Pod) error {
+	body, err := json.Marshal(&policyv1.Eviction{
+		TypeMeta:   metav1.TypeMeta{APIVersion: policyv1.SchemeGroupVersion.String(), Kind: "Eviction"},
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.client.Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("eviction").
+		Body(body).
+		Do(ctx).
+		Error()
+}
@@ -0,0 +1,308 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func rotateTestNode(id, state string) *godo.KubernetesNode {
+	return &godo.KubernetesNode{ID: id, Name: "pool-" + id, Status: &godo.KubernetesNodeStatus{State: state}}
+}
+
+func rotateTestNodePool(nodes ...*godo.KubernetesNode) *do.KubernetesNodePool {
+	return &do.KubernetesNodePool{KubernetesNodePool: &godo.KubernetesNodePool{ID: "pool-id", Name: "pool", Nodes: nodes}}
+}
+
+func withRotateTestPool(t *testing.T, tm *tcMocks, nodes ...*godo.KubernetesNode) {
+	interval := nodePoolRotatePollInterval
+	nodePoolRotatePollInterval = time.Millisecond
+	t.Cleanup(func() { nodePoolRotatePollInterval = interval })
+
+	tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{
+		{KubernetesCluster: &godo.KubernetesCluster{ID: "cluster-id", Name: "web", RegionSlug: "nyc1"}},
+	}, nil)
+	tm.kubernetes.EXPECT().ListNodePools("cluster-id").Return(do.KubernetesNodePools{*rotateTestNodePool(nodes...)}, nil)
+}
+
+func TestKubernetesNodePoolRotate(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		withRotateTestPool(t, tm,
+			rotateTestNode("1", "running"),
+			rotateTestNode("2", "running"),
+			rotateTestNode("3", "running"),
+		)
+
+		replace := &godo.KubernetesNodeDeleteRequest{Replace: true}
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "1", replace).Return(nil)
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "2", replace).Return(nil)
+		gomock.InOrder(
+			tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(
+				rotateTestNode("1", "deleting"),
+				rotateTestNode("2", "running"),
+				rotateTestNode("3", "running"),
+				rotateTestNode("4", "provisioning"),
+			), nil),
+			tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(
+				rotateTestNode("3", "running"),
+				rotateTestNode("4", "running"),
+				rotateTestNode("5", "running"),
+			), nil),
+		)
+
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "3", replace).Return(nil)
+		tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(
+			rotateTestNode("4", "running"),
+			rotateTestNode("5", "running"),
+			rotateTestNode("6", "running"),
+		), nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 2)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := testK8sCmdService().RunKubernetesNodePoolRotate(config)
+		require.NoError(t, err)
+
+		expected := `ID    Name      Status      Replacement    Error
+1     pool-1    replaced    pool-4         
+2     pool-2    replaced    pool-5         
+3     pool-3    replaced    pool-6         
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesNodePoolRotateTimeout(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		withRotateTestPool(t, tm, rotateTestNode("1", "running"), rotateTestNode("2", "running"))
+
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "1", &godo.KubernetesNodeDeleteRequest{Replace: true}).Return(nil)
+		tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(
+			rotateTestNode("2", "running"),
+			rotateTestNode("3", "provisioning"),
+		), nil).AnyTimes()
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 1)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, 10*time.Millisecond)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := testK8sCmdService().RunKubernetesNodePoolRotate(config)
+		assert.EqualError(t, err, "rotation stopped: nodes pool-1: timed out after 10ms waiting for the replacement nodes to be running")
+
+		expected := `ID    Name      Status     Replacement    Error
+1     pool-1    failed                    timed out after 10ms waiting for the replacement nodes to be running
+2     pool-2    pending                   
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+// fakeDrainServer is a Kubernetes API server whose node pool-1 runs an
+// application pod, a DaemonSet pod and a completed pod, and whose other nodes
+// run no pods. The first eviction of the application pod is refused as if by
+// a PodDisruptionBudget, and every eviction fails when failEvictions is set.
+// It records the nodes cordoned and uncordoned, and the pods evicted, in
+// events.
+type fakeDrainServer struct {
+	mu            sync.Mutex
+	failEvictions bool
+	events        []string
+	evictions     int
+	evicted       bool
+}
+
+func (f *fakeDrainServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	node, isNode := strings.CutPrefix(r.URL.Path, "/api/v1/nodes/")
+	nodeName, isPodList := strings.CutPrefix(r.URL.Query().Get("fieldSelector"), "spec.nodeName=")
+	switch {
+	case r.Method == http.MethodPatch && isNode:
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case `{"spec":{"unschedulable":true}}`:
+			f.events = append(f.events, "cordon "+node)
+		case `{"spec":{"unschedulable":false}}`:
+			f.events = append(f.events, "uncordon "+node)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"kind": "Node", "apiVersion": "v1", "metadata": map[string]any{"name": node}})
+
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/pods" && isPodList:
+		var pods []map[string]any
+		if nodeName == "pool-1" {
+			pods = []map[string]any{
+				{
+					"metadata": map[string]any{"name": "agent", "namespace": "kube-system", "ownerReferences": []map[string]any{
+						{"apiVersion": "apps/v1", "kind": "DaemonSet", "name": "agent", "uid": "1", "controller": true},
+					}},
+				},
+				{"metadata": map[string]any{"name": "job", "namespace": "default"}, "status": map[string]any{"phase": "Succeeded"}},
+			}
+			if !f.evicted {
+				pods = append(pods, map[string]any{"metadata": map[string]any{"name": "app", "namespace": "default"}})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"kind": "PodList", "apiVersion": "v1", "items": pods})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/default/pods/app/eviction":
+		var eviction map[string]any
+		json.NewDecoder(r.Body).Decode(&eviction)
+		if eviction["kind"] != "Eviction" || eviction["apiVersion"] != "policy/v1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if f.failEvictions {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"InternalError","code":500,"message":"etcd is unavailable"}`)
+			return
+		}
+
+		f.evictions++
+		if f.evictions == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"TooManyRequests","code":429,"message":"Cannot evict pod as it would violate the pod's disruption budget."}`)
+			return
+		}
+		f.evicted = true
+		f.events = append(f.events, "evict default/app")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Success","code":201}`)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+	}
+}
+
+func TestKubernetesNodePoolRotateDrain(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		fake := &fakeDrainServer{}
+		s := fakeKubernetesAPI(t, fake)
+
+		withRotateTestPool(t, tm, rotateTestNode("1", "running"))
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "1", &godo.KubernetesNodeDeleteRequest{Replace: true, SkipDrain: true}).Return(nil)
+		tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(rotateTestNode("2", "running")), nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 1)
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateDrain, true)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := s.RunKubernetesNodePoolRotate(config)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"cordon pool-1", "evict default/app"}, fake.events)
+		assert.Equal(t, 2, fake.evictions)
+
+		expected := `ID    Name      Status      Replacement    Error
+1     pool-1    replaced    pool-2         
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesNodePoolRotateDrainBatch(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		fake := &fakeDrainServer{}
+		s := fakeKubernetesAPI(t, fake)
+
+		withRotateTestPool(t, tm, rotateTestNode("1", "running"), rotateTestNode("2", "running"))
+		skipDrain := &godo.KubernetesNodeDeleteRequest{Replace: true, SkipDrain: true}
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "1", skipDrain).Return(nil)
+		tm.kubernetes.EXPECT().DeleteNode("cluster-id", "pool-id", "2", skipDrain).Return(nil)
+		tm.kubernetes.EXPECT().GetNodePool("cluster-id", "pool-id").Return(rotateTestNodePool(
+			rotateTestNode("3", "running"),
+			rotateTestNode("4", "running"),
+		), nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 2)
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateDrain, true)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := s.RunKubernetesNodePoolRotate(config)
+		require.NoError(t, err)
+
+		// the pods evicted from pool-1 cannot be scheduled on pool-2
+		assert.Equal(t, []string{"cordon pool-1", "cordon pool-2", "evict default/app"}, fake.events)
+	})
+}
+
+func TestKubernetesNodePoolRotateDrainFailure(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		fake := &fakeDrainServer{failEvictions: true}
+		s := fakeKubernetesAPI(t, fake)
+
+		withRotateTestPool(t, tm, rotateTestNode("1", "running"), rotateTestNode("2", "running"))
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 2)
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateDrain, true)
+		config.Doit.Set(config.NS, doctl.ArgTimeout, time.Minute)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := s.RunKubernetesNodePoolRotate(config)
+		assert.EqualError(t, err, "rotation stopped: node pool-1: evicting pod default/app: etcd is unavailable")
+
+		// no node was replaced, so the whole batch is schedulable again
+		assert.Equal(t, []string{"cordon pool-1", "cordon pool-2", "uncordon pool-1", "uncordon pool-2"}, fake.events)
+
+		expected := `ID    Name      Status     Replacement    Error
+1     pool-1    failed                    evicting pod default/app: etcd is unavailable
+2     pool-2    pending                   
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesNodePoolRotateDrainWithoutCredentials(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		withRotateTestPool(t, tm, rotateTestNode("1", "running"))
+
+		config.Args = append(config.Args, "web", "pool")
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateBatch, 1)
+		config.Doit.Set(config.NS, doctl.ArgNodePoolRotateDrain, true)
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+
+		err := testK8sCmdService().RunKubernetesNodePoolRotate(config)
+		assert.EqualError(t, err, "no credentials for cluster web in kubeconfig file found in \"/some/kube/path\"; save them with `doctl kubernetes cluster kubeconfig save web`")
+	})
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	preflightOK      = "ok"
+	preflightWarning = "warning"
+	preflightBlocker = "blocker"
+
+	preflightCheckRemovedAPI   = "removed-api"
+	preflightCheckPDB          = "pod-disruption-budget"
+	preflightCheckSurgeUpgrade = "surge-upgrade"
+	preflightCheckNodePool     = "node-pool"
+
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+//go:embed kubernetes_removed_apis.yaml
+var removedKubernetesAPIsYAML []byte
+
+// removedKubernetesAPI is an API version of a resource that Kubernetes
+// stopped serving in the minor version RemovedIn.
+type removedKubernetesAPI struct {
+	RemovedIn   string `json:"removedIn"`
+	Group       string `json:"group"`
+	Version     string `json:"version"`
+	Resource    string `json:"resource"`
+	Kind        string `json:"kind"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+func (api removedKubernetesAPI) groupVersion() string {
+	return api.Group + "/" + api.Version
+}
+
+// removedKubernetesAPIs returns the API versions removed by an upgrade from
+// the version from to the version to.
+func removedKubernetesAPIs(from, to string) ([]removedKubernetesAPI, error) {
+	fromMinor, err := minorVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	toMinor, err := minorVersion(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []removedKubernetesAPI
+	if err := yaml.Unmarshal(removedKubernetesAPIsYAML, &all); err != nil {
+		return nil, fmt.Errorf("parsing the removed Kubernetes APIs: %w", err)
+	}
+
+	var removed []removedKubernetesAPI
+	for _, api := range all {
+		removedIn, err := minorVersion(api.RemovedIn)
+		if err != nil {
+			return nil, err
+		}
+		if removedIn.GT(fromMinor) && removedIn.LTE(toMinor) {
+			removed = append(removed, api)
+		}
+	}
+	return removed, nil
+}
+
+// minorVersion returns the major and minor parts of a Kubernetes version or
+// version slug.
+func minorVersion(version string) (semver.Version, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return semver.Version{Major: v.Major, Minor: v.Minor}, nil
+}
+
+// runKubernetesUpgradePreflight checks a cluster for what would block or
+// disrupt its upgrade to version, through its Kubernetes API, and returns an
+// error if anything blocks the upgrade.
+func (s *KubernetesCommandService) runKubernetesUpgradePreflight(c *CmdConfig, cluster *do.KubernetesCluster, version string) error {
+	apis, err := removedKubernetesAPIs(cluster.VersionSlug, version)
+	if err != nil {
+		return err
+	}
+
+	config, err := clusterRESTConfig(s.KubeconfigProvider, cluster)
+	if err != nil {
+		return err
+	}
+	client, err := coreV1Client(config)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	removed, err := checkRemovedAPIs(ctx, client, apis, version)
+	if err != nil {
+		return err
+	}
+	pdbs, err := checkPodDisruptionBudgets(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	checks := append(removed, pdbs...)
+	checks = append(checks, checkUpgradeCapacity(cluster.KubernetesCluster)...)
+	if err := c.Display(&displayers.KubernetesPreflightChecks{Checks: checks}); err != nil {
+		return err
+	}
+
+	blockers := 0
+	for _, check := range checks {
+		if check.Result == preflightBlocker {
+			blockers++
+		}
+	}
+	if blockers > 0 {
+		return fmt.Errorf("preflight found %d blockers for the upgrade to %s", blockers, version)
+	}
+
+	notice("Preflight checks passed for the upgrade to %s", version)
+	return nil
+}
+
+// checkRemovedAPIs finds the resources last written with an API version
+// removed by the upgrade, according to their managed fields or the
+// configuration last applied by kubectl.
+func checkRemovedAPIs(ctx context.Context, client *rest.RESTClient, apis []removedKubernetesAPI, version string) ([]displayers.KubernetesPreflightCheck, error) {
+	var checks []displayers.KubernetesPreflightCheck
+	for _, api := range apis {
+		path := "/apis/" + api.groupVersion() + "/" + api.Resource
+		err := listPages(ctx, client, path, func(page []byte) (string, error) {
+			var list metav1.PartialObjectMetadataList
+			if err := json.Unmarshal(page, &list); err != nil {
+				return "", err
+			}
+
+			for _, item := range list.Items {
+				if !usesAPIVersion(item.ObjectMeta, api.groupVersion()) {
+					continue
+				}
+
+				msg := fmt.Sprintf("uses %s, removed in %s", api.groupVersion(), api.RemovedIn)
+				if api.Replacement != "" {
+					msg += fmt.Sprintf("; migrate to %s", api.Replacement)
+				}
+				checks = append(checks, displayers.KubernetesPreflightCheck{
+					Check:    preflightCheckRemovedAPI,
+					Result:   preflightBlocker,
+					Resource: kubernetesObjectName(api.Kind, item.ObjectMeta),
+					Message:  msg,
+				})
+			}
+			return list.Continue, nil
+		})
+		// The cluster no longer serves the API version, so nothing uses it.
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", path, err)
+		}
+	}
+
+	if len(checks) == 0 {
+		target, err := minorVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, displayers.KubernetesPreflightCheck{
+			Check:   preflightCheckRemovedAPI,
+			Result:  preflightOK,
+			Message: fmt.Sprintf("no resources use API versions removed by %d.%d", target.Major, target.Minor),
+		})
+	}
+	return checks, nil
+}
+
+// usesAPIVersion reports whether an object was written with an API version,
+// according to its managed fields or the configuration last applied by
+// kubectl.
+func usesAPIVersion(meta metav1.ObjectMeta, apiVersion string) bool {
+	for _, field := range meta.ManagedFields {
+		if field.APIVersion == apiVersion {
+			return true
+		}
+	}
+
+	if applied, ok := meta.Annotations[lastAppliedConfigAnnotation]; ok {
+		var typeMeta metav1.TypeMeta
+		if json.Unmarshal([]byte(applied), &typeMeta) == nil && typeMeta.APIVersion == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPodDisruptionBudgets finds the PodDisruptionBudgets that allow no
+// disruptions, and so would block the drain of their pods' nodes.
+func checkPodDisruptionBudgets(ctx context.Context, client *rest.RESTClient) ([]displayers.KubernetesPreflightCheck, error) {
+	var checks []displayers.KubernetesPreflightCheck
+	err := listPages(ctx, client, "/apis/policy/v1/poddisruptionbudgets", func(page []byte) (string, error) {
+		var list policyv1.PodDisruptionBudgetList
+		if err := json.Unmarshal(page, &list); err != nil {
+			return "", err
+		}
+
+		for _, pdb := range list.Items {
+			if pdb.Status.ExpectedPods == 0 || pdb.Status.DisruptionsAllowed > 0 {
+				continue
+			}
+			checks = append(checks, displayers.KubernetesPreflightCheck{
+				Check:    preflightCheckPDB,
+				Result:   preflightBlocker,
+				Resource: kubernetesObjectName("PodDisruptionBudget", pdb.ObjectMeta),
+				Message: fmt.Sprintf("allows no disruptions, which blocks node drains: %d of %d pods healthy, %d required",
+					pdb.Status.CurrentHealthy, pdb.Status.ExpectedPods, pdb.Status.DesiredHealthy),
+			})
+		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing PodDisruptionBudgets: %w", err)
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, displayers.KubernetesPreflightCheck{
+			Check:   preflightCheckPDB,
+			Result:  preflightOK,
+			Message: "no PodDisruptionBudgets block node drains",
+		})
+	}
+	return checks, nil
+}
+
+// checkUpgradeCapacity reports the surge upgrade setting of a cluster and
+// the capacity of its node pools, warning about what reduces capacity during
+// the upgrade.
+func checkUpgradeCapacity(cluster *godo.KubernetesCluster) []displayers.KubernetesPreflightCheck {
+	surge := displayers.KubernetesPreflightCheck{
+		Check:   preflightCheckSurgeUpgrade,
+		Result:  preflightOK,
+		Message: "enabled: new nodes are created before the old ones are drained",
+	}
+	if !cluster.SurgeUpgrade {
+		surge.Result = preflightWarning
+		surge.Message = fmt.Sprintf("disabled: nodes are drained and replaced in place, reducing capacity during the upgrade; enable it with `doctl kubernetes cluster update %s --%s`", cluster.Name, doctl.ArgSurgeUpgrade)
+	}
+	checks := []displayers.KubernetesPreflightCheck{surge}
+
+	for _, pool := range cluster.NodePools {
+		running := 0
+		for _, node := range pool.Nodes {
+			if node.Status != nil && node.Status.State == kubernetesNodeStateRunning {
+				running++
+			}
+		}
+
+		check := displayers.KubernetesPreflightCheck{
+			Check:    preflightCheckNodePool,
+			Result:   preflightOK,
+			Resource: pool.Name,
+		}
+		details := []string{fmt.Sprintf("%d of %d %s nodes running", running, len(pool.Nodes), pool.Size)}
+		if pool.AutoScale {
+			details = append(details, fmt.Sprintf("autoscaling between %d and %d nodes", pool.MinNodes, pool.MaxNodes))
+		}
+		switch {
+		case running < len(pool.Nodes):
+			check.Result = preflightWarning
+			details = append(details, "nodes that are not running may not be replaced cleanly")
+		case len(pool.Nodes) == 1 && !cluster.SurgeUpgrade:
+			check.Result = preflightWarning
+			details = append(details, "its pods are unavailable while its only node is replaced")
+		}
+		check.Message = strings.Join(details, "; ")
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// listPages lists the objects at path a page at a time, calling page with
+// each page until it returns an empty continue token.
+func listPages(ctx context.Context, client *rest.RESTClient, path string, page func([]byte) (string, error)) error {
+	token := ""
+	for {
+		req := client.Get().AbsPath(path).Param("limit", "500")
+		if token != "" {
+			req = req.Param("continue", token)
+		}
+		body, err := req.DoRaw(ctx)
+		if err != nil {
+			return err
+		}
+
+		token, err = page(body)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return nil
+		}
+	}
+}
+
+func kubernetesObjectName(kind string, meta metav1.ObjectMeta) string {
+	if meta.Namespace == "" {
+		return kind + " " + meta.Name
+	}
+	return kind + " " + meta.Namespace + "/" + meta.Name
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// preflightTestAPI is a Kubernetes API with a PodDisruptionBudget and two
+// HorizontalPodAutoscalers using API versions removed in 1.25, listed a page
+// at a time, and PodDisruptionBudgets that block drains when blocked is set.
+type preflightTestAPI struct {
+	blocked bool
+}
+
+func (a *preflightTestAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/apis/policy/v1beta1/poddisruptionbudgets":
+		writeTestList(w, "", map[string]any{
+			"metadata": map[string]any{"name": "legacy", "namespace": "default", "managedFields": []map[string]any{
+				{"manager": "kubectl", "operation": "Update", "apiVersion": "policy/v1beta1"},
+			}},
+		}, map[string]any{
+			"metadata": map[string]any{"name": "current", "namespace": "default", "managedFields": []map[string]any{
+				{"manager": "kubectl", "operation": "Update", "apiVersion": "policy/v1"},
+			}},
+		})
+
+	case "/apis/autoscaling/v2beta1/horizontalpodautoscalers":
+		if r.URL.Query().Get("continue") == "" {
+			writeTestList(w, "next", map[string]any{
+				"metadata": map[string]any{"name": "web", "namespace": "default", "annotations": map[string]any{
+					lastAppliedConfigAnnotation: `{"apiVersion":"autoscaling/v2beta1","kind":"HorizontalPodAutoscaler"}`,
+				}},
+			})
+			return
+		}
+		writeTestList(w, "", map[string]any{
+			"metadata": map[string]any{"name": "api", "namespace": "default", "annotations": map[string]any{
+				lastAppliedConfigAnnotation: `{"apiVersion":"autoscaling/v2beta1","kind":"HorizontalPodAutoscaler"}`,
+			}},
+		})
+
+	case "/apis/policy/v1/poddisruptionbudgets":
+		allowed := 1
+		if a.blocked {
+			allowed = 0
+		}
+		writeTestList(w, "", map[string]any{
+			"metadata": map[string]any{"name": "web", "namespace": "default"},
+			"status":   map[string]any{"currentHealthy": 2, "desiredHealthy": 2, "expectedPods": 2, "disruptionsAllowed": allowed},
+		}, map[string]any{
+			"metadata": map[string]any{"name": "idle", "namespace": "default"},
+			"status":   map[string]any{"currentHealthy": 0, "desiredHealthy": 0, "expectedPods": 0, "disruptionsAllowed": 0},
+		})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+	}
+}
+
+func writeTestList(w io.Writer, continueToken string, items ...map[string]any) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"metadata": map[string]any{"continue": continueToken},
+		"items":    items,
+	})
+}
+
+func withPreflightTestCluster(tm *tcMocks, surgeUpgrade bool) {
+	cluster := &do.KubernetesCluster{KubernetesCluster: &godo.KubernetesCluster{
+		ID:           "web-id",
+		Name:         "web",
+		RegionSlug:   "nyc1",
+		VersionSlug:  "1.24.17-do.0",
+		SurgeUpgrade: surgeUpgrade,
+		NodePools: []*godo.KubernetesNodePool{
+			{Name: "workers", Size: "s-2vcpu-4gb", AutoScale: true, MinNodes: 2, MaxNodes: 5, Nodes: []*godo.KubernetesNode{
+				{Status: &godo.KubernetesNodeStatus{State: "running"}},
+				{Status: &godo.KubernetesNodeStatus{State: "running"}},
+			}},
+			{Name: "ingress", Size: "s-1vcpu-2gb", Nodes: []*godo.KubernetesNode{
+				{Status: &godo.KubernetesNodeStatus{State: "running"}},
+			}},
+		},
+	}}
+	tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{*cluster}, nil)
+	tm.kubernetes.EXPECT().Get("web-id").Return(cluster, nil)
+}
+
+func TestKubernetesClusterUpgradePreflight(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		s := fakeKubernetesAPI(t, &preflightTestAPI{blocked: true})
+		withPreflightTestCluster(tm, false)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web")
+		config.Doit.Set(config.NS, doctl.ArgClusterVersionSlug, "1.25.16-do.0")
+		config.Doit.Set(config.NS, doctl.ArgClusterUpgradePreflight, true)
+
+		err := s.RunKubernetesClusterUpgrade(config)
+		assert.EqualError(t, err, "preflight found 4 blockers for the upgrade to 1.25.16-do.0")
+
+		expected := `Check                    Result     Resource                               Message
+removed-api              blocker    HorizontalPodAutoscaler default/web    uses autoscaling/v2beta1, removed in 1.25; migrate to autoscaling/v2
+removed-api              blocker    HorizontalPodAutoscaler default/api    uses autoscaling/v2beta1, removed in 1.25; migrate to autoscaling/v2
+removed-api              blocker    PodDisruptionBudget default/legacy     uses policy/v1beta1, removed in 1.25; migrate to policy/v1
+pod-disruption-budget    blocker    PodDisruptionBudget default/web        allows no disruptions, which blocks node drains: 2 of 2 pods healthy, 2 required
+surge-upgrade            warning                                           disabled: nodes are drained and replaced in place, reducing capacity during the upgrade; enable it with ` + "`doctl kubernetes cluster update web --surge-upgrade`" + `
+node-pool                ok         workers                                2 of 2 s-2vcpu-4gb nodes running; autoscaling between 2 and 5 nodes
+node-pool                warning    ingress                                1 of 1 s-1vcpu-2gb nodes running; its pods are unavailable while its only node is replaced
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesClusterUpgradePreflightPasses(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		s := fakeKubernetesAPI(t, &preflightTestAPI{})
+		withPreflightTestCluster(tm, true)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, "web")
+		config.Doit.Set(config.NS, doctl.ArgClusterVersionSlug, "1.24.17-do.1")
+		config.Doit.Set(config.NS, doctl.ArgClusterUpgradePreflight, true)
+
+		err := s.RunKubernetesClusterUpgrade(config)
+		require.NoError(t, err)
+
+		expected := `Check                    Result    Resource    Message
+removed-api              ok                    no resources use API versions removed by 1.24
+pod-disruption-budget    ok                    no PodDisruptionBudgets block node drains
+surge-upgrade            ok                    enabled: new nodes are created before the old ones are drained
+node-pool                ok        workers     2 of 2 s-2vcpu-4gb nodes running; autoscaling between 2 and 5 nodes
+node-pool                ok        ingress     1 of 1 s-1vcpu-2gb nodes running
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestRemovedKubernetesAPIs(t *testing.T) {
+	apis, err := removedKubernetesAPIs("1.25.16-do.0", "1.27.4-do.0")
+	require.NoError(t, err)
+
+	var removed []string
+	for _, api := range apis {
+		assert.Contains(t, []string{"1.26", "1.27"}, api.RemovedIn)
+		removed = append(removed, api.groupVersion()+"/"+api.Resource)
+	}
+	assert.ElementsMatch(t, []string{
+		"flowcontrol.apiserver.k8s.io/v1beta1/flowschemas",
+		"flowcontrol.apiserver.k8s.io/v1beta1/prioritylevelconfigurations",
+		"autoscaling/v2beta2/horizontalpodautoscalers",
+		"storage.k8s.io/v1beta1/csistoragecapacities",
+	}, removed)
+
+	apis, err = removedKubernetesAPIs("1.31.1-do.0", "1.31.5-do.0")
+	require.NoError(t, err)
+	assert.Empty(t, apis)
+}
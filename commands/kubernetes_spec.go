@@ -0,0 +1,547 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+)
+
+// Kinds of the changes planned by doctl kubernetes cluster apply.
+const (
+	stackKindKubernetesCluster  = "kubernetes_cluster"
+	stackKindKubernetesNodePool = "kubernetes_node_pool"
+)
+
+// clusterRequestFlags are the flags that set fields of a cluster create
+// request, which cannot be combined with a spec file.
+var clusterRequestFlags = []string{
+	doctl.ArgRegionSlug,
+	doctl.ArgClusterVersionSlug,
+	doctl.ArgClusterVPCUUID,
+	doctl.ArgWorkerSubnetUUID,
+	doctl.ArgClusterSubnet,
+	doctl.ArgServiceSubnet,
+	doctl.ArgAutoUpgrade,
+	doctl.ArgSurgeUpgrade,
+	doctl.ArgIsolatedWorkers,
+	doctl.ArgHA,
+	doctl.ArgEnableControlPlaneFirewall,
+	doctl.ArgControlPlaneFirewallAllowedAddresses,
+	doctl.ArgClusterAutoscalerScaleDownUtilizationThreshold,
+	doctl.ArgClusterAutoscalerScaleDownUnneededTime,
+	doctl.ArgClusterAutoscalerExpanders,
+	doctl.ArgEnableRoutingAgent,
+	doctl.ArgEnablePeerToPeerOciRegistryPlugin,
+	doctl.ArgEnableCorednsAutoscaler,
+	doctl.ArgEnableAmdGpuDevicePlugin,
+	doctl.ArgEnableAmdGpuDeviceMetricsExporterPlugin,
+	doctl.ArgEnableNvidiaGpuDevicePlugin,
+	doctl.ArgEnableNvidiaGpuDraDriver,
+	doctl.ArgEnableAmdGpuDraDriver,
+	doctl.ArgEnableRDMASharedDevicePlugin,
+	doctl.ArgKubernetesEnableSSO,
+	doctl.ArgKubernetesRequireSSO,
+	doctl.ArgKubernetesSSOIssuerURL,
+	doctl.ArgKubernetesSSOClientID,
+	doctl.ArgTag,
+	doctl.ArgSizeSlug,
+	doctl.ArgNodePoolCount,
+	doctl.ArgClusterNodePool,
+	doctl.ArgMaintenanceWindow,
+}
+
+// Fields of a cluster spec that cannot change once the cluster exists.
+var (
+	immutableClusterFields  = []string{"region", "version", "vpc_uuid", "worker_subnet_uuid", "cluster_subnet", "service_subnet", "isolated_workers"}
+	immutableNodePoolFields = []string{"size", "gpu_partition_mode"}
+)
+
+// clusterCreateRequest builds a cluster create request from the spec file
+// given with --spec or, without one, from the command's name argument and
+// flags.
+func clusterCreateRequest(c *CmdConfig, defaultNodeSize string, defaultNodeCount int) (*godo.KubernetesClusterCreateRequest, error) {
+	specPath, err := c.Doit.GetString(c.NS, doctl.ArgClusterSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	if specPath == "" {
+		if err := ensureOneArg(c); err != nil {
+			return nil, err
+		}
+		if !c.Doit.IsSet(doctl.ArgRegionSlug) {
+			return nil, fmt.Errorf("--%s is required unless --%s is set", doctl.ArgRegionSlug, doctl.ArgClusterSpec)
+		}
+
+		r := &godo.KubernetesClusterCreateRequest{Name: c.Args[0]}
+		if err := buildClusterCreateRequestFromArgs(c, r, defaultNodeSize, defaultNodeCount); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if len(c.Args) > 1 {
+		return nil, doctl.NewTooManyArgsErr(c.NS)
+	}
+	for _, flag := range clusterRequestFlags {
+		if c.Doit.IsSet(flag) {
+			return nil, fmt.Errorf("--%s cannot be combined with --%s", flag, doctl.ArgClusterSpec)
+		}
+	}
+
+	r, _, err := readClusterSpec(os.Stdin, specPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Args) == 1 {
+		r.Name = c.Args[0]
+	}
+	if r.Name == "" {
+		return nil, doctl.NewMissingArgsErr(c.NS)
+	}
+	if err := resolveSpecVersion(c.Kubernetes(), r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// readClusterSpec reads a cluster create request in YAML or JSON from a
+// file, or from stdin when path is "-". It also returns the spec as JSON.
+func readClusterSpec(stdin io.Reader, path string) (*godo.KubernetesClusterCreateRequest, json.RawMessage, error) {
+	var r godo.KubernetesClusterCreateRequest
+	raw, err := readSpecFile(stdin, path, "Kubernetes cluster", &r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &r, raw, nil
+}
+
+// resolveSpecVersion replaces a missing or "latest" version in a spec with
+// the latest version available.
+func resolveSpecVersion(kube do.KubernetesService, r *godo.KubernetesClusterCreateRequest) error {
+	if r.VersionSlug != "" && r.VersionSlug != defaultKubernetesLatestVersion {
+		return nil
+	}
+
+	version, err := latestKubernetesVersion(kube)
+	if err != nil {
+		return fmt.Errorf("No version in the spec. Unable to lookup the latest version from the API: %v", err)
+	}
+	r.VersionSlug = version
+	return nil
+}
+
+// clusterSpec returns the request that would create a cluster like cluster,
+// without the fields and tags the API sets.
+func clusterSpec(cluster *godo.KubernetesCluster) *godo.KubernetesClusterCreateRequest {
+	r := &godo.KubernetesClusterCreateRequest{
+		Name:                              cluster.Name,
+		RegionSlug:                        cluster.RegionSlug,
+		VersionSlug:                       cluster.VersionSlug,
+		Tags:                              userClusterTags(cluster.ID, cluster.Tags),
+		VPCUUID:                           cluster.VPCUUID,
+		WorkerSubnetUUID:                  cluster.WorkerSubnetUUID,
+		ClusterSubnet:                     cluster.ClusterSubnet,
+		ServiceSubnet:                     cluster.ServiceSubnet,
+		HA:                                boolPtr(cluster.HA),
+		AutoUpgrade:                       cluster.AutoUpgrade,
+		SurgeUpgrade:                      cluster.SurgeUpgrade,
+		ControlPlaneFirewall:              cluster.ControlPlaneFirewall,
+		ClusterAutoscalerConfiguration:    cluster.ClusterAutoscalerConfiguration,
+		RoutingAgent:                      cluster.RoutingAgent,
+		AmdGpuDevicePlugin:                cluster.AmdGpuDevicePlugin,
+		AmdGpuDeviceMetricsExporterPlugin: cluster.AmdGpuDeviceMetricsExporterPlugin,
+		NvidiaGpuDevicePlugin:             cluster.NvidiaGpuDevicePlugin,
+		NvidiaGpuDraDriver:                cluster.NvidiaGpuDraDriver,
+		AmdGpuDraDriver:                   cluster.AmdGpuDraDriver,
+		RdmaSharedDevicePlugin:            cluster.RdmaSharedDevicePlugin,
+		CorednsAutoscaler:                 cluster.CorednsAutoscaler,
+		SSO:                               cluster.SSO,
+		P2pOciRegistryPlugin:              cluster.P2pOciRegistryPlugin,
+		IsolatedWorkers:                   cluster.IsolatedWorkers,
+	}
+
+	if mp := cluster.MaintenancePolicy; mp != nil {
+		// The duration of the maintenance window is set by the API.
+		r.MaintenancePolicy = &godo.KubernetesMaintenancePolicy{StartTime: mp.StartTime, Day: mp.Day}
+	}
+	for _, pool := range cluster.NodePools {
+		r.NodePools = append(r.NodePools, nodePoolSpec(cluster.ID, pool))
+	}
+
+	return r
+}
+
+// nodePoolSpec returns the request that would create a node pool like pool.
+func nodePoolSpec(clusterID string, pool *godo.KubernetesNodePool) *godo.KubernetesNodePoolCreateRequest {
+	return &godo.KubernetesNodePoolCreateRequest{
+		Name:             pool.Name,
+		Size:             pool.Size,
+		Count:            pool.Count,
+		Tags:             userClusterTags(clusterID, pool.Tags),
+		Labels:           pool.Labels,
+		Taints:           pool.Taints,
+		AutoScale:        pool.AutoScale,
+		MinNodes:         pool.MinNodes,
+		MaxNodes:         pool.MaxNodes,
+		GPUPartitionMode: pool.GPUPartitionMode,
+	}
+}
+
+// userClusterTags returns tags without the ones the API adds to every
+// cluster and node pool.
+func userClusterTags(clusterID string, tags []string) []string {
+	var user []string
+	for _, tag := range tags {
+		if tag != "k8s" && tag != "k8s:"+clusterID && tag != "k8s:worker" {
+			user = append(user, tag)
+		}
+	}
+	return user
+}
+
+// RunKubernetesClusterApply creates or updates a cluster and its node pools
+// to match a spec file.
+func (s *KubernetesCommandService) RunKubernetesClusterApply(c *CmdConfig) error {
+	specPath, err := c.Doit.GetString(c.NS, doctl.ArgClusterSpec)
+	if err != nil {
+		return err
+	}
+	dryRun, err := c.Doit.GetBool(c.NS, doctl.ArgDryRun)
+	if err != nil {
+		return err
+	}
+	force, err := c.Doit.GetBool(c.NS, doctl.ArgForce)
+	if err != nil {
+		return err
+	}
+
+	spec, raw, err := readClusterSpec(os.Stdin, specPath)
+	if err != nil {
+		return err
+	}
+	if spec.Name == "" {
+		return errors.New("the Kubernetes cluster spec must set the cluster's name")
+	}
+
+	changes, err := planCluster(c.Kubernetes(), spec, raw)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	item := &displayers.StackChanges{}
+	for _, change := range changes {
+		item.Changes = append(item.Changes, change.StackChange)
+		if change.apply != nil {
+			pending++
+		}
+	}
+
+	if len(changes) == 0 {
+		notice("Kubernetes cluster %s matches the spec", spec.Name)
+		return nil
+	}
+	if err := c.Display(item); err != nil {
+		return err
+	}
+	if dryRun || pending == 0 {
+		return nil
+	}
+
+	if !force && AskForConfirm(fmt.Sprintf("apply %d changes to the Kubernetes cluster %s", pending, spec.Name)) != nil {
+		return errOperationAborted
+	}
+
+	for _, change := range changes {
+		if change.apply == nil {
+			continue
+		}
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("%s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// planCluster returns the changes needed for the cluster named in spec to
+// match it. Only the fields present in raw, the spec as JSON, are compared.
+// The cluster is updated first, then node pools are updated, created and,
+// last, deleted when the spec lists node pools but not them.
+func planCluster(kube do.KubernetesService, spec *godo.KubernetesClusterCreateRequest, raw json.RawMessage) ([]*stackChange, error) {
+	clusters, err := kube.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var cluster *godo.KubernetesCluster
+	for _, cl := range clusters {
+		if cl.Name == spec.Name {
+			cluster = cl.KubernetesCluster
+			break
+		}
+	}
+
+	if cluster == nil {
+		if err := resolveSpecVersion(kube, spec); err != nil {
+			return nil, err
+		}
+		return []*stackChange{{
+			StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindKubernetesCluster, Name: spec.Name},
+			apply: func() error {
+				_, err := kube.Create(spec)
+				return err
+			},
+		}}, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "name")
+	if spec.VersionSlug == defaultKubernetesLatestVersion {
+		delete(fields, "version")
+	}
+	rawPools, hasPools := fields["node_pools"]
+	delete(fields, "node_pools")
+
+	current := clusterSpec(cluster)
+	_, mutable := splitSpecFields(fields, immutableClusterFields)
+	update := new(godo.KubernetesClusterUpdateRequest)
+	if err := mergeSpec(update, current, mutable); err != nil {
+		return nil, err
+	}
+	update.Name = cluster.Name
+
+	unsent, err := unsentSpecFields(mutable, current, update)
+	if err != nil {
+		return nil, err
+	}
+	immutable, mutable := splitSpecFields(fields, append(unsent, immutableClusterFields...))
+
+	var changes []*stackChange
+	drift, err := specDiff(immutable, current)
+	if err != nil {
+		return nil, err
+	}
+	if len(drift) > 0 {
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDrift, Kind: stackKindKubernetesCluster, Name: cluster.Name, Changes: drift},
+		})
+	}
+
+	diff, err := specDiff(mutable, current)
+	if err != nil {
+		return nil, err
+	}
+	if len(diff) > 0 {
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionUpdate, Kind: stackKindKubernetesCluster, Name: cluster.Name, Changes: diff},
+			apply: func() error {
+				_, err := kube.Update(cluster.ID, update)
+				return err
+			},
+		})
+	}
+
+	if !hasPools {
+		return changes, nil
+	}
+
+	var poolFields []map[string]json.RawMessage
+	if err := json.Unmarshal(rawPools, &poolFields); err != nil {
+		return nil, err
+	}
+
+	poolChanges, err := planNodePools(kube, cluster, spec.NodePools, poolFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(changes, poolChanges...), nil
+}
+
+// planNodePools compares the node pools of a spec to those of a cluster,
+// matching them by name.
+func planNodePools(kube do.KubernetesService, cluster *godo.KubernetesCluster, pools []*godo.KubernetesNodePoolCreateRequest, fields []map[string]json.RawMessage) ([]*stackChange, error) {
+	live := map[string]*godo.KubernetesNodePool{}
+	for _, pool := range cluster.NodePools {
+		live[pool.Name] = pool
+	}
+
+	var changes, creates, deletes []*stackChange
+	declared := map[string]bool{}
+	for i, req := range pools {
+		if req.Name == "" {
+			return nil, fmt.Errorf("node pool %d of the spec has no name", i+1)
+		}
+		declared[req.Name] = true
+
+		pool, ok := live[req.Name]
+		if !ok {
+			creates = append(creates, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindKubernetesNodePool, Name: req.Name},
+				apply: func() error {
+					_, err := kube.CreateNodePool(cluster.ID, req)
+					return err
+				},
+			})
+			continue
+		}
+
+		poolFields := fields[i]
+		delete(poolFields, "name")
+		if req.AutoScale {
+			// The autoscaler owns the node count of the pool.
+			delete(poolFields, "count")
+		}
+
+		current := nodePoolSpec(cluster.ID, pool)
+		_, mutable := splitSpecFields(poolFields, immutableNodePoolFields)
+		update := new(godo.KubernetesNodePoolUpdateRequest)
+		if err := mergeSpec(update, current, mutable); err != nil {
+			return nil, err
+		}
+
+		unsent, err := unsentSpecFields(mutable, current, update)
+		if err != nil {
+			return nil, err
+		}
+		immutable, mutable := splitSpecFields(poolFields, append(unsent, immutableNodePoolFields...))
+
+		drift, err := specDiff(immutable, current)
+		if err != nil {
+			return nil, err
+		}
+		if len(drift) > 0 {
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionDrift, Kind: stackKindKubernetesNodePool, Name: pool.Name, Changes: drift},
+			})
+		}
+
+		diff, err := specDiff(mutable, current)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff) == 0 {
+			continue
+		}
+
+		poolID := pool.ID
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionUpdate, Kind: stackKindKubernetesNodePool, Name: pool.Name, Changes: diff},
+			apply: func() error {
+				_, err := kube.UpdateNodePool(cluster.ID, poolID, update)
+				return err
+			},
+		})
+	}
+
+	for _, pool := range cluster.NodePools {
+		if declared[pool.Name] {
+			continue
+		}
+
+		poolID := pool.ID
+		deletes = append(deletes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDelete, Kind: stackKindKubernetesNodePool, Name: pool.Name},
+			apply:       func() error { return kube.DeleteNodePool(cluster.ID, poolID) },
+		})
+	}
+
+	changes = append(changes, creates...)
+	return append(changes, deletes...), nil
+}
+
+// splitSpecFields splits the fields of a spec into the given keys and the
+// rest, both as JSON objects.
+func splitSpecFields(fields map[string]json.RawMessage, keys []string) (json.RawMessage, json.RawMessage) {
+	matched, rest := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	for k, v := range fields {
+		if slices.Contains(keys, k) {
+			matched[k] = v
+		} else {
+			rest[k] = v
+		}
+	}
+
+	// Marshaling a map of raw JSON values cannot fail.
+	m, _ := json.Marshal(matched)
+	r, _ := json.Marshal(rest)
+	return m, r
+}
+
+// mergeSpec fills an update request with the current state of a resource,
+// then with the fields set in a spec. The requests share their JSON field
+// names with the spec.
+func mergeSpec(update, current any, spec json.RawMessage) error {
+	b, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, update); err != nil {
+		return err
+	}
+	return json.Unmarshal(spec, update)
+}
+
+// unsentSpecFields returns the keys of the spec fields that differ from the
+// current state but are missing from the update request. The update requests
+// omit false and empty values, so applying them cannot turn off surge
+// upgrades or clear tags, for example.
+func unsentSpecFields(spec json.RawMessage, current, update any) ([]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(spec, &fields); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(b, &sent); err != nil {
+		return nil, err
+	}
+
+	var unsent []string
+	for k, v := range fields {
+		if _, ok := sent[k]; ok {
+			continue
+		}
+		field, _ := json.Marshal(map[string]json.RawMessage{k: v})
+		diff, err := specDiff(field, current)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff) > 0 {
+			unsent = append(unsent, k)
+		}
+	}
+
+	return unsent, nil
+}
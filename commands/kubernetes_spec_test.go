@@ -0,0 +1,412 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestClusterSpec(t *testing.T, spec string) string {
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(spec), 0644))
+	return path
+}
+
+// testSpecCluster returns a cluster with the default tags the API adds.
+func testSpecCluster() *godo.KubernetesCluster {
+	const id = "8d91899c-0739-4a1a-acc5-deadbeefbb8f"
+	return &godo.KubernetesCluster{
+		ID:           id,
+		Name:         "prod",
+		RegionSlug:   "nyc3",
+		VersionSlug:  "1.31.1-do.0",
+		Tags:         []string{"k8s", "k8s:" + id, "team:web"},
+		VPCUUID:      "0d3176ad-41e0-4021-b831-0c5c45c60959",
+		AutoUpgrade:  true,
+		SurgeUpgrade: true,
+		MaintenancePolicy: &godo.KubernetesMaintenancePolicy{
+			StartTime: "04:00",
+			Duration:  "4h0m0s",
+			Day:       godo.KubernetesMaintenanceDaySunday,
+		},
+		NodePools: []*godo.KubernetesNodePool{
+			{
+				ID:     "pool-web",
+				Name:   "web",
+				Size:   "s-2vcpu-4gb",
+				Count:  3,
+				Tags:   []string{"k8s", "k8s:" + id, "k8s:worker", "web"},
+				Labels: map[string]string{"tier": "web"},
+				Nodes:  []*godo.KubernetesNode{{ID: "node-1", Name: "web-1"}},
+			},
+			{
+				ID:        "pool-batch",
+				Name:      "batch",
+				Size:      "c-4",
+				Count:     2,
+				Tags:      []string{"k8s", "k8s:" + id, "k8s:worker"},
+				AutoScale: true,
+				MinNodes:  1,
+				MaxNodes:  5,
+			},
+			{
+				ID:    "pool-old",
+				Name:  "old",
+				Size:  "s-1vcpu-2gb",
+				Count: 1,
+				Tags:  []string{"k8s", "k8s:" + id, "k8s:worker"},
+			},
+		},
+		Status: &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusRunning},
+	}
+}
+
+func TestKubernetesClusterGetSpecOutput(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		cluster := testSpecCluster()
+		tm.kubernetes.EXPECT().Get(cluster.ID).Return(&do.KubernetesCluster{KubernetesCluster: cluster}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, cluster.ID)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpecOutput, "yaml")
+
+		err := testK8sCmdService().RunKubernetesClusterGet(config)
+		require.NoError(t, err)
+
+		expected := `auto_upgrade: true
+ha: false
+maintenance_policy:
+  day: sunday
+  duration: ""
+  start_time: "04:00"
+name: prod
+node_pools:
+- count: 3
+  labels:
+    tier: web
+  name: web
+  size: s-2vcpu-4gb
+  tags:
+  - web
+- auto_scale: true
+  count: 2
+  max_nodes: 5
+  min_nodes: 1
+  name: batch
+  size: c-4
+- count: 1
+  name: old
+  size: s-1vcpu-2gb
+region: nyc3
+surge_upgrade: true
+tags:
+- team:web
+version: 1.31.1-do.0
+vpc_uuid: 0d3176ad-41e0-4021-b831-0c5c45c60959
+`
+		assert.Equal(t, expected, buf.String())
+
+		spec, _, err := readClusterSpec(nil, writeTestClusterSpec(t, buf.String()))
+		require.NoError(t, err)
+		assert.Equal(t, clusterSpec(cluster), spec)
+	})
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		cluster := testSpecCluster()
+		tm.kubernetes.EXPECT().Get(cluster.ID).Return(&do.KubernetesCluster{KubernetesCluster: cluster}, nil)
+
+		config.Args = append(config.Args, cluster.ID)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpecOutput, "toml")
+
+		err := testK8sCmdService().RunKubernetesClusterGet(config)
+		assert.EqualError(t, err, `invalid spec format "toml", must be one of: json, yaml`)
+	})
+}
+
+func TestKubernetesClusterCreateSpec(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		r := godo.KubernetesClusterCreateRequest{
+			Name:        "staging",
+			RegionSlug:  "sfo3",
+			VersionSlug: "1.31.1-do.0",
+			Tags:        []string{"team:web"},
+			NodePools: []*godo.KubernetesNodePoolCreateRequest{
+				{Name: "web", Size: "s-2vcpu-4gb", Count: 2, Taints: []godo.Taint{{Key: "dedicated", Value: "web", Effect: "NoSchedule"}}},
+			},
+			MaintenancePolicy: &godo.KubernetesMaintenancePolicy{StartTime: "02:00", Day: godo.KubernetesMaintenanceDaySaturday},
+			SurgeUpgrade:      true,
+			ClusterAutoscalerConfiguration: &godo.KubernetesClusterAutoscalerConfiguration{
+				Expanders: []string{"least-waste"},
+			},
+		}
+		tm.kubernetes.EXPECT().GetVersions().Return(do.KubernetesVersions{
+			{KubernetesVersion: &godo.KubernetesVersion{Slug: "1.31.1-do.0", KubernetesVersion: "1.31.1"}},
+		}, nil)
+		tm.kubernetes.EXPECT().Create(&r).Return(&do.KubernetesCluster{KubernetesCluster: &godo.KubernetesCluster{ID: "cluster-id", Name: "staging"}}, nil)
+
+		config.Args = append(config.Args, "staging")
+		config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, `name: ignored
+region: sfo3
+version: latest
+tags: [team:web]
+surge_upgrade: true
+maintenance_policy:
+  day: saturday
+  start_time: "02:00"
+cluster_autoscaler_configuration:
+  expanders: [least-waste]
+node_pools:
+  - name: web
+    size: s-2vcpu-4gb
+    count: 2
+    taints:
+      - key: dedicated
+        value: web
+        effect: NoSchedule
+`))
+
+		err := testK8sCmdService().RunKubernetesClusterCreate("s-1vcpu-2gb", 3)(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestKubernetesClusterCreateSpecErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		args  []string
+		flags map[string]any
+		err   string
+	}{
+		{
+			name:  "combined with a flag",
+			spec:  "name: staging\n",
+			flags: map[string]any{doctl.ArgSizeSlug: "s-1vcpu-2gb"},
+			err:   "--size cannot be combined with --spec",
+		},
+		{
+			name: "unknown field",
+			spec: "name: staging\nnode_pool: []\n",
+			err:  `parsing Kubernetes cluster spec: json: unknown field "node_pool"`,
+		},
+		{
+			name: "no name",
+			spec: "region: nyc3\n",
+			err:  "(test) command is missing required arguments",
+		},
+		{
+			name: "too many arguments",
+			spec: "name: staging\n",
+			args: []string{"a", "b"},
+			err:  "(test) command contains unsupported arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				config.Args = append(config.Args, tt.args...)
+				config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, tt.spec))
+				for k, v := range tt.flags {
+					config.Doit.Set(config.NS, k, v)
+				}
+
+				err := testK8sCmdService().RunKubernetesClusterCreate("s-1vcpu-2gb", 3)(config)
+				assert.EqualError(t, err, tt.err)
+			})
+		})
+	}
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "staging")
+
+		err := testK8sCmdService().RunKubernetesClusterCreate("s-1vcpu-2gb", 3)(config)
+		assert.EqualError(t, err, "--region is required unless --spec is set")
+	})
+}
+
+func TestKubernetesClusterApply(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		cluster := testSpecCluster()
+		tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{{KubernetesCluster: cluster}}, nil)
+
+		tm.kubernetes.EXPECT().Update(cluster.ID, &godo.KubernetesClusterUpdateRequest{
+			Name:              "prod",
+			Tags:              []string{"team:web", "env:prod"},
+			MaintenancePolicy: &godo.KubernetesMaintenancePolicy{StartTime: "02:00", Day: godo.KubernetesMaintenanceDaySaturday},
+			AutoUpgrade:       boolPtr(true),
+			SurgeUpgrade:      true,
+			ClusterAutoscalerConfiguration: &godo.KubernetesClusterAutoscalerConfiguration{
+				Expanders: []string{"least-waste"},
+			},
+			HA: boolPtr(false),
+		}).Return(&do.KubernetesCluster{KubernetesCluster: cluster}, nil)
+		tm.kubernetes.EXPECT().UpdateNodePool(cluster.ID, "pool-web", &godo.KubernetesNodePoolUpdateRequest{
+			Name:   "web",
+			Count:  intPtr(5),
+			Tags:   []string{"web"},
+			Labels: map[string]string{"tier": "web"},
+		}).Return(&do.KubernetesNodePool{}, nil)
+		tm.kubernetes.EXPECT().CreateNodePool(cluster.ID, &godo.KubernetesNodePoolCreateRequest{
+			Name:  "gpu",
+			Size:  "gpu-h100x1-80gb",
+			Count: 1,
+		}).Return(&do.KubernetesNodePool{}, nil)
+		tm.kubernetes.EXPECT().DeleteNodePool(cluster.ID, "pool-old").Return(nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, `name: prod
+region: nyc3
+version: latest
+tags: [team:web, env:prod]
+maintenance_policy:
+  day: saturday
+  start_time: "02:00"
+cluster_autoscaler_configuration:
+  expanders: [least-waste]
+node_pools:
+  - name: web
+    size: s-2vcpu-4gb
+    count: 5
+  - name: batch
+    size: c-8
+    count: 3
+    auto_scale: true
+    min_nodes: 1
+    max_nodes: 5
+  - name: gpu
+    size: gpu-h100x1-80gb
+    count: 1
+`))
+
+		err := testK8sCmdService().RunKubernetesClusterApply(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind                    Name     Changes
+update    kubernetes_cluster      prod     cluster_autoscaler_configuration: (none) -> {"expanders":["least-waste"]}, maintenance_policy: {"day":"sunday","duration":"","start_time":"04:00"} -> {"day":"saturday","start_time":"02:00"}, tags: ["team:web"] -> ["team:web","env:prod"]
+update    kubernetes_node_pool    web      count: 3 -> 5
+drift     kubernetes_node_pool    batch    size: c-4 -> c-8
+create    kubernetes_node_pool    gpu      
+delete    kubernetes_node_pool    old      
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesClusterApplyUnsentFields(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		cluster := testSpecCluster()
+		tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{{KubernetesCluster: cluster}}, nil)
+
+		tm.kubernetes.EXPECT().Update(cluster.ID, &godo.KubernetesClusterUpdateRequest{
+			Name:              "prod",
+			Tags:              []string{},
+			MaintenancePolicy: &godo.KubernetesMaintenancePolicy{StartTime: "02:00", Day: godo.KubernetesMaintenanceDaySunday},
+			AutoUpgrade:       boolPtr(true),
+			HA:                boolPtr(false),
+		}).Return(&do.KubernetesCluster{KubernetesCluster: cluster}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, `name: prod
+region: nyc3
+version: latest
+tags: []
+surge_upgrade: false
+maintenance_policy:
+  day: sunday
+  start_time: "02:00"
+node_pools:
+  - name: web
+    size: s-2vcpu-4gb
+    count: 3
+    tags: []
+  - name: batch
+    size: c-4
+    auto_scale: true
+    min_nodes: 1
+    max_nodes: 5
+  - name: old
+    size: s-1vcpu-2gb
+    count: 1
+`))
+
+		err := testK8sCmdService().RunKubernetesClusterApply(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind                    Name    Changes
+drift     kubernetes_cluster      prod    surge_upgrade: true -> (none), tags: ["team:web"] -> (none)
+update    kubernetes_cluster      prod    maintenance_policy: {"day":"sunday","duration":"","start_time":"04:00"} -> {"day":"sunday","start_time":"02:00"}
+drift     kubernetes_node_pool    web     tags: ["web"] -> (none)
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesClusterApplyCreate(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{}, nil)
+		tm.kubernetes.EXPECT().Create(&godo.KubernetesClusterCreateRequest{
+			Name:        "staging",
+			RegionSlug:  "sfo3",
+			VersionSlug: "1.31.1-do.0",
+			NodePools:   []*godo.KubernetesNodePoolCreateRequest{{Name: "web", Size: "s-2vcpu-4gb", Count: 2}},
+		}).Return(&do.KubernetesCluster{KubernetesCluster: &godo.KubernetesCluster{ID: "cluster-id"}}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgForce, true)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, `name: staging
+region: sfo3
+version: 1.31.1-do.0
+node_pools:
+  - name: web
+    size: s-2vcpu-4gb
+    count: 2
+`))
+
+		err := testK8sCmdService().RunKubernetesClusterApply(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind                  Name       Changes
+create    kubernetes_cluster    staging    
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestKubernetesClusterApplyDryRun(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		cluster := testSpecCluster()
+		tm.kubernetes.EXPECT().List().Return(do.KubernetesClusters{{KubernetesCluster: cluster}}, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgDryRun, true)
+		config.Doit.Set(config.NS, doctl.ArgClusterSpec, writeTestClusterSpec(t, `name: prod
+region: sfo3
+version: 1.32.1-do.0
+auto_upgrade: false
+`))
+
+		err := testK8sCmdService().RunKubernetesClusterApply(config)
+		require.NoError(t, err)
+
+		expected := `Action    Kind                  Name    Changes
+drift     kubernetes_cluster    prod    region: nyc3 -> sfo3, version: 1.31.1-do.0 -> 1.32.1-do.0
+update    kubernetes_cluster    prod    auto_upgrade: true -> (none)
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
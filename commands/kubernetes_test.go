@@ -269,6 +269,7 @@ func TestKubernetesClusterCommand(t *testing.T) {
 		"registry",
 		"delete-selective",
 		"list-associated-resources",
+		"apply",
 	)
 }
 
@@ -284,6 +285,7 @@ func TestKubernetesNodePoolCommand(t *testing.T) {
 		"delete",
 		"delete-node",
 		"replace-node",
+		"rotate",
 	)
 }
 
@@ -603,6 +605,14 @@ func TestRunKubernetesKubeconfigExecCredential(t *testing.T) {
 				require.NoError(t, json.Unmarshal(onDisk, &cached))
 				require.Equal(t, got.Status.Token, cached.Status.Token)
 				require.Equal(t, got.Status.ExpirationTimestamp.UTC(), cached.Status.ExpirationTimestamp.UTC())
+
+				refreshTokenPath := filepath.Join(cacheDir, clusterIDWithCachedTokenCreds+"_sso_refresh.json")
+				info, err := os.Stat(refreshTokenPath)
+				require.NoError(t, err)
+				require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+				refreshToken, err := loadCachedSSORefreshToken(refreshTokenPath)
+				require.NoError(t, err)
+				require.Equal(t, "oidc-refresh-token", refreshToken)
 			},
 		},
 		{
@@ -631,8 +641,8 @@ func TestRunKubernetesKubeconfigExecCredential(t *testing.T) {
 				tt.setArgs(config)
 
 				svc := kubernetesCommandService()
-				svc.ssoLogin = func(ctx context.Context, clientID, issuerURL string, opts ...sso.LocalOIDCLoginOption) (string, time.Time, error) {
-					return "oidc-id-token", expiryNew, nil
+				svc.ssoLogin = func(ctx context.Context, clientID, issuerURL string, opts ...sso.LocalOIDCLoginOption) (*sso.Token, error) {
+					return &sso.Token{IDToken: "oidc-id-token", Expiry: expiryNew, RefreshToken: "oidc-refresh-token"}, nil
 				}
 
 				err := svc.RunKubernetesKubeconfigExecCredential(config)
@@ -651,6 +661,27 @@ func TestRunKubernetesKubeconfigExecCredential(t *testing.T) {
 	}
 }
 
+func TestRunKubernetesKubeconfigExecCredentialInvalidSSOFlow(t *testing.T) {
+	testRoot := t.TempDir()
+	origConfigHomeFn := defaultConfigHome
+	defaultConfigHome = func() string {
+		return filepath.Join(testRoot, "doctl")
+	}
+	t.Cleanup(func() { defaultConfigHome = origConfigHomeFn })
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = []string{"cluster-id"}
+		config.Doit.Set(config.NS, doctl.ArgVersion, "v1beta1")
+		config.Doit.Set(config.NS, doctl.ArgKubernetesSSOIssuerURL, "https://issuer.example")
+		config.Doit.Set(config.NS, doctl.ArgKubernetesSSOClientID, "oidc-client-id")
+		config.Doit.Set(config.NS, doctl.ArgKubernetesSSOLocalServerPort, 8080)
+		config.Doit.Set(config.NS, doctl.ArgKubernetesSSOFlow, "popup")
+
+		err := kubernetesCommandService().RunKubernetesKubeconfigExecCredential(config)
+		assert.EqualError(t, err, `Invalid sso-flow flag: "popup", must be one of: auto, browser, device`)
+	})
+}
+
 func TestKubernetesList(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		tm.kubernetes.EXPECT().List().Return(testClusterList, nil)
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/godo"
+	"sigs.k8s.io/yaml"
+)
+
+// loadBalancerRequestFlags are the flags that set fields of a load balancer
+// request, which cannot be combined with a spec file.
+var loadBalancerRequestFlags = []string{
+	doctl.ArgLoadBalancerName,
+	doctl.ArgRegionSlug,
+	doctl.ArgSizeSlug,
+	doctl.ArgSizeUnit,
+	doctl.ArgLoadBalancerType,
+	doctl.ArgVPCUUID,
+	doctl.ArgSubnetUUID,
+	doctl.ArgLoadBalancerAlgorithm,
+	doctl.ArgRedirectHTTPToHTTPS,
+	doctl.ArgEnableProxyProtocol,
+	doctl.ArgEnableBackendKeepalive,
+	doctl.ArgDisableLetsEncryptDNSRecords,
+	doctl.ArgTagName,
+	doctl.ArgDropletIDs,
+	doctl.ArgStickySessions,
+	doctl.ArgHealthCheck,
+	doctl.ArgForwardingRules,
+	doctl.ArgProjectID,
+	doctl.ArgHTTPIdleTimeoutSeconds,
+	doctl.ArgAllowList,
+	doctl.ArgDenyList,
+	doctl.ArgLoadBalancerDomains,
+	doctl.ArgGlobalLoadBalancerSettings,
+	doctl.ArgGlobalLoadBalancerCDNSettings,
+	doctl.ArgTargetLoadBalancerIDs,
+	doctl.ArgLoadBalancerNetwork,
+	doctl.ArgLoadBalancerNetworkStack,
+	doctl.ArgLoadBalancerTLSCipherPolicy,
+	doctl.ArgLoadBalancerIP,
+}
+
+// loadBalancerRequest builds a load balancer request from the spec file
+// given with --spec or, without one, from the command's flags.
+func loadBalancerRequest(c *CmdConfig) (*godo.LoadBalancerRequest, error) {
+	specPath, err := c.Doit.GetString(c.NS, doctl.ArgLoadBalancerSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	if specPath == "" {
+		r := new(godo.LoadBalancerRequest)
+		if err := buildRequestFromArgs(c, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	for _, flag := range loadBalancerRequestFlags {
+		if c.Doit.IsSet(flag) {
+			return nil, fmt.Errorf("--%s cannot be combined with --%s", flag, doctl.ArgLoadBalancerSpec)
+		}
+	}
+
+	return readLoadBalancerSpec(os.Stdin, specPath)
+}
+
+// readLoadBalancerSpec reads a load balancer request in YAML or JSON from a
+// file, or from stdin when path is "-".
+func readLoadBalancerSpec(stdin io.Reader, path string) (*godo.LoadBalancerRequest, error) {
+	var r godo.LoadBalancerRequest
+	if _, err := readSpecFile(stdin, path, "load balancer", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// readSpecFile decodes a spec in YAML or JSON from a file, or from stdin when
+// path is "-", into v, rejecting unknown fields. It returns the spec as JSON,
+// so callers can tell which fields it sets.
+func readSpecFile(stdin io.Reader, path, kind string, v any) (json.RawMessage, error) {
+	var spec io.Reader
+	if path == "-" && stdin != nil {
+		spec = stdin
+	} else {
+		specFile, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("opening %s spec: %s does not exist", kind, path)
+			}
+			return nil, fmt.Errorf("opening %s spec: %w", kind, err)
+		}
+		defer specFile.Close()
+		spec = specFile
+	}
+
+	byt, err := io.ReadAll(spec)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s spec: %w", kind, err)
+	}
+
+	jsonSpec, err := yaml.YAMLToJSON(byt)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s spec: %w", kind, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(jsonSpec))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return nil, fmt.Errorf("parsing %s spec: %w", kind, err)
+	}
+
+	return jsonSpec, nil
+}
+
+// loadBalancerSpec returns the request that would create a load balancer
+// like lb, without the fields the API sets.
+func loadBalancerSpec(lb *godo.LoadBalancer) *godo.LoadBalancerRequest {
+	r := lb.AsRequest()
+
+	// The algorithm is deprecated and ignored by the API.
+	r.Algorithm = ""
+	r.Tags = append([]string(nil), lb.Tags...)
+	// Droplets are assigned by tag when one is set.
+	if r.Tag != "" {
+		r.DropletIDs = nil
+	}
+	for _, d := range r.Domains {
+		d.Status = ""
+		d.VerificationErrorReasons = nil
+		d.SSLValidationErrorReasons = nil
+	}
+
+	return r
+}
+
+// writeSpec writes a spec as JSON or YAML.
+func writeSpec(out io.Writer, format string, spec any) error {
+	switch format {
+	case "json":
+		e := json.NewEncoder(out)
+		e.SetIndent("", "  ")
+		return e.Encode(spec)
+	case "yaml":
+		yaml, err := yaml.Marshal(spec)
+		if err != nil {
+			return fmt.Errorf("marshaling the spec as yaml: %v", err)
+		}
+		_, err = out.Write(yaml)
+		return err
+	default:
+		return fmt.Errorf("invalid spec format %q, must be one of: json, yaml", format)
+	}
+}
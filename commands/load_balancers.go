@@ -15,6 +15,7 @@ package commands
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -51,13 +52,17 @@ With the load-balancer command, you can list, create, or delete load balancers,
 	}
 
 	forwardingRulesTxt := "A comma-separated list of key-value pairs representing forwarding rules, which define how traffic is routed, e.g.: `entry_protocol:tcp,entry_port:3306,target_protocol:tcp,target_port:3306`."
-	CmdBuilder(cmd, RunLoadBalancerGet, "get <load-balancer-id>", "Retrieve a load balancer", "Use this command to retrieve information about a load balancer instance, including:\n\n"+lbDetail, Writer,
+	cmdLoadBalancerGet := CmdBuilder(cmd, RunLoadBalancerGet, "get <load-balancer-id>", "Retrieve a load balancer", "Use this command to retrieve information about a load balancer instance, including:\n\n"+lbDetail+"\n\nWith `--spec-output`, the command instead prints the load balancer's configuration as a spec that `doctl compute load-balancer create --spec` and `update --spec` accept.", Writer,
 		aliasOpt("g"), displayerType(&displayers.LoadBalancer{}))
+	AddStringFlag(cmdLoadBalancerGet, doctl.ArgLoadBalancerSpecOutput, "", "",
+		"Print the load balancer's configuration as a spec in the given format: `yaml` or `json`")
 
 	cmdLoadBalancerCreate := CmdBuilder(cmd, RunLoadBalancerCreate, "create",
-		"Create a new load balancer", "Use this command to create a new load balancer on your account. Valid forwarding rules are:\n"+forwardingDetail, Writer, aliasOpt("c"))
+		"Create a new load balancer", "Use this command to create a new load balancer on your account. Valid forwarding rules are:\n"+forwardingDetail+"\n\nInstead of flags, the load balancer's full configuration can be given with `--spec` as a YAML or JSON file with the fields of the API's load balancer request, such as the one printed by `doctl compute load-balancer get --spec-output yaml`.", Writer, aliasOpt("c"))
+	AddStringFlag(cmdLoadBalancerCreate, doctl.ArgLoadBalancerSpec, "", "",
+		"Path to a load balancer spec in YAML or JSON format. Set to `-` to read from stdin. Cannot be combined with the other configuration flags.")
 	AddStringFlag(cmdLoadBalancerCreate, doctl.ArgLoadBalancerName, "", "",
-		"The load balancer's name", requiredOpt())
+		"The load balancer's name. Required unless --spec is set")
 	AddStringFlag(cmdLoadBalancerCreate, doctl.ArgRegionSlug, "", "",
 		"The load balancer's region, e.g.: `nyc1`")
 	AddStringFlag(cmdLoadBalancerCreate, doctl.ArgSizeSlug, "", "",
@@ -110,7 +115,11 @@ With the load-balancer command, you can list, create, or delete load balancers,
 		"An optional BYOIP address to assign to the load balancer. Must be an unassigned BYOIP on your account in the same region. Not supported for GLOBAL or INTERNAL load balancers.")
 
 	cmdRecordUpdate := CmdBuilder(cmd, RunLoadBalancerUpdate, "update <load-balancer-id>",
-		"Update a load balancer's configuration", `Use this command to update the configuration of a specified load balancer. Using all applicable flags, the command should contain a full representation of the load balancer including existing attributes, such as the load balancer's name, region, forwarding rules, and Droplet IDs. Any attribute that is not provided is reset to its default value.`, Writer, aliasOpt("u"))
+		"Update a load balancer's configuration", `Use this command to update the configuration of a specified load balancer. Using all applicable flags, the command should contain a full representation of the load balancer including existing attributes, such as the load balancer's name, region, forwarding rules, and Droplet IDs. Any attribute that is not provided is reset to its default value.
+
+With `+"`"+`--spec`+"`"+`, the full configuration is read from a YAML or JSON file instead, such as one printed by `+"`"+`doctl compute load-balancer get --spec-output yaml`+"`"+` and then edited.`, Writer, aliasOpt("u"))
+	AddStringFlag(cmdRecordUpdate, doctl.ArgLoadBalancerSpec, "", "",
+		"Path to a load balancer spec in YAML or JSON format. Set to `-` to read from stdin. Cannot be combined with the other configuration flags.")
 	AddStringFlag(cmdRecordUpdate, doctl.ArgLoadBalancerName, "", "",
 		"The load balancer's name")
 	AddStringFlag(cmdRecordUpdate, doctl.ArgRegionSlug, "", "",
@@ -204,6 +213,14 @@ func RunLoadBalancerGet(c *CmdConfig) error {
 		return err
 	}
 
+	specOutput, err := c.Doit.GetString(c.NS, doctl.ArgLoadBalancerSpecOutput)
+	if err != nil {
+		return err
+	}
+	if specOutput != "" {
+		return writeSpec(c.Out, specOutput, loadBalancerSpec(lb.LoadBalancer))
+	}
+
 	item := &displayers.LoadBalancer{LoadBalancers: do.LoadBalancers{*lb}}
 	return c.Display(item)
 }
@@ -222,10 +239,20 @@ func RunLoadBalancerList(c *CmdConfig) error {
 
 // RunLoadBalancerCreate creates a new load balancer with a given configuration.
 func RunLoadBalancerCreate(c *CmdConfig) error {
-	r := new(godo.LoadBalancerRequest)
-	if err := buildRequestFromArgs(c, r); err != nil {
+	r, err := loadBalancerRequest(c)
+	if err != nil {
 		return err
 	}
+	if r.Name == "" {
+		specPath, err := c.Doit.GetString(c.NS, doctl.ArgLoadBalancerSpec)
+		if err != nil {
+			return err
+		}
+		if specPath != "" {
+			return errors.New("the load balancer spec must set a name")
+		}
+		return fmt.Errorf("--%s is required unless --%s is set", doctl.ArgLoadBalancerName, doctl.ArgLoadBalancerSpec)
+	}
 
 	lbs := c.LoadBalancers()
 	lb, err := lbs.Create(r)
@@ -266,8 +293,8 @@ func RunLoadBalancerUpdate(c *CmdConfig) error {
 	}
 	lbID := c.Args[0]
 
-	r := new(godo.LoadBalancerRequest)
-	if err := buildRequestFromArgs(c, r); err != nil {
+	r, err := loadBalancerRequest(c)
+	if err != nil {
 		return err
 	}
 
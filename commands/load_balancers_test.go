@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/digitalocean/doctl"
@@ -8,6 +11,7 @@ import (
 	"github.com/digitalocean/godo"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -528,6 +532,180 @@ func TestLoadBalancerUpdateEmptyFirewallFlags(t *testing.T) {
 	})
 }
 
+const testLoadBalancerSpec = `name: lb-name
+region: nyc1
+size_unit: 2
+forwarding_rules:
+- entry_protocol: https
+  entry_port: 443
+  target_protocol: http
+  target_port: 80
+  certificate_id: cert-id
+health_check:
+  protocol: http
+  port: 80
+  path: /healthz
+sticky_sessions:
+  type: cookies
+  cookie_name: DO-LB
+  cookie_ttl_seconds: 300
+tag: web
+firewall:
+  allow:
+  - cidr:10.0.0.0/8
+tls_cipher_policy: STRONG
+`
+
+func writeTestLoadBalancerSpec(t *testing.T, spec string) string {
+	path := filepath.Join(t.TempDir(), "lb.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(spec), 0644))
+	return path
+}
+
+func TestLoadBalancerCreateSpec(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		r := godo.LoadBalancerRequest{
+			Name:     "lb-name",
+			Region:   "nyc1",
+			SizeUnit: 2,
+			ForwardingRules: []godo.ForwardingRule{{
+				EntryProtocol:  "https",
+				EntryPort:      443,
+				TargetProtocol: "http",
+				TargetPort:     80,
+				CertificateID:  "cert-id",
+			}},
+			HealthCheck:     &godo.HealthCheck{Protocol: "http", Port: 80, Path: "/healthz"},
+			StickySessions:  &godo.StickySessions{Type: "cookies", CookieName: "DO-LB", CookieTtlSeconds: 300},
+			Tag:             "web",
+			Firewall:        &godo.LBFirewall{Allow: []string{"cidr:10.0.0.0/8"}},
+			TLSCipherPolicy: "STRONG",
+		}
+		tm.loadBalancers.EXPECT().Create(&r).Return(&testLoadBalancer, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpec, writeTestLoadBalancerSpec(t, testLoadBalancerSpec))
+
+		err := RunLoadBalancerCreate(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestLoadBalancerCreateSpecErrors(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpec, writeTestLoadBalancerSpec(t, testLoadBalancerSpec))
+		config.Doit.Set(config.NS, doctl.ArgRegionSlug, "sfo3")
+
+		err := RunLoadBalancerCreate(config)
+		assert.EqualError(t, err, "--region cannot be combined with --spec")
+	})
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpec, writeTestLoadBalancerSpec(t, "name: lb-name\nforwarding_rule: []\n"))
+
+		err := RunLoadBalancerCreate(config)
+		assert.EqualError(t, err, `parsing load balancer spec: json: unknown field "forwarding_rule"`)
+	})
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpec, writeTestLoadBalancerSpec(t, "region: nyc1\n"))
+
+		err := RunLoadBalancerCreate(config)
+		assert.EqualError(t, err, "the load balancer spec must set a name")
+	})
+}
+
+func TestLoadBalancerCreateWithoutName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgRegionSlug, "nyc1")
+
+		err := RunLoadBalancerCreate(config)
+		assert.EqualError(t, err, "--name is required unless --spec is set")
+	})
+}
+
+func TestLoadBalancerUpdateSpec(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		lbID := "cde2c0d6-41e3-479e-ba60-ad971227232c"
+		r := godo.LoadBalancerRequest{Name: "lb-name", Region: "nyc1", SizeSlug: "lb-small"}
+		tm.loadBalancers.EXPECT().Update(lbID, &r).Return(&testLoadBalancer, nil)
+
+		config.Args = append(config.Args, lbID)
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpec, writeTestLoadBalancerSpec(t, `{"name": "lb-name", "region": "nyc1", "size": "lb-small"}`))
+
+		err := RunLoadBalancerUpdate(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestLoadBalancerGetSpecOutput(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		lbID := "cde2c0d6-41e3-479e-ba60-ad971227232c"
+		lb := do.LoadBalancer{LoadBalancer: &godo.LoadBalancer{
+			ID:          lbID,
+			Name:        "lb-name",
+			IP:          "203.0.113.10",
+			Algorithm:   "round_robin",
+			Status:      "active",
+			Region:      &godo.Region{Slug: "nyc1", Name: "New York 1"},
+			SizeUnit:    2,
+			DropletIDs:  []int{1, 2},
+			Tag:         "web",
+			Tags:        []string{"prod"},
+			HealthCheck: &godo.HealthCheck{Protocol: "http", Port: 80, Path: "/healthz"},
+			ForwardingRules: []godo.ForwardingRule{
+				{EntryProtocol: "http", EntryPort: 80, TargetProtocol: "http", TargetPort: 80},
+			},
+			Domains: []*godo.LBDomain{
+				{Name: "example.com", IsManaged: true, Status: "ACTIVE", VerificationErrorReasons: []string{"none"}},
+			},
+		}}
+		tm.loadBalancers.EXPECT().Get(lbID).Return(&lb, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Args = append(config.Args, lbID)
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpecOutput, "yaml")
+
+		err := RunLoadBalancerGet(config)
+		require.NoError(t, err)
+
+		expected := `domains:
+- is_managed: true
+  name: example.com
+forwarding_rules:
+- entry_port: 80
+  entry_protocol: http
+  target_port: 80
+  target_protocol: http
+health_check:
+  path: /healthz
+  port: 80
+  protocol: http
+name: lb-name
+region: nyc1
+size_unit: 2
+tag: web
+tags:
+- prod
+`
+		assert.Equal(t, expected, buf.String())
+
+		spec, err := readLoadBalancerSpec(nil, writeTestLoadBalancerSpec(t, buf.String()))
+		require.NoError(t, err)
+		assert.Equal(t, loadBalancerSpec(lb.LoadBalancer), spec)
+	})
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.loadBalancers.EXPECT().Get("lb-id").Return(&testLoadBalancer, nil)
+
+		config.Args = append(config.Args, "lb-id")
+		config.Doit.Set(config.NS, doctl.ArgLoadBalancerSpecOutput, "toml")
+
+		err := RunLoadBalancerGet(config)
+		assert.EqualError(t, err, `invalid spec format "toml", must be one of: json, yaml`)
+	})
+}
+
 func TestLoadBalancerDelete(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		lbID := "cde2c0d6-41e3-479e-ba60-ad971227232c"
@@ -0,0 +1,246 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// profilesKey is the config key holding the named profiles.
+const profilesKey = "profiles"
+
+// profile is a named set of configuration values bundled in the config file.
+// A profile may select an auth context and set any global or per-command
+// setting, e.g.:
+//
+//	profiles:
+//	  staging:
+//	    context: staging-team
+//	    api-url: https://api.staging.example.com
+//	    output: json
+//	    compute.droplet.create.region: nyc3
+//
+// Profile values take precedence over the rest of the config file, while
+// flags and environment variables take precedence over the profile.
+type profile struct {
+	name     string
+	settings map[string]any
+
+	// previous holds the config file values replaced by the profile so they
+	// are not overwritten when the config file is written.
+	previous map[string]any
+}
+
+// activeProfile is the profile applied while initializing the configuration.
+var activeProfile *profile
+
+// applyProfile merges the named profile into the configuration of v.
+func applyProfile(v *viper.Viper, name string) (*profile, error) {
+	raw := v.Get(profilesKey + "." + name)
+	if raw == nil {
+		return nil, fmt.Errorf("profile %q does not exist", name)
+	}
+
+	m, ok := toStringMap(raw)
+	if !ok {
+		return nil, fmt.Errorf("profile %q must be a map of settings", name)
+	}
+
+	p := &profile{
+		name:     name,
+		settings: flattenSettings("", m),
+		previous: map[string]any{},
+	}
+
+	for key := range p.settings {
+		if v.InConfig(key) {
+			p.previous[key] = v.Get(key)
+		}
+	}
+
+	if err := v.MergeConfigMap(nestSettings(p.settings)); err != nil {
+		return nil, fmt.Errorf("applying profile %q: %v", name, err)
+	}
+
+	return p, nil
+}
+
+// configSettings returns the settings of v to write to the config file,
+// leaving out the values applied from the active profile.
+func configSettings(v *viper.Viper, p *profile) map[string]any {
+	flat := flattenSettings("", v.AllSettings())
+	if !v.InConfig(doctl.ArgProfile) {
+		// selected with the flag or the environment for this command only
+		delete(flat, doctl.ArgProfile)
+	}
+
+	if p == nil {
+		return nestSettings(flat)
+	}
+
+	for key := range p.settings {
+		delete(flat, key)
+		if prev, ok := p.previous[key]; ok {
+			flat[key] = prev
+		}
+	}
+
+	return nestSettings(flat)
+}
+
+// effectiveSettings returns the global settings of v along with every
+// per-command setting coming from the config file or the active profile.
+// Tokens and the profile definitions themselves are left out.
+func effectiveSettings(v *viper.Viper) map[string]any {
+	flat := flattenSettings("", v.AllSettings())
+	for key := range flat {
+		switch {
+		case key == doctl.ArgAccessToken,
+			strings.HasPrefix(key, "auth-contexts."),
+			key == "auth-contexts",
+			strings.HasPrefix(key, profilesKey+"."),
+			strings.HasPrefix(key, "required."):
+			delete(flat, key)
+		case strings.Contains(key, ".") && !v.InConfig(key):
+			// per-command flag defaults
+			delete(flat, key)
+		}
+	}
+
+	return nestSettings(flat)
+}
+
+// flattenSettings flattens nested settings into dot separated keys.
+func flattenSettings(prefix string, m map[string]any) map[string]any {
+	out := map[string]any{}
+	for k, val := range m {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if nested, ok := toStringMap(val); ok && len(nested) > 0 {
+			for nk, nv := range flattenSettings(key, nested) {
+				out[nk] = nv
+			}
+			continue
+		}
+
+		out[key] = val
+	}
+
+	return out
+}
+
+// nestSettings is the reverse of flattenSettings.
+func nestSettings(flat map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, val := range flat {
+		parts := strings.Split(key, ".")
+
+		m := out
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				m[part] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = val
+	}
+
+	return out
+}
+
+func toStringMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		out := make(map[string]any, len(m))
+		for k, val := range m {
+			out[fmt.Sprint(k)] = val
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func profileNames(v *viper.Viper) []string {
+	names := make([]string, 0)
+	for name := range v.GetStringMap(profilesKey) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// authProfile creates the auth profile commands.
+func authProfile() *Command {
+	cmd := &Command{
+		Command: &cobra.Command{
+			Use:   "profile",
+			Short: "Display commands for working with configuration profiles",
+			Long: `Profiles bundle an authentication context with other settings, such as the API URL, the output format, retry settings and per-command defaults. They are defined under the ` + "`" + `profiles` + "`" + ` key of the configuration file:
+
+    profiles:
+      staging:
+        context: staging-team
+        api-url: https://api.staging.example.com
+        output: json
+        http-retry-max: 3
+        compute.droplet.create.region: nyc3
+
+Select a profile with the ` + "`" + `--profile` + "`" + ` flag or the ` + "`" + `DIGITALOCEAN_PROFILE` + "`" + ` environment variable, or set a top-level ` + "`" + `profile` + "`" + ` key in the configuration file to use one by default. Profile values take precedence over the rest of the configuration file, while flags and environment variables take precedence over the profile.`,
+		},
+	}
+
+	cmdProfileShow := cmdBuilderWithInit(cmd, RunAuthProfileShow, "show", "Display the effective configuration", `Displays the effective configuration after merging the selected profile into the configuration file, one setting per line. Use `+"`"+`--output json`+"`"+` or `+"`"+`--output yaml`+"`"+` to display the settings nested as in the configuration file. Access tokens are not displayed.`, Writer, false, displayerType(&displayers.ProfileSettings{}))
+	cmdProfileShow.Example = `The following example displays the configuration used with the ` + "`" + `staging` + "`" + ` profile: doctl auth profile show --profile staging`
+
+	cmdProfileList := cmdBuilderWithInit(cmd, RunAuthProfileList, "list", "List configuration profiles", `Lists the profiles defined in the configuration file.`, Writer, false, aliasOpt("ls"), displayerType(&displayers.Profiles{}))
+	cmdProfileList.Example = `The following example lists the available profiles: doctl auth profile list`
+
+	return cmd
+}
+
+// RunAuthProfileShow displays the effective configuration.
+func RunAuthProfileShow(c *CmdConfig) error {
+	return c.Display(&displayers.ProfileSettings{Settings: effectiveSettings(viper.GetViper())})
+}
+
+// RunAuthProfileList lists the profiles defined in the configuration file.
+func RunAuthProfileList(c *CmdConfig) error {
+	current := ""
+	if activeProfile != nil {
+		current = activeProfile.name
+	}
+
+	var profiles []displayers.Profile
+	for _, name := range profileNames(viper.GetViper()) {
+		profiles = append(profiles, displayers.Profile{Name: name, Current: name == current})
+	}
+
+	return c.Display(&displayers.Profiles{Profiles: profiles})
+}
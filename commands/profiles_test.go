@@ -0,0 +1,190 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProfilesConfig = `
+access-token: default-token
+context: default
+output: text
+api-url: https://api.digitalocean.com
+auth-contexts:
+  production: prod-token
+  staging: staging-token
+compute:
+  droplet:
+    create:
+      region: sfo3
+profiles:
+  production:
+    context: production
+  staging:
+    context: staging
+    api-url: https://api.staging.example.com
+    output: json
+    http-retry-max: 2
+    compute.droplet.create.region: nyc3
+    compute:
+      droplet:
+        create:
+          project-id: staging-project
+`
+
+func newProfilesViper(t *testing.T) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(testProfilesConfig)))
+	return v
+}
+
+func TestApplyProfile(t *testing.T) {
+	v := newProfilesViper(t)
+
+	p, err := applyProfile(v, "staging")
+	require.NoError(t, err)
+
+	assert.Equal(t, "staging", p.name)
+	assert.Equal(t, "staging", v.GetString("context"))
+	assert.Equal(t, "https://api.staging.example.com", v.GetString("api-url"))
+	assert.Equal(t, "json", v.GetString("output"))
+	assert.Equal(t, 2, v.GetInt("http-retry-max"))
+	assert.Equal(t, "nyc3", v.GetString("compute.droplet.create.region"))
+	assert.Equal(t, "staging-project", v.GetString("compute.droplet.create.project-id"))
+	assert.Equal(t, "prod-token", v.GetStringMapString("auth-contexts")["production"])
+}
+
+func TestApplyProfileEnvironmentWins(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_API_URL", "https://api.override.example.com")
+
+	v := newProfilesViper(t)
+	v.SetEnvPrefix("DIGITALOCEAN")
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+
+	_, err := applyProfile(v, "staging")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://api.override.example.com", v.GetString("api-url"))
+	assert.Equal(t, "nyc3", v.GetString("compute.droplet.create.region"))
+}
+
+func TestApplyProfileMissing(t *testing.T) {
+	v := newProfilesViper(t)
+
+	_, err := applyProfile(v, "qa")
+	assert.EqualError(t, err, `profile "qa" does not exist`)
+}
+
+func TestConfigSettingsRestoresProfileValues(t *testing.T) {
+	v := newProfilesViper(t)
+
+	p, err := applyProfile(v, "staging")
+	require.NoError(t, err)
+
+	v.Set("auth-contexts", map[string]any{"staging": "new-token"})
+
+	settings := flattenSettings("", configSettings(v, p))
+	assert.Equal(t, "default", settings["context"])
+	assert.Equal(t, "https://api.digitalocean.com", settings["api-url"])
+	assert.Equal(t, "text", settings["output"])
+	assert.Equal(t, "sfo3", settings["compute.droplet.create.region"])
+	assert.Equal(t, "new-token", settings["auth-contexts.staging"])
+	assert.Equal(t, "staging-project", settings["profiles.staging.compute.droplet.create.project-id"])
+	assert.NotContains(t, settings, "http-retry-max")
+	assert.NotContains(t, settings, "compute.droplet.create.project-id")
+}
+
+func TestEffectiveSettings(t *testing.T) {
+	v := newProfilesViper(t)
+
+	_, err := applyProfile(v, "staging")
+	require.NoError(t, err)
+
+	settings := flattenSettings("", effectiveSettings(v))
+	assert.Equal(t, map[string]any{
+		"context":                           "staging",
+		"api-url":                           "https://api.staging.example.com",
+		"output":                            "json",
+		"http-retry-max":                    2,
+		"compute.droplet.create.region":     "nyc3",
+		"compute.droplet.create.project-id": "staging-project",
+	}, settings)
+}
+
+func TestProfileNames(t *testing.T) {
+	v := newProfilesViper(t)
+
+	assert.Equal(t, []string{"production", "staging"}, profileNames(v))
+}
+
+func TestRunAuthProfileList(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		viper.Set(profilesKey, newProfilesViper(t).Get(profilesKey))
+		defer viper.Set(profilesKey, nil)
+		activeProfile = &profile{name: "staging"}
+		defer func() { activeProfile = nil }()
+
+		var buf bytes.Buffer
+		config.Out = &buf
+
+		err := RunAuthProfileList(config)
+		require.NoError(t, err)
+		assert.Equal(t, "Name          Current\nproduction    false\nstaging       true\n", buf.String())
+
+		buf.Reset()
+		config.Doit.Set(config.NS, doctl.ArgFormat, "Name")
+		config.Doit.Set(config.NS, doctl.ArgNoHeader, true)
+
+		err = RunAuthProfileList(config)
+		require.NoError(t, err)
+		assert.Equal(t, "production\nstaging\n", buf.String())
+	})
+}
+
+func TestProfileSettingsDisplay(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		v := newProfilesViper(t)
+		_, err := applyProfile(v, "staging")
+		require.NoError(t, err)
+		settings := &displayers.ProfileSettings{Settings: effectiveSettings(v)}
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgFormat, "Key")
+
+		require.NoError(t, config.Display(settings))
+		assert.Equal(t, "Key\napi-url\ncompute.droplet.create.project-id\ncompute.droplet.create.region\ncontext\nhttp-retry-max\noutput\n", buf.String())
+
+		buf.Reset()
+		Output = "json"
+		defer func() { Output = "text" }()
+
+		require.NoError(t, config.Display(settings))
+		var nested map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &nested))
+		assert.Equal(t, map[string]any{"region": "nyc3", "project-id": "staging-project"}, nested["compute"].(map[string]any)["droplet"].(map[string]any)["create"])
+	})
+}
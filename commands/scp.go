@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/pkg/ssh"
+)
+
+// SCP creates the scp command.
+func SCP(parent *Command) *Command {
+	usr, err := user.Current()
+	checkErr(err)
+
+	path := filepath.Join(usr.HomeDir, ".ssh", "id_rsa")
+
+	scpDesc := fmt.Sprintf(`Copies files between your machine and a Droplet over SFTP.
+
+One of the source and destination must be a path on a Droplet, written as `+"`"+`<droplet-id|name>:<path>`+"`"+` or `+"`"+`<user>@<droplet-id|name>:<path>`+"`"+`. Paths on the Droplet are relative to the home directory of the user unless they start with `+"`"+`/`+"`"+`. To copy a directory, use the `+"`"+`--%s`+"`"+` flag.
+
+The connection is made the same way as `+"`"+`doctl compute ssh`+"`"+`, and supports the `+"`"+`--%s`+"`"+`, `+"`"+`--%s`+"`"+` and `+"`"+`--%s`+"`"+` flags. The built-in client does not read `+"`"+`~/.ssh/config`+"`"+`; to apply it, run the scp binary instead with the `+"`"+`--%s`+"`"+` flag.
+`, doctl.ArgRecursive, doctl.ArgSSHBastion, doctl.ArgSSHHostKey, doctl.ArgSSHAcceptNew, doctl.ArgSSHBinary)
+
+	cmdSCP := CmdBuilder(parent, RunSCP, "scp <source> <destination>", "Copy files to or from a Droplet", scpDesc, Writer)
+	AddStringFlag(cmdSCP, doctl.ArgSSHUser, "", "", "SSH user for connection, root by default")
+	AddStringFlag(cmdSCP, doctl.ArgsSSHKeyPath, "", path, "Path to SSH private key")
+	AddIntFlag(cmdSCP, doctl.ArgsSSHPort, "", 22, "The remote port sshd is running on")
+	AddBoolFlag(cmdSCP, doctl.ArgsSSHPrivateIP, "", false, "Connect to the Droplet's private IP address")
+	AddIntFlag(cmdSCP, doctl.ArgSSHRetryMax, "", 0, "Max number of retries for a successful SSH connection to a Droplet (default is 0)")
+	AddStringFlag(cmdSCP, doctl.ArgSSHBastion, "", "", "The ID or name of a Droplet to jump through, optionally prefixed with a user as in user@bastion")
+	AddStringFlag(cmdSCP, doctl.ArgSSHHostKey, "", "", "The SHA256 fingerprint of the Droplet's host key to accept, instead of checking the known hosts file")
+	AddBoolFlag(cmdSCP, doctl.ArgSSHAcceptNew, "", false, "Add the keys of hosts missing from the known hosts file without asking for confirmation")
+	AddBoolFlag(cmdSCP, doctl.ArgSSHBinary, "", false, "Run the scp binary instead of the built-in client, to apply the settings of ~/.ssh/config")
+	AddBoolFlag(cmdSCP, doctl.ArgRecursive, "r", false, "Copy directories and their contents")
+	cmdSCP.Example = `The following example copies ` + "`" + `app.tar.gz` + "`" + ` to the ` + "`" + `/srv` + "`" + ` directory of a Droplet named ` + "`" + `web-1` + "`" + `: doctl compute scp app.tar.gz web-1:/srv`
+
+	return cmdSCP
+}
+
+// RunSCP copies files to or from a Droplet.
+func RunSCP(c *CmdConfig) error {
+	if len(c.Args) != 2 {
+		return doctl.NewMissingArgsErr(c.NS)
+	}
+
+	srcHost, srcPath, srcRemote := splitSCPArg(c.Args[0])
+	dstHost, dstPath, dstRemote := splitSCPArg(c.Args[1])
+	if srcRemote == dstRemote {
+		return errors.New("one of the source and destination must be a path on a Droplet, as in <droplet>:<path>")
+	}
+
+	transfer := ssh.Transfer{Source: srcPath, Destination: dstPath, Upload: dstRemote}
+	host := srcHost
+	if dstRemote {
+		host = dstHost
+	}
+
+	var err error
+	transfer.Recursive, err = c.Doit.GetBool(c.NS, doctl.ArgRecursive)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.Doit.GetString(c.NS, doctl.ArgSSHUser)
+	if err != nil {
+		return err
+	}
+
+	keyPath, err := c.Doit.GetString(c.NS, doctl.ArgsSSHKeyPath)
+	if err != nil {
+		return err
+	}
+
+	port, err := c.Doit.GetInt(c.NS, doctl.ArgsSSHPort)
+	if err != nil {
+		return err
+	}
+
+	var opts = make(ssh.Options)
+	opts[doctl.ArgSSHRetryMax], err = c.Doit.GetInt(c.NS, doctl.ArgSSHRetryMax)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHHostKey], err = c.Doit.GetString(c.NS, doctl.ArgSSHHostKey)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHAcceptNew], err = c.Doit.GetBool(c.NS, doctl.ArgSSHAcceptNew)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHBinary], err = c.Doit.GetBool(c.NS, doctl.ArgSSHBinary)
+	if err != nil {
+		return err
+	}
+
+	privateIPChoice, err := c.Doit.GetBool(c.NS, doctl.ArgsSSHPrivateIP)
+	if err != nil {
+		return err
+	}
+
+	bastion, err := sshBastion(c)
+	if err != nil {
+		return err
+	}
+	if bastion != nil {
+		opts[doctl.ArgSSHBastion] = bastion
+		privateIPChoice = true
+	}
+
+	shi := extractHostInfo(host)
+	droplet, err := findDroplet(c.Droplets(), shi.host)
+	if err != nil {
+		return err
+	}
+
+	if shi.user != "" {
+		user = shi.user
+	}
+	if user == "" {
+		user = defaultSSHUser(droplet)
+	}
+
+	ip, err := privateIPElsePub(droplet, privateIPChoice)
+	if err != nil {
+		return err
+	}
+
+	if ip == "" {
+		return errors.New("Could not find Droplet address")
+	}
+
+	return c.Doit.SCP(user, ip, keyPath, port, transfer, opts).Run()
+}
+
+// splitSCPArg splits a [user@]droplet:path argument. Arguments without a
+// colon, or with a slash before it, are local paths.
+func splitSCPArg(arg string) (string, string, bool) {
+	i := strings.Index(arg, ":")
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) || (i == 1 && filepath.VolumeName(arg) != "") {
+		return "", arg, false
+	}
+
+	return arg[:i], arg[i+1:], true
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/pkg/runner"
+	"github.com/digitalocean/doctl/pkg/ssh"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCPCommand(t *testing.T) {
+	parent := &Command{
+		Command: &cobra.Command{
+			Use:   "compute",
+			Short: "compute commands",
+			Long:  "compute commands are for controlling and managing infrastructure",
+		},
+	}
+	cmd := SCP(parent)
+	assert.NotNil(t, cmd)
+	assertCommandNames(t, cmd)
+}
+
+func TestSCP_Upload(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.sshRunner.EXPECT().Run().Return(nil)
+
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SCPFn = func(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner {
+			assert.Equal(t, "root", user)
+			assert.Equal(t, "8.8.8.8", host)
+			assert.Equal(t, ssh.Transfer{Source: "site", Destination: "/srv/www", Upload: true, Recursive: true}, transfer)
+			return tm.sshRunner
+		}
+
+		tm.droplets.EXPECT().List().Return(testDropletList, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgRecursive, true)
+		config.Args = append(config.Args, "site", testDroplet.Name+":/srv/www")
+
+		err := RunSCP(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSCP_Download(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.sshRunner.EXPECT().Run().Return(nil)
+
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SCPFn = func(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner {
+			assert.Equal(t, "sammy", user)
+			assert.Equal(t, ssh.Transfer{Source: "app.log", Destination: "."}, transfer)
+			return tm.sshRunner
+		}
+
+		tm.droplets.EXPECT().Get(testDroplet.ID).Return(&testDroplet, nil)
+
+		config.Args = append(config.Args, "sammy@1:app.log", ".")
+
+		err := RunSCP(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSCP_NoRemotePath(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "a.txt", "./b.txt")
+
+		err := RunSCP(config)
+		assert.EqualError(t, err, "one of the source and destination must be a path on a Droplet, as in <droplet>:<path>")
+	})
+}
+
+func Test_splitSCPArg(t *testing.T) {
+	cases := []struct {
+		arg    string
+		host   string
+		path   string
+		remote bool
+	}{
+		{arg: "file.txt", path: "file.txt"},
+		{arg: "web-1:file.txt", host: "web-1", path: "file.txt", remote: true},
+		{arg: "root@web-1:/etc/hosts", host: "root@web-1", path: "/etc/hosts", remote: true},
+		{arg: "./dir:with:colons", path: "./dir:with:colons"},
+		{arg: ":file.txt", path: ":file.txt"},
+	}
+
+	for _, c := range cases {
+		host, path, remote := splitSCPArg(c.arg)
+		assert.Equal(t, c.host, host, c.arg)
+		assert.Equal(t, c.path, path, c.arg)
+		assert.Equal(t, c.remote, remote, c.arg)
+	}
+}
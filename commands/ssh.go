@@ -14,15 +14,22 @@ limitations under the License.
 package commands
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
 	"github.com/digitalocean/doctl/do"
 	"github.com/digitalocean/doctl/pkg/ssh"
 )
@@ -41,7 +48,17 @@ func SSH(parent *Command) *Command {
 	sshDesc := fmt.Sprintf(`Access a Droplet using SSH by providing its ID or name.
 
 You may specify the user to login with by passing the `+"`"+`--%s`+"`"+` flag. To access the Droplet on a non-default port, use the `+"`"+`--%s`+"`"+` flag. By default, the connection will be made to the Droplet's public IP address. In order access it using its private IP address, use the `+"`"+`--%s`+"`"+` flag.
-`, doctl.ArgSSHUser, doctl.ArgsSSHPort, doctl.ArgsSSHPrivateIP)
+
+To reach a Droplet without a public IP address, jump through another Droplet with the `+"`"+`--%s`+"`"+` flag. The connection to the Droplet is then made to its private IP address.
+
+Ports can be forwarded with the `+"`"+`-L`+"`"+` and `+"`"+`-R`+"`"+` flags, which use the same `+"`"+`[bind_address:]port:host:hostport`+"`"+` format as ssh.
+
+To run a command on several Droplets at once, select them by tag with the `+"`"+`--%s`+"`"+` flag and set the command with `+"`"+`--%s`+"`"+`. Each line of output is prefixed with the name of the Droplet it comes from, and a summary of the exit code of the command on each Droplet is displayed once it has run everywhere. When the summary is not displayed as text, such as with `+"`"+`--output json`+"`"+`, the output of the command is written to stderr instead, so that stdout only holds the summary. The command exits with an error if it failed on any Droplet.
+
+Host keys are checked against `+"`"+`~/.ssh/known_hosts`+"`"+`. The fingerprint of the key of a host connected to for the first time is displayed for you to confirm before the key is added to it. Without a terminal, the connection fails unless the `+"`"+`--%s`+"`"+` flag is set to add new keys without confirmation. To pin the host key instead, pass its SHA256 fingerprint with the `+"`"+`--%s`+"`"+` flag.
+
+The connection is made by a built-in SSH client, which authenticates with the key set with `+"`"+`--%s`+"`"+` and the keys loaded in ssh-agent, and does not read `+"`"+`~/.ssh/config`+"`"+`. To apply your SSH configuration, such as its IdentityFile, ProxyJump, User or Port settings, run the ssh binary instead with the `+"`"+`--%s`+"`"+` flag.
+`, doctl.ArgSSHUser, doctl.ArgsSSHPort, doctl.ArgsSSHPrivateIP, doctl.ArgSSHBastion, doctl.ArgTag, doctl.ArgSSHCommand, doctl.ArgSSHAcceptNew, doctl.ArgSSHHostKey, doctl.ArgsSSHKeyPath, doctl.ArgSSHBinary)
 
 	cmdSSH := CmdBuilder(parent, RunSSH, "ssh <droplet-id|name>", "Access a Droplet using SSH", sshDesc, Writer)
 	AddStringFlag(cmdSSH, doctl.ArgSSHUser, "", "root", "SSH user for connection")
@@ -51,22 +68,20 @@ You may specify the user to login with by passing the `+"`"+`--%s`+"`"+` flag. T
 	AddBoolFlag(cmdSSH, doctl.ArgsSSHPrivateIP, "", false, "SSH to Droplet's private IP address")
 	AddStringFlag(cmdSSH, doctl.ArgSSHCommand, "", "", "Command to execute on Droplet")
 	AddIntFlag(cmdSSH, doctl.ArgSSHRetryMax, "", 0, "Max number of retries for a successful SSH connection to a Droplet (default is 0)")
+	AddStringSliceFlag(cmdSSH, doctl.ArgSSHLocalForward, "L", []string{}, "Forward a local port to a host reachable from the Droplet, in the [bind_address:]port:host:hostport format")
+	AddStringSliceFlag(cmdSSH, doctl.ArgSSHRemoteForward, "R", []string{}, "Forward a port of the Droplet to a host reachable from this machine, in the [bind_address:]port:host:hostport format")
+	AddStringFlag(cmdSSH, doctl.ArgSSHBastion, "", "", "The ID or name of a Droplet to jump through, optionally prefixed with a user as in user@bastion")
+	AddStringFlag(cmdSSH, doctl.ArgSSHHostKey, "", "", "The SHA256 fingerprint of the Droplet's host key to accept, instead of checking the known hosts file")
+	AddBoolFlag(cmdSSH, doctl.ArgSSHAcceptNew, "", false, "Add the keys of hosts missing from the known hosts file without asking for confirmation")
+	AddBoolFlag(cmdSSH, doctl.ArgSSHBinary, "", false, "Run the ssh binary instead of the built-in client, to apply the settings of ~/.ssh/config")
+	AddStringFlag(cmdSSH, doctl.ArgTag, "", "", "Run the command set with --ssh-command on every Droplet with this tag, instead of a single Droplet")
+	AddIntFlag(cmdSSH, doctl.ArgSSHParallel, "", 10, "The number of Droplets to run the command on at the same time when using --tag")
 
 	return cmdSSH
 }
 
 // RunSSH finds a droplet to ssh to given input parameters (name or id).
 func RunSSH(c *CmdConfig) error {
-	if len(c.Args) == 0 {
-		return doctl.NewMissingArgsErr(c.NS)
-	}
-
-	dropletID := c.Args[0]
-
-	if dropletID == "" {
-		return doctl.NewMissingArgsErr(c.NS)
-	}
-
 	user, err := c.Doit.GetString(c.NS, doctl.ArgSSHUser)
 	if err != nil {
 		return err
@@ -98,11 +113,67 @@ func RunSSH(c *CmdConfig) error {
 		return nil
 	}
 
+	opts[doctl.ArgSSHLocalForward], err = sshForwards(c, doctl.ArgSSHLocalForward)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHRemoteForward], err = sshForwards(c, doctl.ArgSSHRemoteForward)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHHostKey], err = c.Doit.GetString(c.NS, doctl.ArgSSHHostKey)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHAcceptNew], err = c.Doit.GetBool(c.NS, doctl.ArgSSHAcceptNew)
+	if err != nil {
+		return err
+	}
+
+	opts[doctl.ArgSSHBinary], err = c.Doit.GetBool(c.NS, doctl.ArgSSHBinary)
+	if err != nil {
+		return err
+	}
+
 	privateIPChoice, err := c.Doit.GetBool(c.NS, doctl.ArgsSSHPrivateIP)
 	if err != nil {
 		return err
 	}
 
+	bastion, err := sshBastion(c)
+	if err != nil {
+		return err
+	}
+	if bastion != nil {
+		opts[doctl.ArgSSHBastion] = bastion
+		privateIPChoice = true
+	}
+
+	tag, err := c.Doit.GetString(c.NS, doctl.ArgTag)
+	if err != nil {
+		return err
+	}
+
+	if tag != "" {
+		if len(c.Args) > 0 {
+			return fmt.Errorf("a Droplet cannot be given along with --%s", doctl.ArgTag)
+		}
+		return runSSHTag(c, tag, user, keyPath, port, privateIPChoice, opts)
+	}
+
+	if len(c.Args) == 0 {
+		return doctl.NewMissingArgsErr(c.NS)
+	}
+
+	dropletID := c.Args[0]
+
+	if dropletID == "" {
+		return doctl.NewMissingArgsErr(c.NS)
+	}
+
 	var droplet *do.Droplet
 
 	ds := c.Droplets()
@@ -201,3 +272,240 @@ func privateIPElsePub(droplet *do.Droplet, choice bool) (string, error) {
 	}
 	return droplet.PublicIPv4()
 }
+
+// findDroplet finds a Droplet by ID or name.
+func findDroplet(ds do.DropletsService, idOrName string) (*do.Droplet, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return ds.Get(id)
+	}
+
+	droplets, err := ds.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range droplets {
+		if d.Name == idOrName {
+			return &d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Could not find Droplet %q", idOrName)
+}
+
+// sshBastion returns the Droplet to jump through, if one is set.
+func sshBastion(c *CmdConfig) (*ssh.Endpoint, error) {
+	bastion, err := c.Doit.GetString(c.NS, doctl.ArgSSHBastion)
+	if err != nil || bastion == "" {
+		return nil, err
+	}
+
+	shi := extractHostInfo(bastion)
+	droplet, err := findDroplet(c.Droplets(), shi.host)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := droplet.PublicIPv4()
+	if err != nil {
+		return nil, err
+	}
+	if ip == "" {
+		return nil, fmt.Errorf("bastion Droplet %s has no public IP address", droplet.Name)
+	}
+
+	e := &ssh.Endpoint{User: shi.user, Host: ip}
+	if e.User == "" {
+		e.User = defaultSSHUser(droplet)
+	}
+	if shi.port != "" {
+		e.Port, _ = strconv.Atoi(shi.port)
+	}
+
+	return e, nil
+}
+
+func sshForwards(c *CmdConfig, flag string) ([]ssh.Forward, error) {
+	specs, err := c.Doit.GetStringSlice(c.NS, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	forwards := make([]ssh.Forward, 0, len(specs))
+	for _, spec := range specs {
+		f, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, f)
+	}
+
+	return forwards, nil
+}
+
+// runSSHTag runs a command on every Droplet with a tag, a few at a time.
+func runSSHTag(c *CmdConfig, tag, user, keyPath string, port int, privateIP bool, opts ssh.Options) error {
+	if opts[doctl.ArgSSHCommand] == "" {
+		return fmt.Errorf("--%s is required with --%s", doctl.ArgSSHCommand, doctl.ArgTag)
+	}
+
+	local, _ := opts[doctl.ArgSSHLocalForward].([]ssh.Forward)
+	remote, _ := opts[doctl.ArgSSHRemoteForward].([]ssh.Forward)
+	if len(local) > 0 || len(remote) > 0 {
+		return fmt.Errorf("ports cannot be forwarded with --%s", doctl.ArgTag)
+	}
+
+	parallel, err := c.Doit.GetInt(c.NS, doctl.ArgSSHParallel)
+	if err != nil {
+		return err
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	droplets, err := c.Droplets().ListByTag(tag)
+	if err != nil {
+		return err
+	}
+	if len(droplets) == 0 {
+		return fmt.Errorf("no Droplets found with tag %q", tag)
+	}
+
+	// Load the keys once for all Droplets, so the passphrase of an encrypted
+	// key is asked for once rather than by every connection at the same time.
+	if useBinary, _ := opts[doctl.ArgSSHBinary].(bool); !useBinary {
+		keys, err := ssh.LoadKeys(keyPath)
+		if err != nil {
+			return err
+		}
+		opts[ssh.OptionKeys] = keys
+	}
+
+	// The output of the command goes to stderr when the summary is not
+	// text, so that stdout holds a single document.
+	hostOut := c.Out
+	if Output != "text" || TemplateFile != "" {
+		hostOut = os.Stderr
+	}
+
+	width := 0
+	for _, d := range droplets {
+		width = max(width, len(d.Name))
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, parallel)
+		results = make([]displayers.SSHResult, len(droplets))
+	)
+	for i, d := range droplets {
+		results[i] = displayers.SSHResult{DropletID: d.ID, Name: d.Name}
+
+		ip, err := privateIPElsePub(&d, privateIP)
+		if err == nil && ip == "" {
+			err = errors.New("Could not find Droplet address")
+		}
+		if err != nil {
+			results[i].ExitCode = ssh.ExitStatus(err)
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Address = ip
+
+		u := user
+		if u == "" {
+			u = defaultSSHUser(&d)
+		}
+
+		prefix := fmt.Sprintf("%-*s | ", width, d.Name)
+		stdout := &prefixWriter{w: hostOut, prefix: prefix, mu: &mu}
+		stderr := &prefixWriter{w: os.Stderr, prefix: prefix, mu: &mu}
+
+		hostOpts := maps.Clone(opts)
+		hostOpts[ssh.OptionStdin] = strings.NewReader("")
+		hostOpts[ssh.OptionStdout] = stdout
+		hostOpts[ssh.OptionStderr] = stderr
+		runner := c.Doit.SSH(u, ip, keyPath, port, hostOpts)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := runner.Run()
+			stdout.Flush()
+			stderr.Flush()
+
+			results[i].ExitCode = ssh.ExitStatus(err)
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	if err := c.Display(&displayers.SSHResults{Results: results}); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d Droplets", failed, len(results))
+	}
+
+	return nil
+}
+
+// prefixWriter writes each line with a prefix. Writers sharing a mutex
+// write whole lines, so output from several Droplets does not interleave
+// within a line.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes the last line when it does not end with a newline.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	err := p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := io.WriteString(p.w, p.prefix); err != nil {
+		return err
+	}
+	_, err := p.w.Write(line)
+	return err
+}
@@ -14,14 +14,21 @@ limitations under the License.
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"testing"
 
 	"github.com/digitalocean/doctl"
+	"github.com/digitalocean/doctl/commands/displayers"
 	"github.com/digitalocean/doctl/pkg/runner"
 	"github.com/digitalocean/doctl/pkg/ssh"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSSHCommand(t *testing.T) {
@@ -137,6 +144,28 @@ func TestSSH_AgentForwarding(t *testing.T) {
 	})
 }
 
+func TestSSH_AcceptNewAndBinary(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.sshRunner.EXPECT().Run().Return(nil)
+
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SSHFn = func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+			assert.Equal(t, true, opts[doctl.ArgSSHAcceptNew])
+			assert.Equal(t, true, opts[doctl.ArgSSHBinary])
+			return tm.sshRunner
+		}
+
+		tm.droplets.EXPECT().List().Return(testDropletList, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgSSHAcceptNew, true)
+		config.Doit.Set(config.NS, doctl.ArgSSHBinary, true)
+		config.Args = append(config.Args, testDroplet.Name)
+
+		err := RunSSH(config)
+		assert.NoError(t, err)
+	})
+}
+
 func TestSSH_CommandExecuting(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		tm.sshRunner.EXPECT().Run().Return(nil)
@@ -156,6 +185,142 @@ func TestSSH_CommandExecuting(t *testing.T) {
 	})
 }
 
+func TestSSH_Bastion(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.sshRunner.EXPECT().Run().Return(nil)
+
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SSHFn = func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+			assert.Equal(t, "172.16.1.2", host)
+			assert.Equal(t, &ssh.Endpoint{User: "sammy", Host: "8.8.8.9"}, opts[doctl.ArgSSHBastion])
+			return tm.sshRunner
+		}
+
+		tm.droplets.EXPECT().List().Return(testDropletList, nil).Times(2)
+
+		config.Doit.Set(config.NS, doctl.ArgSSHBastion, "sammy@another-droplet")
+		config.Args = append(config.Args, testDroplet.Name)
+
+		err := RunSSH(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSSH_PortForwarding(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.sshRunner.EXPECT().Run().Return(nil)
+
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SSHFn = func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+			assert.Equal(t, []ssh.Forward{{BindAddress: "localhost", BindPort: 5432, Host: "10.0.0.5", HostPort: 5432}}, opts[doctl.ArgSSHLocalForward])
+			assert.Equal(t, []ssh.Forward{{BindAddress: "0.0.0.0", BindPort: 8080, Host: "localhost", HostPort: 3000}}, opts[doctl.ArgSSHRemoteForward])
+			return tm.sshRunner
+		}
+
+		tm.droplets.EXPECT().List().Return(testDropletList, nil)
+
+		config.Doit.Set(config.NS, doctl.ArgSSHLocalForward, []string{"5432:10.0.0.5:5432"})
+		config.Doit.Set(config.NS, doctl.ArgSSHRemoteForward, []string{"*:8080:localhost:3000"})
+		config.Args = append(config.Args, testDroplet.Name)
+
+		err := RunSSH(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSSH_InvalidPortForwarding(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgSSHLocalForward, []string{"5432"})
+		config.Args = append(config.Args, testDroplet.Name)
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, `invalid port forward "5432", must be [bind_address:]port:host:hostport`)
+	})
+}
+
+// runnerFunc is a runner.Runner running a function.
+type runnerFunc func() error
+
+func (f runnerFunc) Run() error {
+	return f()
+}
+
+func TestSSH_Tag(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SSHFn = func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+			assert.Equal(t, "uptime", opts[doctl.ArgSSHCommand])
+			return runnerFunc(func() error {
+				stdout := opts[ssh.OptionStdout].(io.Writer)
+				if host == "8.8.8.9" {
+					fmt.Fprint(stdout, "load average: 0.10")
+					return errors.New("connection refused")
+				}
+				fmt.Fprint(stdout, "up 3 days\nload average: 0.01\n")
+				return nil
+			})
+		}
+
+		tm.droplets.EXPECT().ListByTag("web").Return(testDropletList, nil)
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgSSHCommand, "uptime")
+		config.Doit.Set(config.NS, doctl.ArgSSHParallel, 1)
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, "command failed on 1 of 2 Droplets")
+
+		expected := `a-droplet       | up 3 days
+a-droplet       | load average: 0.01
+another-droplet | load average: 0.10
+ID    Name               Address    Exit Code    Error
+1     a-droplet          8.8.8.8    0            
+3     another-droplet    8.8.8.9    255          connection refused
+`
+		assert.Equal(t, expected, buf.String())
+	})
+}
+
+func TestSSH_TagJSON(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tc := config.Doit.(*doctl.TestConfig)
+		tc.SSHFn = func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+			return runnerFunc(func() error {
+				fmt.Fprintln(opts[ssh.OptionStdout].(io.Writer), "up 3 days")
+				return nil
+			})
+		}
+
+		tm.droplets.EXPECT().ListByTag("web").Return(testDropletList, nil)
+
+		Output = "json"
+		defer func() { Output = "text" }()
+
+		var buf bytes.Buffer
+		config.Out = &buf
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+		config.Doit.Set(config.NS, doctl.ArgSSHCommand, "uptime")
+
+		err := RunSSH(config)
+		require.NoError(t, err)
+
+		var results []displayers.SSHResult
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &results))
+		assert.Len(t, results, 2)
+	})
+}
+
+func TestSSH_TagRequiresCommand(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doctl.ArgTag, "web")
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, "--ssh-command is required with --tag")
+	})
+}
+
 func Test_extractHostInfo(t *testing.T) {
 	cases := []struct {
 		s string
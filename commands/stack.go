@@ -0,0 +1,1347 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/doctl/commands/displayers"
+	"github.com/digitalocean/doctl/do"
+	"github.com/digitalocean/godo"
+	"sigs.k8s.io/yaml"
+)
+
+// Kinds of resources managed by doctl apply, in the order they are applied.
+const (
+	stackKindVPC          = "vpc"
+	stackKindVolume       = "volume"
+	stackKindDroplet      = "droplet"
+	stackKindFirewall     = "firewall"
+	stackKindLoadBalancer = "load_balancer"
+	stackKindDomain       = "domain"
+)
+
+var stackKinds = []string{stackKindVPC, stackKindVolume, stackKindDroplet, stackKindFirewall, stackKindLoadBalancer, stackKindDomain}
+
+// Actions of the changes planned by doctl apply.
+const (
+	stackActionCreate = "create"
+	stackActionUpdate = "update"
+	stackActionDelete = "delete"
+	// stackActionDrift reports differences apply cannot reconcile in place,
+	// such as the region of a Droplet.
+	stackActionDrift = "drift"
+)
+
+// stackResource is a document of a stack file.
+//
+//	kind: droplet
+//	name: web-1
+//	spec:
+//	  region: nyc3
+//	  size: s-1vcpu-1gb
+//	  image: ubuntu-24-04-x64
+type stackResource struct {
+	Kind string          `json:"kind"`
+	Name string          `json:"name"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+// dropletSpec is the spec of a droplet stack resource.
+type dropletSpec struct {
+	Region  string   `json:"region"`
+	Size    string   `json:"size"`
+	Image   string   `json:"image"`
+	SSHKeys []string `json:"ssh_keys,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	VPCUUID string   `json:"vpc_uuid,omitempty"`
+	// VPC is the name of the VPC, an alternative to its ID in VPCUUID.
+	VPC        string `json:"vpc,omitempty"`
+	UserData   string `json:"user_data,omitempty"`
+	Backups    bool   `json:"backups,omitempty"`
+	IPv6       bool   `json:"ipv6,omitempty"`
+	Monitoring bool   `json:"monitoring,omitempty"`
+}
+
+// firewallStackSpec is the spec of a firewall stack resource: the fields of
+// the API request creating it, and the names of its Droplets, an alternative
+// to their IDs in droplet_ids.
+type firewallStackSpec struct {
+	godo.FirewallRequest
+	Droplets []string `json:"droplets,omitempty"`
+}
+
+// loadBalancerStackSpec is the spec of a load balancer stack resource: the
+// fields of the API request creating it, and the names of its VPC and
+// Droplets, alternatives to their IDs in vpc_uuid and droplet_ids.
+type loadBalancerStackSpec struct {
+	godo.LoadBalancerRequest
+	VPC      string   `json:"vpc,omitempty"`
+	Droplets []string `json:"droplets,omitempty"`
+}
+
+// vpcSpec is the spec of a VPC stack resource.
+type vpcSpec struct {
+	Region      string `json:"region"`
+	IPRange     string `json:"ip_range,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// volumeSpec is the spec of a volume stack resource.
+type volumeSpec struct {
+	Region          string   `json:"region"`
+	SizeGigaBytes   int64    `json:"size_gigabytes"`
+	FilesystemType  string   `json:"filesystem_type,omitempty"`
+	FilesystemLabel string   `json:"filesystem_label,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+}
+
+// domainSpec is the spec of a domain stack resource.
+type domainSpec struct {
+	IPAddress string             `json:"ip_address,omitempty"`
+	Records   []domainRecordSpec `json:"records,omitempty"`
+}
+
+// domainRecordSpec is a record of a domain stack resource.
+type domainRecordSpec struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Data     string `json:"data"`
+	Priority int    `json:"priority,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	TTL      int    `json:"ttl,omitempty"`
+	Weight   int    `json:"weight,omitempty"`
+	Flags    int    `json:"flags,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+}
+
+var stackDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// readStack reads the resources of a multi-document YAML stack file from a
+// path or, with "-", from stdin.
+func readStack(stdin io.Reader, path string) ([]*stackResource, error) {
+	var r io.Reader
+	if path == "-" && stdin != nil {
+		r = stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("opening stack: %s does not exist", path)
+			}
+			return nil, fmt.Errorf("opening stack: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	byt, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading stack: %w", err)
+	}
+
+	return parseStack(byt)
+}
+
+// parseStack parses the documents of a stack file. A document may also be a
+// list of resources, such as the JSON list written by doctl export.
+func parseStack(byt []byte) ([]*stackResource, error) {
+	var resources []*stackResource
+	seen := map[string]bool{}
+
+	for i, doc := range stackDocumentSeparator.Split(string(byt), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		jsonDoc, err := yaml.YAMLToJSON([]byte(doc))
+		if err != nil {
+			return nil, fmt.Errorf("parsing stack document %d: %w", i+1, err)
+		}
+		if string(jsonDoc) == "null" {
+			// the document only holds comments
+			continue
+		}
+
+		items := []json.RawMessage{jsonDoc}
+		if bytes.HasPrefix(jsonDoc, []byte("[")) {
+			if err := json.Unmarshal(jsonDoc, &items); err != nil {
+				return nil, fmt.Errorf("parsing stack document %d: %w", i+1, err)
+			}
+		}
+
+		for j, item := range items {
+			position := fmt.Sprintf("stack document %d", i+1)
+			if len(items) > 1 || !bytes.Equal(item, jsonDoc) {
+				position += fmt.Sprintf(" item %d", j+1)
+			}
+
+			res, err := parseStackResource(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", position, err)
+			}
+
+			key := res.Kind + "/" + res.Name
+			if seen[key] {
+				return nil, fmt.Errorf("%s: %s %s is declared more than once", position, res.Kind, res.Name)
+			}
+			seen[key] = true
+
+			resources = append(resources, res)
+		}
+	}
+
+	return resources, nil
+}
+
+func parseStackResource(jsonDoc []byte) (*stackResource, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonDoc))
+	dec.DisallowUnknownFields()
+
+	var res stackResource
+	if err := dec.Decode(&res); err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	if !slices.Contains(stackKinds, res.Kind) {
+		return nil, fmt.Errorf("unsupported kind %q, must be one of: %s", res.Kind, strings.Join(stackKinds, ", "))
+	}
+	if res.Name == "" {
+		return nil, errors.New("a name is required")
+	}
+
+	if len(res.Spec) == 0 || string(res.Spec) == "null" {
+		res.Spec = json.RawMessage("{}")
+	}
+
+	return &res, nil
+}
+
+// decodeSpec decodes the spec of a stack resource, rejecting unknown fields.
+func decodeSpec(res *stackResource, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(res.Spec))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%s %s: invalid spec: %w", res.Kind, res.Name, err)
+	}
+
+	return nil
+}
+
+// stackChange is a change planned by doctl apply.
+type stackChange struct {
+	displayers.StackChange
+
+	apply func() error
+}
+
+// stackPlanner compares the resources of a stack against the live state of
+// an account.
+type stackPlanner struct {
+	vpcs          do.VPCsService
+	volumes       do.VolumesService
+	droplets      do.DropletsService
+	tags          do.TagsService
+	firewalls     do.FirewallsService
+	loadBalancers do.LoadBalancersService
+	domains       do.DomainsService
+
+	// tag is added to the volumes, droplets and load balancers created by
+	// apply. It also scopes the resources deleted with prune.
+	tag   string
+	prune bool
+
+	// vpcIDs and dropletIDs map the names of the VPCs and Droplets specs
+	// reference to their IDs. They are listed on first use, and apply adds
+	// the resources it creates.
+	vpcIDs     map[string]string
+	dropletIDs map[string][]int
+}
+
+func (p *stackPlanner) indexVPCs(list do.VPCs) {
+	p.vpcIDs = map[string]string{}
+	for _, v := range list {
+		p.vpcIDs[v.Name] = v.ID
+	}
+}
+
+func (p *stackPlanner) indexDroplets(list do.Droplets) {
+	p.dropletIDs = map[string][]int{}
+	for _, d := range list {
+		p.dropletIDs[d.Name] = append(p.dropletIDs[d.Name], d.ID)
+	}
+}
+
+// vpcID returns the ID of the VPC with a name.
+func (p *stackPlanner) vpcID(name string) (string, error) {
+	if p.vpcIDs == nil {
+		list, err := p.vpcs.List()
+		if err != nil {
+			return "", err
+		}
+		p.indexVPCs(list)
+	}
+
+	id, ok := p.vpcIDs[name]
+	if !ok {
+		return "", fmt.Errorf("vpc %s does not exist", name)
+	}
+	return id, nil
+}
+
+// vpcName returns the name of the VPC with an ID, or the ID if no VPC has it.
+func (p *stackPlanner) vpcName(id string) (string, error) {
+	if p.vpcIDs == nil {
+		list, err := p.vpcs.List()
+		if err != nil {
+			return "", err
+		}
+		p.indexVPCs(list)
+	}
+
+	for name, vpcID := range p.vpcIDs {
+		if vpcID == id {
+			return name, nil
+		}
+	}
+	return id, nil
+}
+
+// dropletIDsByName returns the IDs of the Droplets with names.
+func (p *stackPlanner) dropletIDsByName(names []string) ([]int, error) {
+	if p.dropletIDs == nil {
+		list, err := p.droplets.List()
+		if err != nil {
+			return nil, err
+		}
+		p.indexDroplets(list)
+	}
+
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		switch found := p.dropletIDs[name]; len(found) {
+		case 0:
+			return nil, fmt.Errorf("droplet %s does not exist", name)
+		case 1:
+			ids = append(ids, found[0])
+		default:
+			return nil, fmt.Errorf("droplet %s: more than one Droplet has this name", name)
+		}
+	}
+	return ids, nil
+}
+
+// dropletNames returns the names of the Droplets with IDs, or the IDs of
+// those no Droplet has.
+func (p *stackPlanner) dropletNames(ids []int) ([]string, error) {
+	if p.dropletIDs == nil {
+		list, err := p.droplets.List()
+		if err != nil {
+			return nil, err
+		}
+		p.indexDroplets(list)
+	}
+
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		name := strconv.Itoa(id)
+		for n, found := range p.dropletIDs {
+			if slices.Contains(found, id) {
+				name = n
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func newStackPlanner(c *CmdConfig, tag string, prune bool) *stackPlanner {
+	return &stackPlanner{
+		vpcs:          c.VPCs(),
+		volumes:       c.Volumes(),
+		droplets:      c.Droplets(),
+		tags:          c.Tags(),
+		firewalls:     c.Firewalls(),
+		loadBalancers: c.LoadBalancers(),
+		domains:       c.Domains(),
+		tag:           tag,
+		prune:         prune,
+	}
+}
+
+// plan returns the changes needed for the live state to match resources.
+// Resources are created and updated in the order of stackKinds, and deleted
+// in the reverse order.
+func (p *stackPlanner) plan(resources []*stackResource) ([]*stackChange, error) {
+	byKind := map[string][]*stackResource{}
+	for _, res := range resources {
+		byKind[res.Kind] = append(byKind[res.Kind], res)
+	}
+
+	var changes, deletes []*stackChange
+	for _, kind := range stackKinds {
+		if len(byKind[kind]) == 0 && (!p.prune || p.tag == "" || kind == stackKindDomain || kind == stackKindVPC) {
+			continue
+		}
+
+		var (
+			c, d []*stackChange
+			err  error
+		)
+		switch kind {
+		case stackKindVPC:
+			c, err = p.planVPCs(byKind[kind])
+		case stackKindVolume:
+			c, d, err = p.planVolumes(byKind[kind])
+		case stackKindDroplet:
+			c, d, err = p.planDroplets(byKind[kind])
+		case stackKindFirewall:
+			c, d, err = p.planFirewalls(byKind[kind])
+		case stackKindLoadBalancer:
+			c, d, err = p.planLoadBalancers(byKind[kind])
+		case stackKindDomain:
+			c, err = p.planDomains(byKind[kind])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c...)
+		deletes = append(d, deletes...)
+	}
+
+	return append(changes, deletes...), nil
+}
+
+// planVPCs creates missing VPCs. VPCs are never pruned.
+func (p *stackPlanner) planVPCs(resources []*stackResource) ([]*stackChange, error) {
+	list, err := p.vpcs.List()
+	if err != nil {
+		return nil, err
+	}
+	p.indexVPCs(list)
+
+	live := map[string]do.VPC{}
+	for _, v := range list {
+		live[v.Name] = v
+	}
+
+	var changes []*stackChange
+	for _, res := range resources {
+		var spec vpcSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, err
+		}
+
+		v, ok := live[res.Name]
+		if !ok {
+			req := &godo.VPCCreateRequest{
+				Name:        res.Name,
+				RegionSlug:  spec.Region,
+				IPRange:     spec.IPRange,
+				Description: spec.Description,
+			}
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindVPC, Name: res.Name},
+				apply: func() error {
+					v, err := p.vpcs.Create(req)
+					if err != nil {
+						return err
+					}
+					p.vpcIDs[v.Name] = v.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		var drift []string
+		if spec.Region != "" && spec.Region != v.RegionSlug {
+			drift = append(drift, diffLine("region", v.RegionSlug, spec.Region))
+		}
+		if spec.IPRange != "" && spec.IPRange != v.IPRange {
+			drift = append(drift, diffLine("ip_range", v.IPRange, spec.IPRange))
+		}
+		if len(drift) > 0 {
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionDrift, Kind: stackKindVPC, Name: res.Name, Changes: drift},
+			})
+		}
+
+		if spec.Description != "" && spec.Description != v.Description {
+			id := v.ID
+			description := spec.Description
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{
+					Action:  stackActionUpdate,
+					Kind:    stackKindVPC,
+					Name:    res.Name,
+					Changes: []string{diffLine("description", v.Description, spec.Description)},
+				},
+				apply: func() error {
+					_, err := p.vpcs.PartialUpdate(id, godo.VPCSetDescription(description))
+					return err
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// planVolumes creates missing volumes. Volumes cannot be updated by apply,
+// differences are reported as drift.
+func (p *stackPlanner) planVolumes(resources []*stackResource) ([]*stackChange, []*stackChange, error) {
+	list, err := p.volumes.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// volume names are unique per region
+	live := map[string][]do.Volume{}
+	for _, v := range list {
+		live[v.Name] = append(live[v.Name], v)
+	}
+
+	var changes []*stackChange
+	declared := map[string]bool{}
+	for _, res := range resources {
+		declared[res.Name] = true
+
+		var spec volumeSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, nil, err
+		}
+
+		var (
+			v     do.Volume
+			found bool
+		)
+		for _, l := range live[res.Name] {
+			if spec.Region == "" || (l.Region != nil && l.Region.Slug == spec.Region) {
+				v, found = l, true
+				break
+			}
+		}
+
+		if !found {
+			if p.tag != "" && !slices.Contains(spec.Tags, p.tag) {
+				spec.Tags = append(spec.Tags, p.tag)
+			}
+
+			req := &godo.VolumeCreateRequest{
+				Name:            res.Name,
+				Region:          spec.Region,
+				SizeGigaBytes:   spec.SizeGigaBytes,
+				FilesystemType:  spec.FilesystemType,
+				FilesystemLabel: spec.FilesystemLabel,
+				Description:     spec.Description,
+				Tags:            spec.Tags,
+			}
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindVolume, Name: res.Name},
+				apply: func() error {
+					_, err := p.volumes.CreateVolume(req)
+					return err
+				},
+			})
+			continue
+		}
+
+		var drift []string
+		if spec.SizeGigaBytes != 0 && spec.SizeGigaBytes != v.SizeGigaBytes {
+			drift = append(drift, diffLine("size_gigabytes", v.SizeGigaBytes, spec.SizeGigaBytes))
+		}
+		if spec.FilesystemType != "" && spec.FilesystemType != v.FilesystemType {
+			drift = append(drift, diffLine("filesystem_type", v.FilesystemType, spec.FilesystemType))
+		}
+		if len(drift) > 0 {
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionDrift, Kind: stackKindVolume, Name: res.Name, Changes: drift},
+			})
+		}
+	}
+
+	if !p.prune || p.tag == "" {
+		return changes, nil, nil
+	}
+
+	var deletes []*stackChange
+	for _, v := range list {
+		if declared[v.Name] || !slices.Contains(v.Tags, p.tag) {
+			continue
+		}
+
+		id := v.ID
+		deletes = append(deletes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDelete, Kind: stackKindVolume, Name: v.Name},
+			apply:       func() error { return p.volumes.DeleteVolume(id) },
+		})
+	}
+
+	return changes, deletes, nil
+}
+
+func (p *stackPlanner) planDroplets(resources []*stackResource) ([]*stackChange, []*stackChange, error) {
+	list, err := p.droplets.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.indexDroplets(list)
+
+	live := map[string]do.Droplet{}
+	for _, d := range list {
+		if _, ok := live[d.Name]; ok {
+			// identity by name is ambiguous, only fail if the stack uses it
+			live[d.Name] = do.Droplet{}
+			continue
+		}
+		live[d.Name] = d
+	}
+
+	var changes []*stackChange
+	declared := map[string]bool{}
+	for _, res := range resources {
+		declared[res.Name] = true
+
+		var spec dropletSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, nil, err
+		}
+		if spec.VPC != "" && spec.VPCUUID != "" {
+			return nil, nil, fmt.Errorf("droplet %s: set either vpc or vpc_uuid", res.Name)
+		}
+		// tags missing from the live droplet are only removed when the
+		// spec lists them
+		exactTags := spec.Tags != nil
+		if p.tag != "" && !slices.Contains(spec.Tags, p.tag) {
+			spec.Tags = append(spec.Tags, p.tag)
+		}
+
+		d, ok := live[res.Name]
+		if ok && d.Droplet == nil {
+			return nil, nil, fmt.Errorf("droplet %s: more than one Droplet has this name", res.Name)
+		}
+
+		if !ok {
+			changes = append(changes, p.createDroplet(res.Name, spec))
+			continue
+		}
+
+		diff, err := p.diffDroplet(res.Name, spec, exactTags, d)
+		if err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, diff...)
+	}
+
+	if !p.prune || p.tag == "" {
+		return changes, nil, nil
+	}
+
+	tagged, err := p.droplets.ListByTag(p.tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var deletes []*stackChange
+	for _, d := range tagged {
+		if declared[d.Name] {
+			continue
+		}
+
+		id := d.ID
+		deletes = append(deletes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDelete, Kind: stackKindDroplet, Name: d.Name},
+			apply:       func() error { return p.droplets.Delete(id) },
+		})
+	}
+
+	return changes, deletes, nil
+}
+
+func (p *stackPlanner) createDroplet(name string, spec dropletSpec) *stackChange {
+	image := godo.DropletCreateImage{Slug: spec.Image}
+	if id, err := strconv.Atoi(spec.Image); err == nil {
+		image = godo.DropletCreateImage{ID: id}
+	}
+
+	dcr := &godo.DropletCreateRequest{
+		Name:       name,
+		Region:     spec.Region,
+		Size:       spec.Size,
+		Image:      image,
+		SSHKeys:    extractSSHKeys(spec.SSHKeys),
+		Tags:       spec.Tags,
+		VPCUUID:    spec.VPCUUID,
+		UserData:   spec.UserData,
+		Backups:    spec.Backups,
+		IPv6:       spec.IPv6,
+		Monitoring: spec.Monitoring,
+	}
+
+	return &stackChange{
+		StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindDroplet, Name: name},
+		apply: func() error {
+			if spec.VPC != "" {
+				id, err := p.vpcID(spec.VPC)
+				if err != nil {
+					return fmt.Errorf("droplet %s: %w", name, err)
+				}
+				dcr.VPCUUID = id
+			}
+
+			d, err := p.droplets.Create(dcr, false)
+			if err != nil {
+				return err
+			}
+			p.dropletIDs[d.Name] = append(p.dropletIDs[d.Name], d.ID)
+			return nil
+		},
+	}
+}
+
+// diffDroplet compares a droplet spec to a live droplet. Only tags can be
+// changed in place, other differences are reported as drift.
+func (p *stackPlanner) diffDroplet(name string, spec dropletSpec, exactTags bool, d do.Droplet) ([]*stackChange, error) {
+	var changes []*stackChange
+
+	var drift []string
+	if spec.Region != "" && d.Region != nil && spec.Region != d.Region.Slug {
+		drift = append(drift, diffLine("region", d.Region.Slug, spec.Region))
+	}
+	if spec.Size != "" && spec.Size != d.SizeSlug {
+		drift = append(drift, diffLine("size", d.SizeSlug, spec.Size))
+	}
+	if spec.Image != "" && d.Image != nil && spec.Image != d.Image.Slug && spec.Image != strconv.Itoa(d.Image.ID) {
+		live := d.Image.Slug
+		if live == "" {
+			live = strconv.Itoa(d.Image.ID)
+		}
+		drift = append(drift, diffLine("image", live, spec.Image))
+	}
+	if spec.VPCUUID != "" && spec.VPCUUID != d.VPCUUID {
+		drift = append(drift, diffLine("vpc_uuid", d.VPCUUID, spec.VPCUUID))
+	}
+	if spec.VPC != "" {
+		vpc, err := p.vpcName(d.VPCUUID)
+		if err != nil {
+			return nil, err
+		}
+		if vpc != spec.VPC {
+			drift = append(drift, diffLine("vpc", vpc, spec.VPC))
+		}
+	}
+	if len(drift) > 0 {
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDrift, Kind: stackKindDroplet, Name: name, Changes: drift},
+		})
+	}
+
+	var add, remove []string
+	for _, t := range spec.Tags {
+		if !slices.Contains(d.Tags, t) {
+			add = append(add, t)
+		}
+	}
+	for _, t := range d.Tags {
+		if exactTags && !slices.Contains(spec.Tags, t) {
+			remove = append(remove, t)
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return changes, nil
+	}
+
+	resource := godo.Resource{ID: strconv.Itoa(d.ID), Type: godo.DropletResourceType}
+	changes = append(changes, &stackChange{
+		StackChange: displayers.StackChange{
+			Action:  stackActionUpdate,
+			Kind:    stackKindDroplet,
+			Name:    name,
+			Changes: []string{diffLine("tags", d.Tags, spec.Tags)},
+		},
+		apply: func() error {
+			for _, t := range add {
+				if err := p.tags.TagResources(t, &godo.TagResourcesRequest{Resources: []godo.Resource{resource}}); err != nil {
+					return err
+				}
+			}
+			for _, t := range remove {
+				if err := p.tags.UntagResources(t, &godo.UntagResourcesRequest{Resources: []godo.Resource{resource}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	return changes, nil
+}
+
+func (p *stackPlanner) planFirewalls(resources []*stackResource) ([]*stackChange, []*stackChange, error) {
+	list, err := p.firewalls.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	live := map[string]do.Firewall{}
+	for _, f := range list {
+		if _, ok := live[f.Name]; ok {
+			// identity by name is ambiguous, only fail if the stack uses it
+			live[f.Name] = do.Firewall{}
+			continue
+		}
+		live[f.Name] = f
+	}
+
+	var changes []*stackChange
+	declared := map[string]bool{}
+	for _, res := range resources {
+		declared[res.Name] = true
+
+		var spec firewallStackSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, nil, err
+		}
+		if spec.Droplets != nil && spec.DropletIDs != nil {
+			return nil, nil, fmt.Errorf("firewall %s: set either droplets or droplet_ids", res.Name)
+		}
+		req := spec.FirewallRequest
+		req.Name = res.Name
+
+		f, ok := live[res.Name]
+		if ok && f.Firewall == nil {
+			return nil, nil, fmt.Errorf("firewall %s: more than one firewall has this name", res.Name)
+		}
+		if !ok {
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindFirewall, Name: res.Name},
+				apply: func() error {
+					if spec.Droplets != nil {
+						ids, err := p.dropletIDsByName(spec.Droplets)
+						if err != nil {
+							return fmt.Errorf("firewall %s: %w", req.Name, err)
+						}
+						req.DropletIDs = ids
+					}
+
+					_, err := p.firewalls.Create(&req)
+					return err
+				},
+			})
+			continue
+		}
+
+		current := &firewallStackSpec{FirewallRequest: godo.FirewallRequest{
+			Name:          f.Name,
+			InboundRules:  f.InboundRules,
+			OutboundRules: f.OutboundRules,
+			DropletIDs:    f.DropletIDs,
+			Tags:          f.Tags,
+		}}
+		if spec.Droplets != nil {
+			current.Droplets, err = p.dropletNames(f.DropletIDs)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		diff, err := specDiff(res.Spec, current)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(diff) == 0 {
+			continue
+		}
+
+		// fields left out of the spec keep their current value
+		update := *current
+		if err := json.Unmarshal(res.Spec, &update); err != nil {
+			return nil, nil, err
+		}
+		update.Name = res.Name
+
+		id := f.ID
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionUpdate, Kind: stackKindFirewall, Name: res.Name, Changes: diff},
+			apply: func() error {
+				if spec.Droplets != nil {
+					ids, err := p.dropletIDsByName(spec.Droplets)
+					if err != nil {
+						return fmt.Errorf("firewall %s: %w", update.Name, err)
+					}
+					update.DropletIDs = ids
+				}
+
+				_, err := p.firewalls.Update(id, &update.FirewallRequest)
+				return err
+			},
+		})
+	}
+
+	if !p.prune || p.tag == "" {
+		return changes, nil, nil
+	}
+
+	var deletes []*stackChange
+	for _, f := range list {
+		if declared[f.Name] || !slices.Contains(f.Tags, p.tag) {
+			continue
+		}
+
+		id := f.ID
+		deletes = append(deletes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDelete, Kind: stackKindFirewall, Name: f.Name},
+			apply:       func() error { return p.firewalls.Delete(id) },
+		})
+	}
+
+	return changes, deletes, nil
+}
+
+func (p *stackPlanner) planLoadBalancers(resources []*stackResource) ([]*stackChange, []*stackChange, error) {
+	list, err := p.loadBalancers.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	live := map[string]do.LoadBalancer{}
+	for _, lb := range list {
+		if _, ok := live[lb.Name]; ok {
+			// identity by name is ambiguous, only fail if the stack uses it
+			live[lb.Name] = do.LoadBalancer{}
+			continue
+		}
+		live[lb.Name] = lb
+	}
+
+	var changes []*stackChange
+	declared := map[string]bool{}
+	for _, res := range resources {
+		declared[res.Name] = true
+
+		var spec loadBalancerStackSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, nil, err
+		}
+		if spec.VPC != "" && spec.VPCUUID != "" {
+			return nil, nil, fmt.Errorf("load balancer %s: set either vpc or vpc_uuid", res.Name)
+		}
+		if spec.Droplets != nil && spec.DropletIDs != nil {
+			return nil, nil, fmt.Errorf("load balancer %s: set either droplets or droplet_ids", res.Name)
+		}
+		req := spec.LoadBalancerRequest
+		req.Name = res.Name
+
+		lb, ok := live[res.Name]
+		if ok && lb.LoadBalancer == nil {
+			return nil, nil, fmt.Errorf("load balancer %s: more than one load balancer has this name", res.Name)
+		}
+		if !ok {
+			if p.tag != "" && !slices.Contains(req.Tags, p.tag) {
+				req.Tags = append(req.Tags, p.tag)
+			}
+
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindLoadBalancer, Name: res.Name},
+				apply: func() error {
+					if err := p.resolveLoadBalancerRefs(spec, &req); err != nil {
+						return err
+					}
+
+					_, err := p.loadBalancers.Create(&req)
+					return err
+				},
+			})
+			continue
+		}
+
+		current := &loadBalancerStackSpec{LoadBalancerRequest: *lb.AsRequest()}
+		if spec.VPC != "" {
+			current.VPC, err = p.vpcName(lb.VPCUUID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if spec.Droplets != nil {
+			current.Droplets, err = p.dropletNames(lb.DropletIDs)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		diff, err := specDiff(res.Spec, current)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(diff) == 0 {
+			continue
+		}
+
+		update := *current
+		if err := json.Unmarshal(res.Spec, &update); err != nil {
+			return nil, nil, err
+		}
+		update.Name = res.Name
+
+		id := lb.ID
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionUpdate, Kind: stackKindLoadBalancer, Name: res.Name, Changes: diff},
+			apply: func() error {
+				if err := p.resolveLoadBalancerRefs(spec, &update.LoadBalancerRequest); err != nil {
+					return err
+				}
+
+				_, err := p.loadBalancers.Update(id, &update.LoadBalancerRequest)
+				return err
+			},
+		})
+	}
+
+	if !p.prune || p.tag == "" {
+		return changes, nil, nil
+	}
+
+	var deletes []*stackChange
+	for _, lb := range list {
+		if declared[lb.Name] || !slices.Contains(lb.Tags, p.tag) {
+			continue
+		}
+
+		id := lb.ID
+		deletes = append(deletes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionDelete, Kind: stackKindLoadBalancer, Name: lb.Name},
+			apply:       func() error { return p.loadBalancers.Delete(id) },
+		})
+	}
+
+	return changes, deletes, nil
+}
+
+// resolveLoadBalancerRefs sets the IDs of the VPC and Droplets a load
+// balancer spec references by name.
+func (p *stackPlanner) resolveLoadBalancerRefs(spec loadBalancerStackSpec, req *godo.LoadBalancerRequest) error {
+	if spec.VPC != "" {
+		id, err := p.vpcID(spec.VPC)
+		if err != nil {
+			return fmt.Errorf("load balancer %s: %w", req.Name, err)
+		}
+		req.VPCUUID = id
+	}
+
+	if spec.Droplets != nil {
+		ids, err := p.dropletIDsByName(spec.Droplets)
+		if err != nil {
+			return fmt.Errorf("load balancer %s: %w", req.Name, err)
+		}
+		req.DropletIDs = ids
+	}
+
+	return nil
+}
+
+// planDomains creates missing domains and reconciles the records of every
+// declared domain. Domains themselves are never pruned, but with prune the
+// records missing from a domain spec are deleted.
+func (p *stackPlanner) planDomains(resources []*stackResource) ([]*stackChange, error) {
+	list, err := p.domains.List()
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[string]bool{}
+	for _, d := range list {
+		live[d.Name] = true
+	}
+
+	var changes []*stackChange
+	for _, res := range resources {
+		var spec domainSpec
+		if err := decodeSpec(res, &spec); err != nil {
+			return nil, err
+		}
+
+		name := res.Name
+		var current do.DomainRecords
+		if !live[name] {
+			req := &godo.DomainCreateRequest{Name: name, IPAddress: spec.IPAddress}
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: stackKindDomain, Name: name},
+				apply: func() error {
+					_, err := p.domains.Create(req)
+					return err
+				},
+			})
+		} else {
+			current, err = p.domains.Records(name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		changes = append(changes, p.planRecords(name, spec.Records, current)...)
+	}
+
+	return changes, nil
+}
+
+// planRecords reconciles the records of a domain. Records are identified by
+// their type, name and data.
+func (p *stackPlanner) planRecords(domain string, desired []domainRecordSpec, current do.DomainRecords) []*stackChange {
+	var changes []*stackChange
+
+	matched := map[int]bool{}
+	for _, r := range desired {
+		r.Type = strings.ToUpper(r.Type)
+		name := domain + " " + r.Type + " " + recordName(r.Name)
+
+		idx := slices.IndexFunc(current, func(l do.DomainRecord) bool {
+			return !matched[l.ID] && recordKey(l.Type, l.Name, l.Data) == recordKey(r.Type, r.Name, r.Data)
+		})
+
+		req := &do.DomainRecordEditRequest{
+			Type:     r.Type,
+			Name:     r.Name,
+			Data:     r.Data,
+			Priority: r.Priority,
+			TTL:      r.TTL,
+			Weight:   r.Weight,
+			Flags:    r.Flags,
+			Tag:      r.Tag,
+		}
+		if r.Port != 0 {
+			port := r.Port
+			req.Port = &port
+		}
+
+		if idx < 0 {
+			changes = append(changes, &stackChange{
+				StackChange: displayers.StackChange{Action: stackActionCreate, Kind: "domain_record", Name: name, Changes: []string{"data: " + r.Data}},
+				apply: func() error {
+					_, err := p.domains.CreateRecord(domain, req)
+					return err
+				},
+			})
+			continue
+		}
+
+		l := current[idx]
+		matched[l.ID] = true
+
+		var diff []string
+		if r.TTL != 0 && r.TTL != l.TTL {
+			diff = append(diff, diffLine("ttl", l.TTL, r.TTL))
+		}
+		if r.Priority != l.Priority {
+			diff = append(diff, diffLine("priority", l.Priority, r.Priority))
+		}
+		if r.Port != l.Port {
+			diff = append(diff, diffLine("port", l.Port, r.Port))
+		}
+		if r.Weight != l.Weight {
+			diff = append(diff, diffLine("weight", l.Weight, r.Weight))
+		}
+		if r.Flags != l.Flags {
+			diff = append(diff, diffLine("flags", l.Flags, r.Flags))
+		}
+		if r.Tag != l.Tag {
+			diff = append(diff, diffLine("tag", l.Tag, r.Tag))
+		}
+		if len(diff) == 0 {
+			continue
+		}
+
+		id := l.ID
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{Action: stackActionUpdate, Kind: "domain_record", Name: name, Changes: diff},
+			apply: func() error {
+				_, err := p.domains.EditRecord(domain, id, req)
+				return err
+			},
+		})
+	}
+
+	if !p.prune {
+		return changes
+	}
+
+	for _, l := range current {
+		// the SOA and apex NS records are managed by DigitalOcean
+		if matched[l.ID] || l.Type == "SOA" || (l.Type == "NS" && l.Name == "@") {
+			continue
+		}
+
+		id := l.ID
+		changes = append(changes, &stackChange{
+			StackChange: displayers.StackChange{
+				Action:  stackActionDelete,
+				Kind:    "domain_record",
+				Name:    domain + " " + l.Type + " " + recordName(l.Name),
+				Changes: []string{"data: " + l.Data},
+			},
+			apply: func() error { return p.domains.DeleteRecord(domain, id) },
+		})
+	}
+
+	return changes
+}
+
+func recordName(name string) string {
+	if name == "" {
+		return "@"
+	}
+	return name
+}
+
+// recordKey identifies a record. Names are case-insensitive, and so is the
+// data of the record types holding a hostname, which may be fully qualified.
+func recordKey(typ, name, data string) string {
+	typ = strings.ToUpper(typ)
+	switch typ {
+	case "CNAME", "MX", "NS", "SRV":
+		data = strings.TrimSuffix(strings.ToLower(data), ".")
+	}
+	return typ + " " + strings.ToLower(recordName(name)) + " " + data
+}
+
+// specDiff compares the fields set in a spec to the current state of a
+// resource and describes those that differ.
+func specDiff(spec json.RawMessage, current any) ([]string, error) {
+	var desired map[string]any
+	if err := json.Unmarshal(spec, &desired); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var live map[string]any
+	if err := json.Unmarshal(b, &live); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var diff []string
+	for _, k := range keys {
+		if !specMatches(k, desired[k], live[k]) {
+			diff = append(diff, diffLine(k, live[k], desired[k]))
+		}
+	}
+
+	return diff, nil
+}
+
+// specMatches reports whether the live value holds every field set in the
+// desired value. Lists match when each desired item matches a distinct live
+// item, regardless of their order.
+func specMatches(key string, desired, live any) bool {
+	if isZeroValue(desired) && isZeroValue(live) {
+		return true
+	}
+
+	switch d := desired.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range d {
+			if !specMatches(k, v, l[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(l) != len(d) {
+			return false
+		}
+		used := make([]bool, len(l))
+		for _, dv := range d {
+			found := false
+			for i, lv := range l {
+				if !used[i] && specMatches(key, dv, lv) {
+					used[i] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case string:
+		if key == "ports" && (d == "all" || d == "0") {
+			// firewall rules covering every port are returned as "0"
+			return live == "all" || live == "0"
+		}
+	}
+
+	return reflect.DeepEqual(desired, live)
+}
+
+func isZeroValue(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func diffLine(key string, from, to any) string {
+	return fmt.Sprintf("%s: %s -> %s", key, compactValue(from), compactValue(to))
+}
+
+func compactValue(v any) string {
+	if isZeroValue(v) {
+		return "(none)"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
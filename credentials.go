@@ -0,0 +1,377 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+)
+
+const (
+	// CredentialHelperSecretService stores tokens with the Secret Service
+	// D-Bus API through libsecret.
+	CredentialHelperSecretService = "secret-service"
+	// CredentialHelperFile stores tokens in a file encrypted with a
+	// passphrase.
+	CredentialHelperFile = "file"
+
+	// credentialHelperPrefix is prepended to the name of external helpers
+	// looked up in the PATH, e.g. doctl-credential-vault.
+	credentialHelperPrefix = "doctl-credential-"
+)
+
+// CredentialStore stores the access tokens of auth contexts outside of the
+// config file.
+type CredentialStore interface {
+	// Get returns the token of a context, or an empty string if none is
+	// stored.
+	Get(context string) (string, error)
+	Store(context, token string) error
+	Erase(context string) error
+}
+
+// CredentialStoreConfig configures the store built by NewCredentialStore.
+type CredentialStoreConfig struct {
+	// Helper is either one of the built-in helpers, the name of an
+	// external helper executable or its path, optionally followed by
+	// arguments. It is split into words like a shell does, so paths with
+	// spaces can be quoted.
+	Helper string
+	// File is the path of the encrypted credentials file.
+	File string
+	// Passphrase returns the passphrase of the encrypted credentials file.
+	Passphrase func() (string, error)
+}
+
+// NewCredentialStore builds the CredentialStore for a helper.
+func NewCredentialStore(cfg CredentialStoreConfig) (CredentialStore, error) {
+	switch cfg.Helper {
+	case "":
+		return nil, errors.New("no credential helper configured")
+	case CredentialHelperSecretService:
+		return &secretServiceStore{command: "secret-tool"}, nil
+	case CredentialHelperFile:
+		if cfg.File == "" {
+			return nil, errors.New("no credentials file configured")
+		}
+		return &encryptedFileStore{path: cfg.File, passphrase: cfg.Passphrase}, nil
+	}
+
+	args, err := shellquote.Split(cfg.Helper)
+	if err != nil {
+		return nil, fmt.Errorf("parsing credential helper %q: %w", cfg.Helper, err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no credential helper configured")
+	}
+	if !strings.ContainsRune(args[0], filepath.Separator) && !strings.ContainsRune(args[0], '/') {
+		args[0] = credentialHelperPrefix + args[0]
+	}
+
+	return &helperStore{path: args[0], args: args[1:]}, nil
+}
+
+// helperStore runs an external credential helper. Like git credential
+// helpers, the helper is invoked with an action, get, store or erase, and
+// reads key=value lines from its standard input:
+//
+//	context=production
+//	token=dop_v1_...
+//
+// The token line is only sent with store. On get, the helper prints a
+// token=<token> line, or nothing when no token is stored for the context.
+type helperStore struct {
+	path string
+	args []string
+}
+
+func (h *helperStore) Get(context string) (string, error) {
+	out, err := h.run("get", map[string]string{"context": context})
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if token, ok := strings.CutPrefix(scanner.Text(), "token="); ok {
+			return token, nil
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+func (h *helperStore) Store(context, token string) error {
+	_, err := h.run("store", map[string]string{"context": context, "token": token})
+	return err
+}
+
+func (h *helperStore) Erase(context string) error {
+	_, err := h.run("erase", map[string]string{"context": context})
+	return err
+}
+
+func (h *helperStore) run(action string, attrs map[string]string) ([]byte, error) {
+	var in bytes.Buffer
+	for _, key := range []string{"context", "token"} {
+		if v, ok := attrs[key]; ok {
+			fmt.Fprintf(&in, "%s=%s\n", key, v)
+		}
+	}
+	in.WriteString("\n")
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(h.path, append(h.args, action)...)
+	cmd.Stdin = &in
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("credential helper %s %s: %v", h.path, action, err)
+		}
+		return nil, fmt.Errorf("credential helper %s %s: %v: %s", h.path, action, err, msg)
+	}
+
+	return out, nil
+}
+
+// secretServiceStore stores tokens with the Secret Service D-Bus API using
+// the secret-tool command shipped with libsecret.
+type secretServiceStore struct {
+	command string
+}
+
+func (s *secretServiceStore) Get(context string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.command, "lookup", "service", "doctl", "context", context)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			// secret-tool exits with 1 when no secret matches
+			return "", nil
+		}
+		return "", s.error("lookup", err, stderr.String())
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (s *secretServiceStore) Store(context, token string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.command, "store", "--label", "doctl: "+context, "service", "doctl", "context", context)
+	cmd.Stdin = strings.NewReader(token)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return s.error("store", err, stderr.String())
+	}
+
+	return nil
+}
+
+func (s *secretServiceStore) Erase(context string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.command, "clear", "service", "doctl", "context", context)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return s.error("clear", err, stderr.String())
+	}
+
+	return nil
+}
+
+func (s *secretServiceStore) error(action string, err error, stderr string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("secret service: %s was not found, install libsecret tools to use the %s credential helper", s.command, CredentialHelperSecretService)
+	}
+
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("secret service: %s: %v: %s", action, err, msg)
+	}
+	return fmt.Errorf("secret service: %s: %v", action, err)
+}
+
+const (
+	credentialsKDFIterations = 600000
+	credentialsKeyLength     = 32
+)
+
+// encryptedCredentials is the content of the encrypted credentials file.
+// Tokens are sealed with AES-256-GCM using a key derived from the passphrase
+// with PBKDF2-SHA256.
+type encryptedCredentials struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptedFileStore stores tokens in a file encrypted with a passphrase.
+type encryptedFileStore struct {
+	path       string
+	passphrase func() (string, error)
+
+	once sync.Once
+	pass string
+	err  error
+}
+
+func (f *encryptedFileStore) Get(context string) (string, error) {
+	tokens, err := f.load()
+	if err != nil {
+		return "", err
+	}
+
+	return tokens[context], nil
+}
+
+func (f *encryptedFileStore) Store(context, token string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	tokens[context] = token
+	return f.save(tokens)
+}
+
+func (f *encryptedFileStore) Erase(context string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	delete(tokens, context)
+	return f.save(tokens)
+}
+
+func (f *encryptedFileStore) getPassphrase() (string, error) {
+	f.once.Do(func() {
+		if f.passphrase == nil {
+			f.err = errors.New("no passphrase available for the credentials file")
+			return
+		}
+		f.pass, f.err = f.passphrase()
+		if f.err == nil && f.pass == "" {
+			f.err = errors.New("the credentials file passphrase cannot be empty")
+		}
+	})
+
+	return f.pass, f.err
+}
+
+func (f *encryptedFileStore) load() (map[string]string, error) {
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credentials file: %v", err)
+	}
+
+	var enc encryptedCredentials
+	if err := json.Unmarshal(b, &enc); err != nil {
+		return nil, fmt.Errorf("credentials file: parsing %s, %v", f.path, err)
+	}
+
+	aead, err := f.cipher(enc.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := aead.Open(nil, enc.Nonce, enc.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("credentials file: unable to decrypt, the passphrase may be wrong")
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(plain, &tokens); err != nil {
+		return nil, fmt.Errorf("credentials file: %v", err)
+	}
+
+	return tokens, nil
+}
+
+func (f *encryptedFileStore) save(tokens map[string]string) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	enc := encryptedCredentials{Salt: make([]byte, 16)}
+	if _, err := rand.Read(enc.Salt); err != nil {
+		return err
+	}
+
+	aead, err := f.cipher(enc.Salt)
+	if err != nil {
+		return err
+	}
+
+	enc.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(enc.Nonce); err != nil {
+		return err
+	}
+	enc.Ciphertext = aead.Seal(nil, enc.Nonce, plain, nil)
+
+	b, err := json.MarshalIndent(enc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(f.path, b, 0600); err != nil {
+		return fmt.Errorf("credentials file: writing %s, %v", f.path, err)
+	}
+
+	return nil
+}
+
+func (f *encryptedFileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	pass, err := f.getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, pass, salt, credentialsKDFIterations, credentialsKeyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCredentialHelper is a credential helper keeping one file per context
+// in the directory given as its first argument.
+const stubCredentialHelper = `#!/bin/sh
+dir="$1"
+action="$2"
+while IFS= read -r line; do
+	[ -z "$line" ] && break
+	case "$line" in
+		context=*) context="${line#context=}" ;;
+		token=*) token="${line#token=}" ;;
+	esac
+done
+case "$action" in
+	get) [ -f "$dir/$context" ] && printf 'token=%s\n' "$(cat "$dir/$context")" ;;
+	store) printf '%s' "$token" > "$dir/$context" ;;
+	erase) rm -f "$dir/$context" ;;
+	*) echo "unknown action $action" >&2; exit 1 ;;
+esac
+exit 0
+`
+
+func writeStub(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executables are shell scripts")
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(script), 0700))
+	return path
+}
+
+func TestCredentialHelperStore(t *testing.T) {
+	helper := writeStub(t, "helper", stubCredentialHelper)
+	dir := t.TempDir()
+
+	store, err := NewCredentialStore(CredentialStoreConfig{Helper: helper + " " + dir})
+	require.NoError(t, err)
+
+	token, err := store.Get("production")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	require.NoError(t, store.Store("production", "dop_v1_secret"))
+
+	b, err := os.ReadFile(filepath.Join(dir, "production"))
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_secret", string(b))
+
+	token, err = store.Get("production")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_secret", token)
+
+	require.NoError(t, store.Erase("production"))
+
+	token, err = store.Get("production")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestCredentialHelperInPath(t *testing.T) {
+	helper := writeStub(t, "doctl-credential-stub", stubCredentialHelper)
+	t.Setenv("PATH", filepath.Dir(helper)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir := t.TempDir()
+
+	store, err := NewCredentialStore(CredentialStoreConfig{Helper: "stub " + dir})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Store("staging", "dop_v1_staging"))
+
+	token, err := store.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_staging", token)
+}
+
+func TestCredentialHelperQuotedPath(t *testing.T) {
+	helper := writeStub(t, "credential helper", stubCredentialHelper)
+	dir := filepath.Join(t.TempDir(), "token store")
+	require.NoError(t, os.Mkdir(dir, 0700))
+
+	store, err := NewCredentialStore(CredentialStoreConfig{Helper: fmt.Sprintf("'%s' \"%s\"", helper, dir)})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Store("production", "dop_v1_secret"))
+
+	token, err := store.Get("production")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_secret", token)
+
+	_, err = NewCredentialStore(CredentialStoreConfig{Helper: "'" + helper})
+	assert.ErrorContains(t, err, "parsing credential helper")
+}
+
+func TestCredentialHelperError(t *testing.T) {
+	helper := writeStub(t, "helper", "#!/bin/sh\necho 'vault is sealed' >&2\nexit 2\n")
+
+	store, err := NewCredentialStore(CredentialStoreConfig{Helper: helper})
+	require.NoError(t, err)
+
+	_, err = store.Get("production")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "vault is sealed")
+}
+
+func TestSecretServiceStore(t *testing.T) {
+	dir := t.TempDir()
+	secretTool := writeStub(t, "secret-tool", `#!/bin/sh
+dir="`+dir+`"
+case "$1" in
+	lookup) [ -f "$dir/$5" ] || exit 1; cat "$dir/$5" ;;
+	store) [ "$2" = "--label" ] || exit 2; cat > "$dir/$7" ;;
+	clear) rm -f "$dir/$5" ;;
+esac
+`)
+
+	store := &secretServiceStore{command: secretTool}
+
+	token, err := store.Get("production")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	require.NoError(t, store.Store("production", "dop_v1_secret"))
+
+	token, err = store.Get("production")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_secret", token)
+
+	require.NoError(t, store.Erase("production"))
+
+	token, err = store.Get("production")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	passphrase := func(pass string) func() (string, error) {
+		return func() (string, error) { return pass, nil }
+	}
+
+	store, err := NewCredentialStore(CredentialStoreConfig{
+		Helper:     CredentialHelperFile,
+		File:       path,
+		Passphrase: passphrase("correct horse"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Store("production", "dop_v1_secret"))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "dop_v1_secret")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reopened := &encryptedFileStore{path: path, passphrase: passphrase("correct horse")}
+	token, err := reopened.Get("production")
+	require.NoError(t, err)
+	assert.Equal(t, "dop_v1_secret", token)
+
+	wrong := &encryptedFileStore{path: path, passphrase: passphrase("battery staple")}
+	_, err = wrong.Get("production")
+	assert.EqualError(t, err, "credentials file: unable to decrypt, the passphrase may be wrong")
+
+	require.NoError(t, reopened.Erase("production"))
+	token, err = reopened.Get("production")
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
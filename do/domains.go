@@ -65,6 +65,7 @@ type DomainsService interface {
 	Delete(string) error
 
 	Records(string) (DomainRecords, error)
+	RecordsIter(context.Context, string) *Iterator[DomainRecord]
 	Record(string, int) (*DomainRecord, error)
 	DeleteRecord(string, int) error
 	EditRecord(string, int, *DomainRecordEditRequest) (*DomainRecord, error)
@@ -165,6 +166,22 @@ func (ds *domainsService) Records(name string) (DomainRecords, error) {
 	return list, nil
 }
 
+func (ds *domainsService) RecordsIter(ctx context.Context, name string) *Iterator[DomainRecord] {
+	return NewIterator(ctx, func(ctx context.Context, opt *godo.ListOptions) ([]DomainRecord, *godo.Response, error) {
+		list, resp, err := ds.client.Domains.Records(ctx, name, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		records := make([]DomainRecord, len(list))
+		for i := range list {
+			records[i] = DomainRecord{DomainRecord: &list[i]}
+		}
+
+		return records, resp, nil
+	})
+}
+
 func (ds *domainsService) Record(domain string, id int) (*DomainRecord, error) {
 	dr, _, err := ds.client.Domains.Record(context.TODO(), domain, id)
 	if err != nil {
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package do
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// PageFunc fetches a single page of a typed list. It may return a nil
+// response for APIs that do not report their number of pages, in which case
+// the iteration stops after the first page holding fewer items than
+// requested.
+type PageFunc[T any] func(context.Context, *godo.ListOptions) ([]T, *godo.Response, error)
+
+// Iterator walks a paginated list one page at a time, fetching the next page
+// only once the previous one was consumed. Unlike PaginateResp it never holds
+// more than a single page in memory. It honors the options set with
+// SetPaginationConfig, and stops early when given a Limit.
+//
+//	it := ds.RecordsIter(ctx, "example.com")
+//	for it.Next() {
+//		for _, r := range it.Page() {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	ctx      context.Context
+	fn       PageFunc[T]
+	cfg      PaginationConfig
+	perPage  int
+	max      int
+	page     int
+	lastPage int
+	fetched  int
+	items    []T
+	err      error
+	done     bool
+}
+
+// NewIterator builds an Iterator over the pages returned by fn.
+func NewIterator[T any](ctx context.Context, fn PageFunc[T]) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:     ctx,
+		fn:      fn,
+		cfg:     paginationConfig,
+		perPage: perPage,
+	}
+}
+
+// Limit stops the iteration once n items were read, fetching only the pages
+// needed to reach them. Zero reads every item, and a limit already set is
+// only ever lowered. It must be called before the first call to Next.
+func (it *Iterator[T]) Limit(n int) *Iterator[T] {
+	if n <= 0 || (it.max > 0 && it.max <= n) {
+		return it
+	}
+
+	it.max = n
+	if n < it.perPage {
+		it.perPage = n
+	}
+
+	return it
+}
+
+// Next fetches the next page. It returns false once every page was read, the
+// item limit was reached, the context was cancelled or an error occurred. Err
+// reports which of those happened.
+func (it *Iterator[T]) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if it.lastPage > 0 && it.page >= it.lastPage {
+		it.finish(nil)
+		return false
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.finish(err)
+		return false
+	}
+
+	it.page++
+	items, resp, err := it.fetch()
+	if err != nil {
+		it.finish(fmt.Errorf("fetching page %d: %w", it.page, err))
+		return false
+	}
+
+	switch {
+	case resp == nil:
+		if len(items) < it.perPage {
+			it.lastPage = it.page
+		}
+	case it.page == 1:
+		it.lastPage, err = lastPage(resp)
+		if err != nil {
+			it.finish(err)
+			return false
+		}
+	}
+
+	if it.max > 0 {
+		if remaining := it.max - it.fetched; len(items) >= remaining {
+			items = items[:remaining]
+			it.lastPage = it.page
+		}
+	}
+
+	it.fetched += len(items)
+	it.items = items
+
+	return true
+}
+
+// Page returns the items of the page fetched by the last call to Next.
+func (it *Iterator[T]) Page() []T {
+	return it.items
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+func (it *Iterator[T]) finish(err error) {
+	it.done = true
+	it.items = nil
+	it.err = err
+}
+
+// fetch requests the current page, retrying with an exponential backoff when
+// the request fails.
+func (it *Iterator[T]) fetch() ([]T, *godo.Response, error) {
+	opt := &godo.ListOptions{Page: it.page, PerPage: it.perPage}
+
+	wait := pageRetryWait
+	for attempt := 0; ; attempt++ {
+		items, resp, err := it.fn(it.ctx, opt)
+		if err == nil || attempt >= it.cfg.PageRetries {
+			return items, resp, err
+		}
+
+		select {
+		case <-it.ctx.Done():
+			return nil, nil, it.ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Collect reads every remaining page of an Iterator into a single slice.
+func Collect[T any](it *Iterator[T]) ([]T, error) {
+	var all []T
+	for it.Next() {
+		all = append(all, it.Page()...)
+	}
+
+	return all, it.Err()
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package do
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func pagesOf(last int, size int) PageFunc[int] {
+	resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=" + strconv.Itoa(last)}}}
+	return func(_ context.Context, opt *godo.ListOptions) ([]int, *godo.Response, error) {
+		items := make([]int, 0, size)
+		for i := 1; i <= size; i++ {
+			items = append(items, (opt.Page-1)*size+i)
+		}
+		return items, resp, nil
+	}
+}
+
+func TestIterator(t *testing.T) {
+	it := NewIterator(context.Background(), pagesOf(3, 2))
+
+	var pages [][]int
+	for it.Next() {
+		pages = append(pages, it.Page())
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5, 6}}, pages)
+	assert.False(t, it.Next())
+}
+
+func TestIterator_Limit(t *testing.T) {
+	defer SetPaginationConfig(PaginationConfig{})
+	err := SetPaginationConfig(PaginationConfig{PageSize: 2})
+	assert.NoError(t, err)
+
+	var fetched []int
+	fn := pagesOf(9, 2)
+	it := NewIterator(context.Background(), func(ctx context.Context, opt *godo.ListOptions) ([]int, *godo.Response, error) {
+		fetched = append(fetched, opt.Page)
+		return fn(ctx, opt)
+	}).Limit(3)
+
+	all, err := Collect(it)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, all)
+	assert.Equal(t, []int{1, 2}, fetched)
+}
+
+func TestIterator_UnknownPageCount(t *testing.T) {
+	defer SetPaginationConfig(PaginationConfig{})
+	err := SetPaginationConfig(PaginationConfig{PageSize: 2})
+	assert.NoError(t, err)
+
+	items := []int{1, 2, 3, 4, 5}
+	it := NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]int, *godo.Response, error) {
+		start := (opt.Page - 1) * opt.PerPage
+		return items[start:min(start+opt.PerPage, len(items))], nil, nil
+	})
+
+	all, err := Collect(it)
+	assert.NoError(t, err)
+	assert.Equal(t, items, all)
+
+	it = NewIterator(context.Background(), func(_ context.Context, opt *godo.ListOptions) ([]int, *godo.Response, error) {
+		start := (opt.Page - 1) * opt.PerPage
+		return items[start:min(start+opt.PerPage, len(items))], nil, nil
+	}).Limit(4).Limit(10)
+
+	all, err = Collect(it)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, all)
+}
+
+func TestIterator_Error(t *testing.T) {
+	fn := pagesOf(3, 1)
+	it := NewIterator(context.Background(), func(ctx context.Context, opt *godo.ListOptions) ([]int, *godo.Response, error) {
+		if opt.Page == 2 {
+			return nil, nil, errors.New("boom")
+		}
+		return fn(ctx, opt)
+	})
+
+	all, err := Collect(it)
+	assert.EqualError(t, err, "fetching page 2: boom")
+	assert.Equal(t, []int{1}, all)
+}
+
+func TestIterator_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewIterator(ctx, pagesOf(3, 1))
+
+	assert.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
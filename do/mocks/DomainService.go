@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	do "github.com/digitalocean/doctl/do"
@@ -173,3 +174,17 @@ func (mr *MockDomainsServiceMockRecorder) Records(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Records", reflect.TypeOf((*MockDomainsService)(nil).Records), arg0)
 }
+
+// RecordsIter mocks base method.
+func (m *MockDomainsService) RecordsIter(arg0 context.Context, arg1 string) *do.Iterator[do.DomainRecord] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordsIter", arg0, arg1)
+	ret0, _ := ret[0].(*do.Iterator[do.DomainRecord])
+	return ret0
+}
+
+// RecordsIter indicates an expected call of RecordsIter.
+func (mr *MockDomainsServiceMockRecorder) RecordsIter(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordsIter", reflect.TypeOf((*MockDomainsService)(nil).RecordsIter), arg0, arg1)
+}
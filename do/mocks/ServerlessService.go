@@ -43,6 +43,20 @@ func (m *MockServerlessService) EXPECT() *MockServerlessServiceMockRecorder {
 	return m.recorder
 }
 
+// ActivationsIter mocks base method.
+func (m *MockServerlessService) ActivationsIter(arg0 context.Context, arg1 whisk.ActivationListOptions) *do.Iterator[whisk.Activation] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActivationsIter", arg0, arg1)
+	ret0, _ := ret[0].(*do.Iterator[whisk.Activation])
+	return ret0
+}
+
+// ActivationsIter indicates an expected call of ActivationsIter.
+func (mr *MockServerlessServiceMockRecorder) ActivationsIter(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivationsIter", reflect.TypeOf((*MockServerlessService)(nil).ActivationsIter), arg0, arg1)
+}
+
 // CheckServerlessStatus mocks base method.
 func (m *MockServerlessService) CheckServerlessStatus() error {
 	m.ctrl.T.Helper()
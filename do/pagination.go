@@ -16,21 +16,64 @@ package do
 import (
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/digitalocean/godo"
+	multierror "github.com/hashicorp/go-multierror"
 )
 
 const maxFetchPages = 5
 
-var perPage = 200
+// maxPerPage is the largest page size accepted by the API.
+const maxPerPage = 200
+
+var perPage = maxPerPage
 
 var fetchFn = fetchPage
 
+// pageRetryWait is the initial delay before retrying a failed page. It doubles
+// after every attempt.
+var pageRetryWait = time.Second
+
+// PaginationConfig controls how PaginateResp and Iterator fetch list
+// results. It applies to every list, so it never limits the number of items
+// returned; use Iterator.Limit to stop a single list early.
+type PaginationConfig struct {
+	// PageSize is the number of items requested per page. Zero uses the
+	// API maximum.
+	PageSize int
+	// PageRetries is the number of times a failed page is fetched again
+	// before giving up.
+	PageRetries int
+}
+
+var paginationConfig PaginationConfig
+
+// SetPaginationConfig sets the options used by PaginateResp.
+func SetPaginationConfig(cfg PaginationConfig) error {
+	if cfg.PageSize < 0 || cfg.PageSize > maxPerPage {
+		return fmt.Errorf("page size must be between 1 and %d, or 0 for the maximum", maxPerPage)
+	}
+	if cfg.PageRetries < 0 {
+		return fmt.Errorf("page retries must be a positive number")
+	}
+
+	paginationConfig = cfg
+	perPage = maxPerPage
+	if cfg.PageSize > 0 {
+		perPage = cfg.PageSize
+	}
+
+	return nil
+}
+
 type paginatedList struct {
 	list  [][]any
 	total int
+	errs  map[int]error
 	mu    sync.Mutex
 }
 
@@ -41,15 +84,45 @@ func (pl *paginatedList) set(page int, items []any) {
 	pl.list[page-1] = items
 }
 
+func (pl *paginatedList) setErr(page int, err error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.errs[page] = err
+}
+
+// err returns the errors of all failed pages, ordered by page number.
+func (pl *paginatedList) err() error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if len(pl.errs) == 0 {
+		return nil
+	}
+
+	pages := make([]int, 0, len(pl.errs))
+	for page := range pl.errs {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	var errs *multierror.Error
+	for _, page := range pages {
+		errs = multierror.Append(errs, fmt.Errorf("fetching page %d of %d: %w", page, len(pl.list), pl.errs[page]))
+	}
+
+	return errs.ErrorOrNil()
+}
+
 // Generator is a function that generates the list to be paginated.
 type Generator func(*godo.ListOptions) ([]any, *godo.Response, error)
 
-// PaginateResp paginates a Response.
+// PaginateResp paginates a Response. If any page can not be fetched, even
+// after retrying, an error is returned rather than a partial list.
 func PaginateResp(gen Generator) ([]any, error) {
-	opt := &godo.ListOptions{Page: 1, PerPage: perPage}
+	size := perPage
 
 	// fetch first page to get page count (x)
-	firstPage, resp, err := gen(opt)
+	firstPage, resp, err := fetchPageWithRetry(gen, 1, size)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +135,7 @@ func PaginateResp(gen Generator) ([]any, error) {
 
 	l := paginatedList{
 		list: make([][]any, lp),
+		errs: map[int]error{},
 	}
 
 	// set results from the first page
@@ -74,43 +148,56 @@ func PaginateResp(gen Generator) ([]any, error) {
 		wg.Add(1)
 		go func() {
 			for page := range fetchChan {
-				items, err := fetchFn(gen, page)
-				if err == nil {
-					l.set(page, items)
+				items, _, err := fetchPageWithRetry(gen, page, size)
+				if err != nil {
+					l.setErr(page, err)
+					continue
 				}
+				l.set(page, items)
 			}
 			wg.Done()
 		}()
 	}
 
 	// start with second page
-	opt.Page++
-	for ; opt.Page <= lp; opt.Page++ {
-		fetchChan <- opt.Page
+	for page := 2; page <= lp; page++ {
+		fetchChan <- page
 	}
 	close(fetchChan)
 
 	wg.Wait()
 
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+
 	// flatten paginated list
 	items := make([]any, l.total)[:0]
 	for _, page := range l.list {
-		if page == nil {
-			// must have been an error getting page results
-			continue
-		}
-		for _, item := range page {
-			items = append(items, item)
-		}
+		items = append(items, page...)
 	}
 
 	return items, nil
 }
 
-func fetchPage(gen Generator, page int) ([]any, error) {
-	opt := &godo.ListOptions{Page: page, PerPage: perPage}
-	items, _, err := gen(opt)
-	return items, err
+// fetchPageWithRetry fetches a page, retrying with an exponential backoff
+// when the request fails.
+func fetchPageWithRetry(gen Generator, page, size int) ([]any, *godo.Response, error) {
+	wait := pageRetryWait
+	for attempt := 0; ; attempt++ {
+		items, resp, err := fetchFn(gen, page, size)
+		if err == nil || attempt >= paginationConfig.PageRetries {
+			return items, resp, err
+		}
+
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func fetchPage(gen Generator, page, size int) ([]any, *godo.Response, error) {
+	opt := &godo.ListOptions{Page: page, PerPage: size}
+	return gen(opt)
 }
 
 func lastPage(resp *godo.Response) (int, error) {
@@ -14,8 +14,10 @@ limitations under the License.
 package do
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
@@ -49,7 +51,7 @@ func Test_Pagination_fetchPage(t *testing.T) {
 		return items, resp, nil
 	}
 
-	fetchPage(gen, 10)
+	fetchPage(gen, 10, perPage)
 }
 
 func Test_Pagination_lastPage(t *testing.T) {
@@ -90,3 +92,53 @@ func Test_Pagination_lastPage(t *testing.T) {
 		}
 	}
 }
+
+func Test_PaginateResp_PageError(t *testing.T) {
+	resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=5"}}}
+
+	gen := func(opt *godo.ListOptions) ([]any, *godo.Response, error) {
+		if opt.Page == 3 {
+			return nil, nil, errors.New("429 Too Many Requests")
+		}
+		return []any{opt.Page}, resp, nil
+	}
+
+	list, err := PaginateResp(gen)
+	assert.EqualError(t, err, "1 error occurred:\n\t* fetching page 3 of 5: 429 Too Many Requests\n\n")
+	assert.Nil(t, list)
+}
+
+func Test_PaginateResp_PageRetry(t *testing.T) {
+	defer SetPaginationConfig(PaginationConfig{})
+	defer func(wait time.Duration) { pageRetryWait = wait }(pageRetryWait)
+	pageRetryWait = 0
+
+	err := SetPaginationConfig(PaginationConfig{PageRetries: 2})
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	failures := map[int]int{}
+	resp := &godo.Response{Links: &godo.Links{Pages: &godo.Pages{Last: "http://example.com/?page=4"}}}
+
+	gen := func(opt *godo.ListOptions) ([]any, *godo.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if (opt.Page == 1 || opt.Page == 2) && failures[opt.Page] < 2 {
+			failures[opt.Page]++
+			return nil, nil, errors.New("500 Internal Server Error")
+		}
+		return []any{opt.Page}, resp, nil
+	}
+
+	list, err := PaginateResp(gen)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1, 2, 3, 4}, list)
+	assert.Equal(t, 2, failures[1])
+	assert.Equal(t, 2, failures[2])
+}
+
+func Test_SetPaginationConfig_Invalid(t *testing.T) {
+	assert.Error(t, SetPaginationConfig(PaginationConfig{PageSize: 201}))
+	assert.Error(t, SetPaginationConfig(PaginationConfig{PageSize: -1}))
+	assert.Error(t, SetPaginationConfig(PaginationConfig{PageRetries: -1}))
+}
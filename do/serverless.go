@@ -253,6 +253,7 @@ type ServerlessService interface {
 	InvokeFunction(string, any, bool, bool) (any, error)
 	InvokeFunctionViaWeb(string, any) error
 	ListActivations(whisk.ActivationListOptions) ([]whisk.Activation, error)
+	ActivationsIter(context.Context, whisk.ActivationListOptions) *Iterator[whisk.Activation]
 	GetActivationCount(whisk.ActivationCountOptions) (whisk.ActivationCount, error)
 	GetActivation(string) (whisk.Activation, error)
 	GetActivationLogs(string) (whisk.Activation, error)
@@ -986,6 +987,28 @@ func (s *serverlessService) ListActivations(options whisk.ActivationListOptions)
 	return resp, err
 }
 
+// ActivationsIter iterates over the activations matching the options a page
+// at a time, starting after the number of activations to skip. The limit of
+// the options is replaced by the page size.
+func (s *serverlessService) ActivationsIter(ctx context.Context, options whisk.ActivationListOptions) *Iterator[whisk.Activation] {
+	return NewIterator(ctx, func(_ context.Context, opt *godo.ListOptions) ([]whisk.Activation, *godo.Response, error) {
+		if err := initWhisk(s); err != nil {
+			return nil, nil, err
+		}
+
+		page := options
+		page.Skip = options.Skip + (opt.Page-1)*opt.PerPage
+		page.Limit = opt.PerPage
+		list, _, err := s.owClient.Activations.List(&page)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The OpenWhisk API does not report a number of pages.
+		return list, nil, nil
+	})
+}
+
 // GetActivationCount drives the OpenWhisk API for getting the total number of activations in namespace
 func (s *serverlessService) GetActivationCount(options whisk.ActivationCountOptions) (whisk.ActivationCount, error) {
 	err := initWhisk(s)
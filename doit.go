@@ -211,6 +211,7 @@ type Config interface {
 	GetGodoClient(trace, allowRetries bool, accessToken string) (*godo.Client, error)
 	GetDockerEngineClient() (builder.DockerEngineClient, error)
 	SSH(user, host, keyPath string, port int, opts ssh.Options) runner.Runner
+	SCP(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner
 	Listen(url *url.URL, token string, schemaFunc listen.SchemaFunc, out io.Writer, inCh <-chan []byte) listen.ListenerService
 	Terminal() terminal.Terminal
 	Set(ns, key string, val any)
@@ -236,8 +237,26 @@ var _ Config = &LiveConfig{}
 
 // GetGodoClient returns a GodoClient.
 func (c *LiveConfig) GetGodoClient(trace, allowRetries bool, accessToken string) (*godo.Client, error) {
+	recordPath := viper.GetString("record")
+	replayPath := viper.GetString("replay")
+	if recordPath != "" && replayPath != "" {
+		return nil, fmt.Errorf("the --record and --replay flags are mutually exclusive")
+	}
+
+	traceFormat := viper.GetString("trace-format")
+	if traceFormat == "" {
+		traceFormat = "text"
+	}
+	if traceFormat != "text" && traceFormat != "json" {
+		return nil, fmt.Errorf("unknown trace format %q, must be one of: text, json", traceFormat)
+	}
+
 	if accessToken == "" {
-		return nil, fmt.Errorf("access token is required. (hint: run 'doctl auth init')")
+		if replayPath == "" {
+			return nil, fmt.Errorf("access token is required. (hint: run 'doctl auth init')")
+		}
+		// responses are served from the cassette, no token is sent
+		accessToken = redactedValue
 	}
 
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
@@ -265,7 +284,7 @@ func (c *LiveConfig) GetGodoClient(trace, allowRetries bool, accessToken string)
 			retryConfig.RetryWaitMin = godo.PtrTo(float64(retryWaitMin))
 		}
 
-		if trace {
+		if trace && traceFormat == "text" {
 			retryConfig.Logger = logger
 		}
 
@@ -282,8 +301,52 @@ func (c *LiveConfig) GetGodoClient(trace, allowRetries bool, accessToken string)
 		return nil, err
 	}
 
-	if trace {
-		r := newRecorder(client.HTTPClient.Transport)
+	if trace && traceFormat == "json" {
+		t := newJSONTracer(client.HTTPClient.Transport, os.Stderr)
+		if rc := retryableClient(client); rc != nil {
+			// trace every attempt made by the retrying client
+			t.wrap = rc.HTTPClient.Transport
+			rc.HTTPClient.Transport = t
+			rc.RequestLogHook = t.logAttempt
+		} else {
+			client.HTTPClient.Transport = t
+		}
+	}
+
+	// installed after the JSON tracer so traced durations exclude time spent
+	// waiting on the throttle
+	if viper.GetBool("http-throttle") {
+		t := newThrottle(client.HTTPClient.Transport,
+			viper.GetFloat64("http-throttle-rate"),
+			viper.GetInt("http-throttle-burst"),
+			viper.GetInt("http-throttle-reserve"),
+		)
+		if rc := retryableClient(client); rc != nil {
+			// throttle retries as well
+			t.wrap = rc.HTTPClient.Transport
+			rc.HTTPClient.Transport = t
+		} else {
+			client.HTTPClient.Transport = t
+		}
+	}
+
+	switch {
+	case recordPath != "":
+		client.HTTPClient.Transport = newCassetteRecorder(client.HTTPClient.Transport, recordPath)
+	case replayPath != "":
+		player, err := newCassettePlayer(replayPath)
+		if err != nil {
+			return nil, err
+		}
+		client.HTTPClient.Transport = player
+	}
+
+	if trace && traceFormat == "text" {
+		var redact *redactor
+		if viper.GetBool("trace-redact") {
+			redact = defaultRedactor
+		}
+		r := newRecorder(client.HTTPClient.Transport, redact)
 
 		go func() {
 			for {
@@ -317,14 +380,59 @@ func userAgent() string {
 
 // SSH creates a ssh connection to a host.
 func (c *LiveConfig) SSH(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+	localForwards, _ := opts[ArgSSHLocalForward].([]ssh.Forward)
+	remoteForwards, _ := opts[ArgSSHRemoteForward].([]ssh.Forward)
+	bastion, _ := opts[ArgSSHBastion].(*ssh.Endpoint)
+	hostKey, _ := opts[ArgSSHHostKey].(string)
+	acceptNew, _ := opts[ArgSSHAcceptNew].(bool)
+	useBinary, _ := opts[ArgSSHBinary].(bool)
+	keys, _ := opts[ssh.OptionKeys].(ssh.Keys)
+	stdin, _ := opts[ssh.OptionStdin].(io.Reader)
+	stdout, _ := opts[ssh.OptionStdout].(io.Writer)
+	stderr, _ := opts[ssh.OptionStderr].(io.Writer)
+
 	return &ssh.Runner{
-		User:            user,
-		Host:            host,
-		KeyPath:         keyPath,
-		Port:            port,
-		AgentForwarding: opts[ArgsSSHAgentForwarding].(bool),
-		Command:         opts[ArgSSHCommand].(string),
-		RetriesMax:      opts[ArgSSHRetryMax].(int),
+		User:              user,
+		Host:              host,
+		KeyPath:           keyPath,
+		Port:              port,
+		AgentForwarding:   opts[ArgsSSHAgentForwarding].(bool),
+		Command:           opts[ArgSSHCommand].(string),
+		RetriesMax:        opts[ArgSSHRetryMax].(int),
+		LocalForwards:     localForwards,
+		RemoteForwards:    remoteForwards,
+		Bastion:           bastion,
+		HostKey:           hostKey,
+		AcceptNewHostKeys: acceptNew,
+		UseBinary:         useBinary,
+		Keys:              keys,
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Stderr:            stderr,
+	}
+}
+
+// SCP creates a file copy to or from a host.
+func (c *LiveConfig) SCP(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner {
+	bastion, _ := opts[ArgSSHBastion].(*ssh.Endpoint)
+	hostKey, _ := opts[ArgSSHHostKey].(string)
+	retriesMax, _ := opts[ArgSSHRetryMax].(int)
+	acceptNew, _ := opts[ArgSSHAcceptNew].(bool)
+	useBinary, _ := opts[ArgSSHBinary].(bool)
+
+	return &ssh.Copier{
+		Runner: ssh.Runner{
+			User:              user,
+			Host:              host,
+			KeyPath:           keyPath,
+			Port:              port,
+			RetriesMax:        retriesMax,
+			Bastion:           bastion,
+			HostKey:           hostKey,
+			AcceptNewHostKeys: acceptNew,
+			UseBinary:         useBinary,
+		},
+		Transfer: transfer,
 	}
 }
 
@@ -502,6 +610,7 @@ func isRequired(key string) bool {
 // TestConfig is an implementation of Config for testing.
 type TestConfig struct {
 	SSHFn              func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner
+	SCPFn              func(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner
 	ListenFn           func(url *url.URL, token string, schemaFunc listen.SchemaFunc, out io.Writer, inCh <-chan []byte) listen.ListenerService
 	TerminalFn         func() terminal.Terminal
 	v                  *viper.Viper
@@ -517,6 +626,9 @@ func NewTestConfig() *TestConfig {
 		SSHFn: func(u, h, kp string, p int, opts ssh.Options) runner.Runner {
 			return &MockRunner{}
 		},
+		SCPFn: func(u, h, kp string, p int, transfer ssh.Transfer, opts ssh.Options) runner.Runner {
+			return &MockRunner{}
+		},
 		ListenFn: func(url *url.URL, token string, schemaFunc listen.SchemaFunc, out io.Writer, inCh <-chan []byte) listen.ListenerService {
 			return &MockListener{}
 		},
@@ -545,6 +657,11 @@ func (c *TestConfig) SSH(user, host, keyPath string, port int, opts ssh.Options)
 	return c.SSHFn(user, host, keyPath, port, opts)
 }
 
+// SCP returns a mock SCP runner.
+func (c *TestConfig) SCP(user, host, keyPath string, port int, transfer ssh.Transfer, opts ssh.Options) runner.Runner {
+	return c.SCPFn(user, host, keyPath, port, transfer, opts)
+}
+
 // Listen returns a mock websocket listener
 func (c *TestConfig) Listen(url *url.URL, token string, schemaFunc listen.SchemaFunc, out io.Writer, inCh <-chan []byte) listen.ListenerService {
 	return c.ListenFn(url, token, schemaFunc, out, inCh)
@@ -85,8 +85,9 @@ var _ = suite("compute/domain/records/create", func(t *testing.T, when spec.G, i
 					"records",
 					alias,
 					"example.com",
-					"--record-name", "example.com",
+					"--record-name", "_sip._tcp",
 					"--record-type", "SRV",
+					"--record-data", "sip.example.com",
 					"--record-port", "0",
 				)
 
@@ -138,7 +139,7 @@ ID    Type    Name           Data    Priority    Port    TTL    Weight
 }
 `
 	domainRecordsCreateRequest = `
-{"flags":0, "name":"example.com", "port":0, "priority":0, "ttl":1800, "type":"SRV", "weight":0}
+{"data":"sip.example.com", "flags":0, "name":"_sip._tcp", "port":0, "priority":0, "ttl":1800, "type":"SRV", "weight":0}
 `
 
 	domainRecordsCreateWithoutPortOutput = `
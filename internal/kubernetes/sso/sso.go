@@ -5,8 +5,12 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"runtime"
+	"slices"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -29,21 +33,57 @@ var (
 
 const (
 	defaultLocalServerPort uint16 = 8080
+
+	scopeOfflineAccess = "offline_access"
+)
+
+// Flow is the way a user logs in to the OIDC provider.
+type Flow string
+
+const (
+	// FlowAuto uses the device flow when no browser can be opened, and the
+	// browser flow otherwise.
+	FlowAuto Flow = "auto"
+	// FlowBrowser follows the Authorization Code Flow with PKCE, in the
+	// default browser.
+	FlowBrowser Flow = "browser"
+	// FlowDevice follows the Device Authorization Grant, printing a URL and a
+	// code for the user to enter on any device with a browser.
+	FlowDevice Flow = "device"
 )
 
-// GetIDToken obtains an ID token from an OIDC provider, following the Authorization Code Flow with PKCE:
+// Flows are the supported login flows.
+var Flows = []Flow{FlowAuto, FlowBrowser, FlowDevice}
+
+// Token is an ID token obtained from an OIDC provider.
+type Token struct {
+	IDToken string
+	Expiry  time.Time
+	// RefreshToken, when the provider issues one, gets a new ID token without
+	// logging in again.
+	RefreshToken string
+}
+
+// GetIDToken obtains an ID token from an OIDC provider. With a refresh token, it first tries to refresh the ID
+// token, and logs in again if that fails. Logins follow the Authorization Code Flow with PKCE:
 // https://auth0.com/docs/get-started/authentication-and-authorization-flow/authorization-code-flow-with-pkce
-func GetIDToken(ctx context.Context, clientID, issuerURL string, opts ...LocalOIDCLoginOption) (string, time.Time, error) {
+// or, without a browser, the Device Authorization Grant: https://datatracker.ietf.org/doc/html/rfc8628
+func GetIDToken(ctx context.Context, clientID, issuerURL string, opts ...LocalOIDCLoginOption) (*Token, error) {
 	ssoTool, err := newLocalOIDCLogin(clientID, issuerURL, opts...)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("setting up SSO login tool: %w", err)
+		return nil, fmt.Errorf("setting up SSO login tool: %w", err)
 	}
-	return ssoTool.getIDToken(ctx)
+	return ssoTool.login(ctx)
 }
 
 type localOIDCLogin struct {
-	port   uint16
-	logger *log.Logger
+	port         uint16
+	logger       *log.Logger
+	flow         Flow
+	refreshToken string
+	// prompt is where the device flow asks the user to log in, which must be
+	// seen even when the logger is discarded.
+	prompt io.Writer
 
 	// set up on creation
 	oauth2Config oauth2.Config
@@ -55,7 +95,8 @@ type localOIDCLogin struct {
 	ssoServer *ssoServer
 
 	// only for stubbing in tests
-	openURL func(url string) error
+	openURL  func(url string) error
+	headless func() bool
 }
 
 // LocalOIDCLoginOption is a function that can be used to configure a local OIDC login tool.
@@ -75,12 +116,36 @@ func WithLogger(logger *log.Logger) func(*localOIDCLogin) {
 	}
 }
 
+// WithFlow sets the flow used to log in.
+func WithFlow(flow Flow) func(*localOIDCLogin) {
+	return func(l *localOIDCLogin) {
+		l.flow = flow
+	}
+}
+
+// WithRefreshToken sets a refresh token to try before logging in.
+func WithRefreshToken(refreshToken string) func(*localOIDCLogin) {
+	return func(l *localOIDCLogin) {
+		l.refreshToken = refreshToken
+	}
+}
+
+// WithPrompt sets where the device flow prints the URL and code to log in with. It defaults to stderr.
+func WithPrompt(w io.Writer) func(*localOIDCLogin) {
+	return func(l *localOIDCLogin) {
+		l.prompt = w
+	}
+}
+
 // NewLocalOIDCLogin creates a new local OIDC login tool.
 func newLocalOIDCLogin(clientID, issuerURL string, opts ...LocalOIDCLoginOption) (*localOIDCLogin, error) {
 	t := &localOIDCLogin{
-		port:    defaultLocalServerPort,
-		openURL: browser.OpenURL,
-		logger:  log.Default(),
+		port:     defaultLocalServerPort,
+		openURL:  browser.OpenURL,
+		headless: func() bool { return headless(os.Getenv, runtime.GOOS) },
+		logger:   log.Default(),
+		flow:     FlowAuto,
+		prompt:   os.Stderr,
 	}
 	for _, opt := range opts {
 		opt(t)
@@ -91,12 +156,22 @@ func newLocalOIDCLogin(clientID, issuerURL string, opts ...LocalOIDCLoginOption)
 		return nil, fmt.Errorf("creating OIDC provider: %w", err)
 	}
 
+	var claims providerClaims
+	if err := provider.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("reading OIDC provider metadata: %w", err)
+	}
+
 	oauth2Config := oauth2.Config{
 		ClientID:    clientID,
 		Endpoint:    provider.Endpoint(),
 		RedirectURL: t.redirectURL(),
 		Scopes:      []string{oidc.ScopeOpenID, "email", "team_role"},
 	}
+	oauth2Config.Endpoint.DeviceAuthURL = claims.DeviceAuthorizationEndpoint
+	// Most providers only issue refresh tokens for the offline_access scope.
+	if slices.Contains(claims.ScopesSupported, scopeOfflineAccess) {
+		oauth2Config.Scopes = append(oauth2Config.Scopes, scopeOfflineAccess)
+	}
 
 	state := uuid.New().String()
 	codeVerifier := oauth2.GenerateVerifier()
@@ -120,6 +195,56 @@ func newLocalOIDCLogin(clientID, issuerURL string, opts ...LocalOIDCLoginOption)
 	return t, nil
 }
 
+// providerClaims are the fields of the provider metadata that go-oidc does not expose.
+type providerClaims struct {
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint"`
+	ScopesSupported             []string `json:"scopes_supported"`
+}
+
+// headless reports whether the user likely cannot open a browser where doctl runs, as in CI jobs, SSH sessions
+// and Linux hosts without a display.
+func headless(getenv func(string) string, goos string) bool {
+	if getenv("CI") != "" {
+		return true
+	}
+
+	switch goos {
+	case "darwin", "windows":
+		return getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != ""
+	default:
+		// An SSH session with X11 forwarding has a display, and can open a browser.
+		return getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == ""
+	}
+}
+
+func (t *localOIDCLogin) login(ctx context.Context) (*Token, error) {
+	if t.refreshToken != "" {
+		token, err := t.refresh(ctx)
+		if err == nil {
+			return token, nil
+		}
+		t.logger.Printf("Refreshing the ID token failed, logging in again: %v\n", err)
+	}
+
+	flow := t.flow
+	if flow == FlowAuto {
+		flow = FlowBrowser
+		if t.headless() {
+			t.logger.Println("No browser available, using the device flow")
+			flow = FlowDevice
+		}
+	}
+
+	switch flow {
+	case FlowBrowser:
+		return t.getIDToken(ctx)
+	case FlowDevice:
+		return t.getIDTokenWithDeviceCode(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported SSO login flow %q", flow)
+	}
+}
+
 func (t *localOIDCLogin) redirectURL() string {
 	return fmt.Sprintf("http://localhost:%d/callback", t.port)
 }
@@ -188,33 +313,79 @@ func (t *localOIDCLogin) getAuthCode(ctx context.Context) (string, error) {
 	return authCode.code, nil
 }
 
-func (t *localOIDCLogin) getIDToken(ctx context.Context) (string, time.Time, error) {
+func (t *localOIDCLogin) getIDToken(ctx context.Context) (*Token, error) {
 	code, err := t.getAuthCode(ctx)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("getting authorization code: %w", err)
+		return nil, fmt.Errorf("getting authorization code: %w", err)
 	}
 
 	t.logger.Println("Received an authorization code, exchanging for ID token")
 	token, err := t.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(t.codeVerifier))
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("exchanging authorization code for ID token: %w", err)
+		return nil, fmt.Errorf("exchanging authorization code for ID token: %w", err)
+	}
+
+	return t.verifyIDToken(ctx, token, t.nonce)
+}
+
+func (t *localOIDCLogin) getIDTokenWithDeviceCode(ctx context.Context) (*Token, error) {
+	if t.oauth2Config.Endpoint.DeviceAuthURL == "" {
+		return nil, errors.New("the OIDC provider does not support the device flow, log in from a machine with a browser")
 	}
 
+	// The device flow has no redirect, and the provider may reject one.
+	config := t.oauth2Config
+	config.RedirectURL = ""
+
+	t.logger.Println("Requesting a device code")
+	da, err := config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+
+	if da.VerificationURIComplete != "" {
+		fmt.Fprintf(t.prompt, "To log in, open this URL in a browser on any device and check that it shows the code %s:\n\t%s\n", da.UserCode, da.VerificationURIComplete)
+	} else {
+		fmt.Fprintf(t.prompt, "To log in, open this URL in a browser on any device and enter the code %s:\n\t%s\n", da.UserCode, da.VerificationURI)
+	}
+
+	token, err := config.DeviceAccessToken(ctx, da)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for device authorization: %w", err)
+	}
+
+	t.logger.Println("Device authorized")
+	return t.verifyIDToken(ctx, token, "")
+}
+
+func (t *localOIDCLogin) refresh(ctx context.Context) (*Token, error) {
+	t.logger.Println("Refreshing the ID token")
+	token, err := t.oauth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: t.refreshToken}).Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// Refreshed ID tokens carry the nonce of the original login, if any.
+	return t.verifyIDToken(ctx, token, "")
+}
+
+// verifyIDToken checks the ID token of an OAuth2 token response and, when nonce is set, its nonce.
+func (t *localOIDCLogin) verifyIDToken(ctx context.Context, token *oauth2.Token, nonce string) (*Token, error) {
 	idToken, ok := token.Extra("id_token").(string)
 	if !ok || idToken == "" {
-		return "", time.Time{}, errors.New("no ID token found")
+		return nil, errors.New("no ID token found")
 	}
 
 	verifier := t.provider.Verifier(&oidc.Config{ClientID: t.oauth2Config.ClientID, Now: now})
 	verifiedIDToken, err := verifier.Verify(ctx, idToken)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("could not verify ID token: %w", err)
+		return nil, fmt.Errorf("could not verify ID token: %w", err)
 	}
-	if t.nonce != verifiedIDToken.Nonce {
-		return "", time.Time{}, fmt.Errorf("nonce did not match (wants %s but got %s)", t.nonce, verifiedIDToken.Nonce)
+	if nonce != "" && nonce != verifiedIDToken.Nonce {
+		return nil, fmt.Errorf("nonce did not match (wants %s but got %s)", nonce, verifiedIDToken.Nonce)
 	}
 
-	return idToken, token.Expiry, nil
+	return &Token{IDToken: idToken, Expiry: token.Expiry, RefreshToken: token.RefreshToken}, nil
 }
 
 type authCodeResponse struct {
@@ -27,12 +27,16 @@ type fakeIDP struct {
 	issuerURL string
 	clientID  string
 	authCode  string
+	// deviceCode enables the device authorization endpoint.
+	deviceCode   string
+	refreshToken string
 
 	// RSA key used to sign id_token and publish JWKS.
 	privKey *rsa.PrivateKey
 
-	mu        sync.Mutex
-	lastNonce string
+	mu          sync.Mutex
+	lastNonce   string
+	devicePolls int
 
 	// inject custom handlers to simulate errors
 	authorizeHandler func(w http.ResponseWriter, r *http.Request)
@@ -41,14 +45,19 @@ type fakeIDP struct {
 func (p *fakeIDP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.URL.Path == "/.well-known/openid-configuration" && r.Method == http.MethodGet:
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{
+		config := map[string]any{
 			"issuer":                                p.issuerURL,
 			"authorization_endpoint":                p.issuerURL + "/oauth/authorize",
 			"token_endpoint":                        p.issuerURL + "/oauth/token",
 			"jwks_uri":                              p.issuerURL + "/jwks",
 			"id_token_signing_alg_values_supported": []string{"RS256"},
-		})
+			"scopes_supported":                      []string{"openid", "email", "offline_access"},
+		}
+		if p.deviceCode != "" {
+			config["device_authorization_endpoint"] = p.issuerURL + "/oauth/device"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
 	case r.URL.Path == "/jwks" && r.Method == http.MethodGet:
 		pub := jose.JSONWebKey{Key: &p.privKey.PublicKey, KeyID: "test-kid", Algorithm: string(jose.RS256), Use: "sig"}
 		set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{pub}}
@@ -77,68 +86,122 @@ func (p *fakeIDP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		q.Set("state", state)
 		u.RawQuery = q.Encode()
 		http.Redirect(w, r, u.String(), http.StatusFound)
-	case r.URL.Path == "/oauth/token" && r.Method == http.MethodPost:
+	case r.URL.Path == "/oauth/device" && r.Method == http.MethodPost:
 		if err := r.ParseForm(); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if r.FormValue("grant_type") != "authorization_code" {
-			http.Error(w, "unexpected grant_type", http.StatusBadRequest)
-			return
-		}
-		if r.FormValue("code") != p.authCode {
-			http.Error(w, "unexpected code", http.StatusBadRequest)
-			return
-		}
 		if r.FormValue("client_id") != p.clientID {
 			http.Error(w, "unexpected client_id", http.StatusBadRequest)
 			return
 		}
-		p.mu.Lock()
-		nonce := p.lastNonce
-		p.mu.Unlock()
-		claims, err := json.Marshal(map[string]any{
-			"iss":   p.issuerURL,
-			"sub":   "test-user",
-			"aud":   p.clientID,
-			"exp":   time.Now().Add(time.Hour).Unix(),
-			"iat":   time.Now().Unix(),
-			"nonce": nonce,
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      p.deviceCode,
+			"user_code":        "WDJB-MJHT",
+			"verification_uri": p.issuerURL + "/device",
+			"expires_in":       60,
+			"interval":         1,
 		})
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		signer, err := jose.NewSigner(
-			jose.SigningKey{Algorithm: jose.RS256, Key: p.privKey},
-			(&jose.SignerOptions{}).WithHeader(jose.HeaderKey("kid"), "test-kid"),
-		)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	case r.URL.Path == "/oauth/token" && r.Method == http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		object, err := signer.Sign(claims)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if r.FormValue("client_id") != p.clientID {
+			http.Error(w, "unexpected client_id", http.StatusBadRequest)
 			return
 		}
-		idTokenStr, err := object.CompactSerialize()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			if r.FormValue("code") != p.authCode {
+				http.Error(w, "unexpected code", http.StatusBadRequest)
+				return
+			}
+			p.mu.Lock()
+			nonce := p.lastNonce
+			p.mu.Unlock()
+			p.writeToken(w, nonce)
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if r.FormValue("device_code") != p.deviceCode {
+				http.Error(w, "unexpected device_code", http.StatusBadRequest)
+				return
+			}
+			p.mu.Lock()
+			p.devicePolls++
+			pending := p.devicePolls == 1
+			p.mu.Unlock()
+			if pending {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "authorization_pending"}`))
+				return
+			}
+			p.writeToken(w, "")
+		case "refresh_token":
+			if p.refreshToken == "" || r.FormValue("refresh_token") != p.refreshToken {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			p.writeToken(w, "")
+		default:
+			http.Error(w, "unexpected grant_type", http.StatusBadRequest)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		_, _ = fmt.Fprintf(w, `{
-				"access_token": "fake-access-token",
-				"token_type": "Bearer",
-				"expires_in": 3600,
-				"id_token": %q
-			}`, idTokenStr)
 	default:
 		http.NotFound(w, r)
 	}
 }
 
+func (p *fakeIDP) polls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.devicePolls
+}
+
+// writeToken writes a token response with a signed ID token.
+func (p *fakeIDP) writeToken(w http.ResponseWriter, nonce string) {
+	claims, err := json.Marshal(map[string]any{
+		"iss":   p.issuerURL,
+		"sub":   "test-user",
+		"aud":   p.clientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: p.privKey},
+		(&jose.SignerOptions{}).WithHeader(jose.HeaderKey("kid"), "test-kid"),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	object, err := signer.Sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	idTokenStr, err := object.CompactSerialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{
+			"access_token": "fake-access-token",
+			"token_type": "Bearer",
+			"expires_in": 3600,
+			"refresh_token": "fake-refresh-token",
+			"id_token": %q
+		}`, idTokenStr)
+}
+
 func authorizeHandlerAccessDenied(w http.ResponseWriter, r *http.Request) {
 	redirectURI := r.URL.Query().Get("redirect_uri")
 	u, err := url.Parse(redirectURI)
@@ -274,12 +337,13 @@ func TestGetIDToken(t *testing.T) {
 				return nil
 			}
 
-			gotToken, exp, err := login.getIDToken(ctx)
+			token, err := login.getIDToken(ctx)
 			if tt.wantErr {
 				require.Error(t, err)
 				require.Contains(t, err.Error(), tt.errSubstring)
 			} else {
 				require.NoError(t, err)
+				gotToken := token.IDToken
 				require.NotEmpty(t, gotToken)
 				parts := strings.Split(gotToken, ".")
 				require.Len(t, parts, 3, "compact JWS should have three segments")
@@ -294,7 +358,8 @@ func TestGetIDToken(t *testing.T) {
 				require.Equal(t, idp.issuerURL, claims.Iss)
 				require.Equal(t, clientID, claims.Aud)
 				require.Equal(t, login.nonce, claims.Nonce)
-				require.True(t, exp.After(time.Now()), "expected non-zero token expiry from IdP response")
+				require.True(t, token.Expiry.After(time.Now()), "expected non-zero token expiry from IdP response")
+				require.Equal(t, "fake-refresh-token", token.RefreshToken)
 			}
 
 			select {
@@ -306,3 +371,120 @@ func TestGetIDToken(t *testing.T) {
 		})
 	}
 }
+
+func newTestIDP(t *testing.T, idp *fakeIDP) *fakeIDP {
+	t.Helper()
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp.privKey = privKey
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.issuerURL = srv.URL
+	return idp
+}
+
+func TestGetIDTokenDeviceFlow(t *testing.T) {
+	const clientID = "test-client-id"
+	idp := newTestIDP(t, &fakeIDP{clientID: clientID, deviceCode: "test-device-code"})
+
+	var prompt strings.Builder
+	login, err := newLocalOIDCLogin(clientID, idp.issuerURL,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithPrompt(&prompt),
+	)
+	require.NoError(t, err)
+	require.Contains(t, login.oauth2Config.Scopes, "offline_access")
+
+	login.headless = func() bool { return true }
+	login.openURL = func(string) error {
+		t.Fatal("the device flow should not open a browser")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	token, err := login.login(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, token.IDToken)
+	require.Equal(t, "fake-refresh-token", token.RefreshToken)
+	require.Equal(t, 2, idp.polls(), "expected a pending poll before the token")
+	require.Equal(t, "To log in, open this URL in a browser on any device and enter the code WDJB-MJHT:\n\t"+idp.issuerURL+"/device\n", prompt.String())
+}
+
+func TestGetIDTokenDeviceFlowUnsupported(t *testing.T) {
+	const clientID = "test-client-id"
+	idp := newTestIDP(t, &fakeIDP{clientID: clientID})
+
+	login, err := newLocalOIDCLogin(clientID, idp.issuerURL,
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithFlow(FlowDevice),
+	)
+	require.NoError(t, err)
+
+	_, err = login.login(context.Background())
+	require.EqualError(t, err, "the OIDC provider does not support the device flow, log in from a machine with a browser")
+}
+
+func TestGetIDTokenRefresh(t *testing.T) {
+	const clientID = "test-client-id"
+	idp := newTestIDP(t, &fakeIDP{clientID: clientID, deviceCode: "test-device-code", refreshToken: "valid-refresh-token"})
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		wantPolls    int
+	}{
+		{name: "valid refresh token", refreshToken: "valid-refresh-token", wantPolls: 0},
+		{name: "rejected refresh token falls back to login", refreshToken: "revoked-refresh-token", wantPolls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.mu.Lock()
+			idp.devicePolls = 0
+			idp.mu.Unlock()
+
+			login, err := newLocalOIDCLogin(clientID, idp.issuerURL,
+				WithLogger(log.New(io.Discard, "", 0)),
+				WithPrompt(io.Discard),
+				WithFlow(FlowDevice),
+				WithRefreshToken(tt.refreshToken),
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+
+			token, err := login.login(ctx)
+			require.NoError(t, err)
+			require.NotEmpty(t, token.IDToken)
+			require.True(t, token.Expiry.After(time.Now()))
+			require.Equal(t, tt.wantPolls, idp.polls())
+		})
+	}
+}
+
+func TestHeadless(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		goos string
+		want bool
+	}{
+		{name: "linux desktop", env: map[string]string{"DISPLAY": ":0"}, goos: "linux", want: false},
+		{name: "linux wayland", env: map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, goos: "linux", want: false},
+		{name: "linux without display", env: map[string]string{}, goos: "linux", want: true},
+		{name: "ssh with x11 forwarding", env: map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22", "DISPLAY": "localhost:10.0"}, goos: "linux", want: false},
+		{name: "macos", env: map[string]string{}, goos: "darwin", want: false},
+		{name: "macos over ssh", env: map[string]string{"SSH_TTY": "/dev/ttys001"}, goos: "darwin", want: true},
+		{name: "ci", env: map[string]string{"CI": "true", "DISPLAY": ":99"}, goos: "linux", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			require.Equal(t, tt.want, headless(getenv, tt.goos))
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dnsquery sends DNS queries directly to a nameserver, such as an
+// authoritative server, rather than through the system resolver.
+package dnsquery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// typeCAA is the CAA record type of RFC 8659, which dnsmessage does not
+// define.
+const typeCAA dnsmessage.Type = 257
+
+var types = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CAA":   typeCAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// ErrNameError is returned when the nameserver reports that the name does
+// not exist.
+var ErrNameError = errors.New("no such domain")
+
+// Record is a resource record of an answer. Names are lower case and have
+// no trailing dot.
+type Record struct {
+	Type     string
+	Name     string
+	TTL      int
+	Data     string
+	Priority int
+	Weight   int
+	Port     int
+	Flags    int
+	Tag      string
+}
+
+// Query asks a nameserver, given as host or host:port, for the records of a
+// name and type. An empty answer is not an error.
+func Query(ctx context.Context, server, name, typ string) ([]Record, error) {
+	qtype, ok := types[strings.ToUpper(typ)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %s", typ)
+	}
+
+	qname, err := dnsmessage.NewName(fqdn(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	id := uint16(rand.Uint32())
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchange(ctx, "udp", server, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var p dnsmessage.Parser
+	h, err := p.Start(resp)
+	if err != nil {
+		return nil, err
+	}
+	if h.Truncated {
+		if resp, err = exchange(ctx, "tcp", server, query); err != nil {
+			return nil, err
+		}
+		if h, err = p.Start(resp); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.ID != id {
+		return nil, errors.New("mismatched DNS response ID")
+	}
+	switch h.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, ErrNameError
+	default:
+		return nil, fmt.Errorf("%s answered %s", server, strings.TrimPrefix(h.RCode.String(), "RCode"))
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, a := range answers {
+		if a.Header.Type != qtype || !strings.EqualFold(a.Header.Name.String(), qname.String()) {
+			continue
+		}
+
+		r := Record{Type: strings.ToUpper(typ), Name: trimName(a.Header.Name), TTL: int(a.Header.TTL)}
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			r.Data = netip.AddrFrom4(body.A).String()
+		case *dnsmessage.AAAAResource:
+			r.Data = netip.AddrFrom16(body.AAAA).String()
+		case *dnsmessage.CNAMEResource:
+			r.Data = trimName(body.CNAME)
+		case *dnsmessage.NSResource:
+			r.Data = trimName(body.NS)
+		case *dnsmessage.MXResource:
+			r.Priority, r.Data = int(body.Pref), trimName(body.MX)
+		case *dnsmessage.SRVResource:
+			r.Priority, r.Weight, r.Port, r.Data = int(body.Priority), int(body.Weight), int(body.Port), trimName(body.Target)
+		case *dnsmessage.TXTResource:
+			r.Data = strings.Join(body.TXT, "")
+		case *dnsmessage.UnknownResource:
+			if err := parseCAA(&r, body.Data); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// exchange sends a query and reads the response, over UDP or, with a length
+// prefix, over TCP.
+func exchange(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// parseCAA parses the flags, tag and value of CAA record data.
+func parseCAA(r *Record, data []byte) error {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return errors.New("malformed CAA record")
+	}
+	r.Flags = int(data[0])
+	r.Tag = string(data[2 : 2+int(data[1])])
+	r.Data = string(data[2+int(data[1]):])
+	return nil
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func trimName(n dnsmessage.Name) string {
+	return strings.ToLower(strings.TrimSuffix(n.String(), "."))
+}
@@ -0,0 +1,171 @@
+package dnsquery
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testServer answers queries over UDP and TCP with the resources of a zone.
+// Over UDP, answers with more than truncateAt resources are truncated.
+type testServer struct {
+	zone       map[string][]dnsmessage.Resource
+	truncateAt int
+}
+
+func (s *testServer) answer(t *testing.T, query []byte, udp bool) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	require.NoError(t, err)
+	q, err := p.Question()
+	require.NoError(t, err)
+
+	key := strings.ToLower(q.Name.String()) + " " + q.Type.String()
+	answers := s.zone[key]
+
+	resp := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true}
+	if _, ok := s.zone[strings.ToLower(q.Name.String())+" exists"]; !ok && len(answers) == 0 {
+		resp.RCode = dnsmessage.RCodeNameError
+	}
+	if udp && s.truncateAt > 0 && len(answers) > s.truncateAt {
+		resp.Truncated = true
+		answers = nil
+	}
+
+	b := dnsmessage.NewBuilder(nil, resp)
+	require.NoError(t, b.StartQuestions())
+	require.NoError(t, b.Question(q))
+	require.NoError(t, b.StartAnswers())
+	for _, a := range answers {
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			require.NoError(t, b.AResource(a.Header, *body))
+		case *dnsmessage.MXResource:
+			require.NoError(t, b.MXResource(a.Header, *body))
+		case *dnsmessage.TXTResource:
+			require.NoError(t, b.TXTResource(a.Header, *body))
+		case *dnsmessage.UnknownResource:
+			require.NoError(t, b.UnknownResource(a.Header, *body))
+		}
+	}
+	msg, err := b.Finish()
+	require.NoError(t, err)
+	return msg
+}
+
+func (s *testServer) start(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(s.answer(t, buf[:n], true), addr)
+		}
+	}()
+
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		return pc.LocalAddr().String()
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					resp := s.answer(t, query, false)
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func header(name string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: 300}
+}
+
+func TestQuery(t *testing.T) {
+	server := &testServer{zone: map[string][]dnsmessage.Resource{
+		"www.example.com. TypeA": {
+			{Header: header("www.example.com.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{203, 0, 113, 10}}},
+		},
+		"example.com. TypeMX": {
+			{Header: header("example.com.", dnsmessage.TypeMX), Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("Mail.example.com.")}},
+		},
+		"example.com. 257": {
+			{Header: header("example.com.", typeCAA), Body: &dnsmessage.UnknownResource{Type: typeCAA, Data: []byte("\x00\x05issueletsencrypt.org")}},
+		},
+		"example.com. exists": nil,
+	}}
+	addr := server.start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	records, err := Query(ctx, addr, "www.example.com", "A")
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Type: "A", Name: "www.example.com", TTL: 300, Data: "203.0.113.10"}}, records)
+
+	records, err = Query(ctx, addr, "example.com", "MX")
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Type: "MX", Name: "example.com", TTL: 300, Data: "mail.example.com", Priority: 10}}, records)
+
+	records, err = Query(ctx, addr, "example.com", "caa")
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Type: "CAA", Name: "example.com", TTL: 300, Data: "letsencrypt.org", Tag: "issue"}}, records)
+
+	records, err = Query(ctx, addr, "example.com", "TXT")
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = Query(ctx, addr, "missing.example.com", "A")
+	assert.ErrorIs(t, err, ErrNameError)
+
+	_, err = Query(ctx, addr, "example.com", "PTR")
+	assert.EqualError(t, err, "unsupported record type PTR")
+}
+
+func TestQueryTruncated(t *testing.T) {
+	var txt []dnsmessage.Resource
+	for i := 0; i < 3; i++ {
+		txt = append(txt, dnsmessage.Resource{
+			Header: header("example.com.", dnsmessage.TypeTXT),
+			Body:   &dnsmessage.TXTResource{TXT: []string{strings.Repeat("x", 10)}},
+		})
+	}
+	server := &testServer{zone: map[string][]dnsmessage.Resource{"example.com. TypeTXT": txt}, truncateAt: 1}
+	addr := server.start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	records, err := Query(ctx, addr, "example.com", "TXT")
+	if err != nil {
+		t.Skipf("no TCP listener on the UDP port: %v", err)
+	}
+	assert.Len(t, records, 3)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/fatih/color"
+)
+
+// errBinaryHostKey is returned when a host key is pinned for the ssh or scp
+// binaries, which cannot pin one.
+var errBinaryHostKey = errors.New("--ssh-host-key cannot be used with --ssh-binary, which checks host keys with the ssh configuration")
+
+// runBinary runs the ssh binary, which applies the ssh configuration files,
+// instead of the built-in client.
+func (r *Runner) runBinary() error {
+	if r.HostKey != "" {
+		return errBinaryHostKey
+	}
+
+	args := r.binaryArgs("-p")
+	if r.AgentForwarding {
+		args = append(args, "-A")
+	}
+	for _, f := range r.LocalForwards {
+		args = append(args, "-L", f.String())
+	}
+	for _, f := range r.RemoteForwards {
+		args = append(args, "-R", f.String())
+	}
+
+	args = append(args, r.remote())
+	if r.Command != "" {
+		args = append(args, r.Command)
+	}
+
+	return r.runCommand("ssh", args)
+}
+
+// binaryArgs returns the options of the ssh and scp binaries, which set the
+// port with portFlag.
+func (r *Runner) binaryArgs(portFlag string) []string {
+	var args []string
+	if r.KeyPath != "" {
+		args = append(args, "-i", r.KeyPath)
+	}
+	if r.Port > 0 {
+		args = append(args, portFlag, strconv.Itoa(r.Port))
+	}
+	if r.Bastion != nil {
+		jump := r.Bastion.Host
+		if r.Bastion.User != "" {
+			jump = r.Bastion.User + "@" + jump
+		}
+		if r.Bastion.Port > 0 {
+			jump += ":" + strconv.Itoa(r.Bastion.Port)
+		}
+		args = append(args, "-J", jump)
+	}
+	if r.RetriesMax > 0 {
+		// the first attempt counts as a connection attempt
+		args = append(args, "-o", fmt.Sprintf("ConnectionAttempts=%d", r.RetriesMax+1))
+	}
+	if r.AcceptNewHostKeys {
+		args = append(args, "-o", "StrictHostKeyChecking=accept-new")
+	}
+	if r.KnownHostsPath != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+r.KnownHostsPath)
+	}
+	return args
+}
+
+func (r *Runner) remote() string {
+	if r.User == "" {
+		return r.Host
+	}
+	return r.User + "@" + r.Host
+}
+
+func (r *Runner) runCommand(name string, args []string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = r.stdin()
+	cmd.Stdout = r.stdout()
+	cmd.Stderr = r.stderr()
+
+	err := cmd.Run()
+	if _, isSnap := os.LookupEnv("SNAP"); os.IsPermission(err) && isSnap {
+		msg := "Using the doctl Snap? Grant access to the ssh-keys interface with this command: sudo snap connect doctl:ssh-keys"
+		fmt.Fprintf(color.Error, "%s: %s\n", color.YellowString("Warning"), msg)
+	}
+	return err
+}
+
+// runBinary runs the scp binary, which applies the ssh configuration files,
+// instead of the built-in client.
+func (c *Copier) runBinary() error {
+	if c.HostKey != "" {
+		return errBinaryHostKey
+	}
+
+	args := c.binaryArgs("-P")
+	if c.Recursive {
+		args = append(args, "-r")
+	}
+
+	if c.Upload {
+		args = append(args, c.Source, c.remote()+":"+c.Destination)
+	} else {
+		args = append(args, c.remote()+":"+c.Source, c.Destination)
+	}
+
+	return c.runCommand("scp", args)
+}
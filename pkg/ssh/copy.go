@@ -0,0 +1,224 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/digitalocean/doctl/pkg/runner"
+	"github.com/pkg/sftp"
+)
+
+// Transfer is a file copy between this machine and a remote host.
+type Transfer struct {
+	// Source is the path of the file or directory to copy.
+	Source string
+	// Destination is the path to copy it to.
+	Destination string
+	// Upload copies a local source to a remote destination, instead of a
+	// remote source to a local destination.
+	Upload bool
+	// Recursive copies directories.
+	Recursive bool
+}
+
+// Copier copies files to or from a host over SFTP.
+type Copier struct {
+	Runner
+	Transfer
+}
+
+var _ runner.Runner = &Copier{}
+
+// Run copies the files.
+func (c *Copier) Run() error {
+	if c.UseBinary {
+		return c.runBinary()
+	}
+
+	defer c.closeAgent()
+	client, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	if c.Upload {
+		return c.upload(sc, c.Source, remotePath(c.Destination))
+	}
+	return c.download(sc, remotePath(c.Source), c.Destination)
+}
+
+// remotePath makes paths relative to the home directory relative to the
+// SFTP working directory, which is the home directory.
+func remotePath(p string) string {
+	if p == "~" || p == "" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "~/")
+}
+
+func (c *Copier) upload(sc *sftp.Client, src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !c.Recursive {
+		return fmt.Errorf("%s is a directory, use --recursive to copy it", src)
+	}
+
+	if remote, err := sc.Stat(dst); err == nil && remote.IsDir() {
+		dst = path.Join(dst, filepath.Base(src))
+	}
+
+	if !info.IsDir() {
+		return uploadFile(sc, src, dst, info.Mode())
+	}
+
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, filepath.ToSlash(rel))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := sc.Mkdir(target); err != nil {
+				if remote, statErr := sc.Stat(target); statErr != nil || !remote.IsDir() {
+					return fmt.Errorf("creating %s: %w", target, err)
+				}
+				return nil
+			}
+			return sc.Chmod(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return uploadFile(sc, p, target, info.Mode())
+	})
+}
+
+// uploadFile copies a local file to a remote path. A file it creates gets
+// the permissions of the local file.
+func uploadFile(sc *sftp.Client, src, dst string, mode fs.FileMode) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, statErr := sc.Stat(dst)
+	created := errors.Is(statErr, fs.ErrNotExist)
+
+	remote, err := sc.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", dst, err)
+	}
+	if _, err := remote.ReadFrom(f); err != nil {
+		remote.Close()
+		return fmt.Errorf("writing %s: %w", dst, err)
+	}
+	if created {
+		if err := remote.Chmod(mode.Perm()); err != nil {
+			remote.Close()
+			return err
+		}
+	}
+
+	return remote.Close()
+}
+
+func (c *Copier) download(sc *sftp.Client, src, dst string) error {
+	info, err := sc.Stat(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if info.IsDir() && !c.Recursive {
+		return fmt.Errorf("%s is a directory, use --recursive to copy it", src)
+	}
+
+	if local, err := os.Stat(dst); err == nil && local.IsDir() {
+		dst = filepath.Join(dst, path.Base(src))
+	}
+
+	return downloadTree(sc, src, dst, info)
+}
+
+func downloadTree(sc *sftp.Client, src, dst string, info fs.FileInfo) error {
+	if !info.IsDir() {
+		return downloadFile(sc, src, dst, info)
+	}
+
+	if err := os.MkdirAll(dst, fileMode(info, 0755)); err != nil {
+		return err
+	}
+
+	entries, err := sc.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", src, err)
+	}
+	for _, e := range entries {
+		if err := downloadTree(sc, path.Join(src, e.Name()), filepath.Join(dst, e.Name()), e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func downloadFile(sc *sftp.Client, src, dst string, info fs.FileInfo) error {
+	remote, err := sc.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", src, err)
+	}
+	defer remote.Close()
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode(info, 0644))
+	if err != nil {
+		return err
+	}
+
+	if _, err := remote.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("reading %s: %w", src, err)
+	}
+
+	return f.Close()
+}
+
+func fileMode(info fs.FileInfo, dflt fs.FileMode) fs.FileMode {
+	if perm := info.Mode().Perm(); perm != 0 {
+		return perm
+	}
+	return dflt
+}
@@ -14,18 +14,148 @@ limitations under the License.
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/digitalocean/doctl/pkg/runner"
+	"github.com/digitalocean/doctl/pkg/terminal"
 	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
 )
 
 // Options is the type used to specify options passed to the SSH command
 type Options map[string]any
 
+// The Options keys of the standard streams of the SSH command, which default
+// to those of doctl.
+const (
+	OptionStdin  = "stdin"
+	OptionStdout = "stdout"
+	OptionStderr = "stderr"
+)
+
+// OptionKeys is the Options key of the Keys to authenticate with.
+const OptionKeys = "keys"
+
+// Keys are private keys loaded by LoadKeys.
+type Keys []ssh.Signer
+
+// ExitStatus returns the exit status of the command run by a Runner from the
+// error it returned: 0 when it succeeded, and 255 when it could not be run,
+// as ssh does.
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	var binaryErr *exec.ExitError
+	if errors.As(err, &binaryErr) {
+		return binaryErr.ExitCode()
+	}
+	return 255
+}
+
+// Endpoint is a host reached over SSH.
+type Endpoint struct {
+	User string
+	Host string
+	Port int
+}
+
+func (e Endpoint) address() string {
+	port := e.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(port))
+}
+
+// Forward is a port forwarded through an SSH connection.
+type Forward struct {
+	BindAddress string
+	BindPort    int
+	Host        string
+	HostPort    int
+}
+
+// ParseForward parses a port forward in the [bind_address:]port:host:hostport
+// format used by ssh's -L and -R flags.
+func ParseForward(spec string) (Forward, error) {
+	var parts []string
+	field := ""
+	bracketed := false
+	for _, ch := range spec {
+		switch {
+		case ch == '[':
+			bracketed = true
+		case ch == ']':
+			bracketed = false
+		case ch == ':' && !bracketed:
+			parts = append(parts, field)
+			field = ""
+		default:
+			field += string(ch)
+		}
+	}
+	parts = append(parts, field)
+
+	var f Forward
+	switch len(parts) {
+	case 3:
+		f.BindAddress = "localhost"
+	case 4:
+		f.BindAddress = parts[0]
+		if f.BindAddress == "" || f.BindAddress == "*" {
+			f.BindAddress = "0.0.0.0"
+		}
+		parts = parts[1:]
+	default:
+		return f, fmt.Errorf("invalid port forward %q, must be [bind_address:]port:host:hostport", spec)
+	}
+
+	var err error
+	if f.BindPort, err = strconv.Atoi(parts[0]); err != nil {
+		return f, fmt.Errorf("invalid port forward %q: bad port %q", spec, parts[0])
+	}
+	f.Host = parts[1]
+	if f.HostPort, err = strconv.Atoi(parts[2]); err != nil {
+		return f, fmt.Errorf("invalid port forward %q: bad port %q", spec, parts[2])
+	}
+
+	return f, nil
+}
+
+// String formats a forward for ssh's -L and -R flags.
+func (f Forward) String() string {
+	return f.bindAddress() + ":" + f.hostAddress()
+}
+
+func (f Forward) bindAddress() string {
+	return net.JoinHostPort(f.BindAddress, strconv.Itoa(f.BindPort))
+}
+
+func (f Forward) hostAddress() string {
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.HostPort))
+}
+
 // Runner runs ssh commands.
 type Runner struct {
 	User            string
@@ -35,58 +165,526 @@ type Runner struct {
 	AgentForwarding bool
 	Command         string
 	RetriesMax      int
+
+	// LocalForwards are ports of this machine forwarded to hosts reachable
+	// from the remote host.
+	LocalForwards []Forward
+	// RemoteForwards are ports of the remote host forwarded to hosts
+	// reachable from this machine.
+	RemoteForwards []Forward
+	// Bastion is a host the connection jumps through.
+	Bastion *Endpoint
+	// HostKey pins the SHA256 fingerprint of the remote host key. When it is
+	// empty, host keys are checked against the known hosts file.
+	HostKey string
+	// KnownHostsPath is the known hosts file, ~/.ssh/known_hosts by default.
+	KnownHostsPath string
+	// AcceptNewHostKeys adds the keys of unknown hosts to the known hosts
+	// file without asking for confirmation.
+	AcceptNewHostKeys bool
+	// UseBinary runs the ssh binary instead of the built-in client, which
+	// applies the ssh configuration files such as ~/.ssh/config.
+	UseBinary bool
+	// Keys are the keys to authenticate with, so that several runners share
+	// keys decrypted once. When it is nil, the keys at KeyPath are loaded on
+	// each run.
+	Keys Keys
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	agent     agent.ExtendedAgent
+	agentConn net.Conn
 }
 
 var _ runner.Runner = &Runner{}
 
+// readPassphrase prompts for the passphrase of an encrypted private key.
+var readPassphrase = func(path string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("private key %s is encrypted, load it in ssh-agent to use it without a terminal", path)
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", path)
+	defer fmt.Fprintln(os.Stderr)
+	return term.ReadPassword(fd)
+}
+
+// confirmHostKey asks whether to trust the key of a host missing from the
+// known hosts file, returning an error unless it is trusted.
+var confirmHostKey = func(hostname string, key ssh.PublicKey) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("host key of %s is not known, its %s key fingerprint is %s. Pin it with --ssh-host-key, or add unknown host keys to the known hosts file with --ssh-accept-new", hostname, key.Type(), ssh.FingerprintSHA256(key))
+	}
+
+	fmt.Fprintf(os.Stderr, "The authenticity of host '%s' can't be established.\n%s key fingerprint is %s.\nAre you sure you want to continue connecting (yes/no)? ", hostname, key.Type(), ssh.FingerprintSHA256(key))
+	var answer []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		if b[0] == '\n' {
+			break
+		}
+		answer = append(answer, b[0])
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(answer))) {
+	case "yes", "y":
+		return nil
+	default:
+		return fmt.Errorf("host key of %s was not accepted", hostname)
+	}
+}
+
 // Run ssh.
 func (r *Runner) Run() error {
-	args := []string{}
-	if r.KeyPath != "" {
-		args = append(args, "-i", r.KeyPath)
+	if r.UseBinary {
+		return r.runBinary()
 	}
 
-	sshHost := r.Host
-	if r.User != "" {
-		sshHost = r.User + "@" + sshHost
+	defer r.closeAgent()
+	client, err := r.connect()
+	if err != nil {
+		return err
 	}
+	defer client.Close()
+
+	for _, f := range r.LocalForwards {
+		l, err := net.Listen("tcp", f.bindAddress())
+		if err != nil {
+			return fmt.Errorf("forwarding local port %d: %w", f.BindPort, err)
+		}
+		defer l.Close()
+		go r.forward(l, f, client.Dial)
+	}
+
+	for _, f := range r.RemoteForwards {
+		l, err := client.Listen("tcp", f.bindAddress())
+		if err != nil {
+			return fmt.Errorf("forwarding remote port %d: %w", f.BindPort, err)
+		}
+		defer l.Close()
+		go r.forward(l, f, net.Dial)
+	}
+
+	return r.runSession(client)
+}
 
-	if r.Port > 0 {
-		args = append(args, "-p", strconv.Itoa(r.Port))
+func (r *Runner) stdin() io.Reader {
+	if r.Stdin != nil {
+		return r.Stdin
 	}
+	return os.Stdin
+}
+
+func (r *Runner) stdout() io.Writer {
+	if r.Stdout != nil {
+		return r.Stdout
+	}
+	return os.Stdout
+}
+
+func (r *Runner) stderr() io.Writer {
+	if r.Stderr != nil {
+		return r.Stderr
+	}
+	return os.Stderr
+}
+
+func (r *Runner) runSession(client *ssh.Client) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = r.stdin()
+	session.Stdout = r.stdout()
+	session.Stderr = r.stderr()
 
 	if r.AgentForwarding {
-		args = append(args, "-A")
+		if r.agent == nil {
+			return errors.New("agent forwarding requires a running ssh-agent, SSH_AUTH_SOCK is not set")
+		}
+		if err := agent.ForwardToAgent(client, r.agent); err != nil {
+			return err
+		}
+		if err := agent.RequestAgentForwarding(session); err != nil {
+			return err
+		}
 	}
 
-	args = append(args, sshHost)
 	if r.Command != "" {
-		args = append(args, r.Command)
+		return session.Run(r.Command)
+	}
+
+	if f, ok := session.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fd := int(f.Fd())
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return err
+		}
+
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = "xterm-256color"
+		}
+		if err := session.RequestPty(termType, height, width, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return err
+		}
+
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		resizes := make(chan terminal.TerminalSize)
+		go terminal.New().MonitorResizeEvents(ctx, resizes)
+		go func() {
+			for {
+				select {
+				case size := <-resizes:
+					session.WindowChange(size.Height, size.Width)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	if err := session.Shell(); err != nil {
+		return err
+	}
+	return session.Wait()
+}
+
+// forward copies the connections accepted by l to the forward's host,
+// dialed with dial.
+func (r *Runner) forward(l net.Listener, f Forward, dial func(network, addr string) (net.Conn, error)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			target, err := dial("tcp", f.hostAddress())
+			if err != nil {
+				fmt.Fprintf(r.stderr(), "%s: forwarding port %d to %s: %v\n", color.YellowString("Warning"), f.BindPort, f.hostAddress(), err)
+				conn.Close()
+				return
+			}
+			pipe(conn, target)
+		}()
 	}
+}
 
-	if r.RetriesMax > 0 {
-		connAttemptsOpt := fmt.Sprintf("ConnectionAttempts=%d", r.RetriesMax)
-		args = append(args, "-o", connAttemptsOpt)
+func pipe(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// connect opens an SSH connection to the host, through the bastion when one
+// is set, retrying up to RetriesMax times when the host cannot be reached.
+func (r *Runner) connect() (*ssh.Client, error) {
+	auth, err := r.authMethods()
+	if err != nil {
+		return nil, err
 	}
 
-	cmd := exec.Command("ssh", args...)
+	target := Endpoint{User: r.User, Host: r.Host, Port: r.Port}
+	for attempt := 0; ; attempt++ {
+		client, err := r.dial(target, auth)
+		var opErr *net.OpError
+		if err == nil || attempt >= r.RetriesMax || !errors.As(err, &opErr) {
+			return client, err
+		}
+		time.Sleep(time.Second)
+	}
+}
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
+func (r *Runner) dial(target Endpoint, auth []ssh.AuthMethod) (*ssh.Client, error) {
+	if r.Bastion == nil {
+		config, err := r.clientConfig(target, auth, r.HostKey)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.Dial("tcp", target.address(), config)
+	}
 
-	err := cmd.Run()
+	config, err := r.clientConfig(*r.Bastion, auth, "")
+	if err != nil {
+		return nil, err
+	}
+	bastion, err := ssh.Dial("tcp", r.Bastion.address(), config)
 	if err != nil {
-		_, isSnap := os.LookupEnv("SNAP")
+		return nil, fmt.Errorf("connecting to bastion %s: %w", r.Bastion.Host, err)
+	}
+
+	config, err = r.clientConfig(target, auth, r.HostKey)
+	if err != nil {
+		bastion.Close()
+		return nil, err
+	}
+	conn, err := bastion.Dial("tcp", target.address())
+	if err != nil {
+		bastion.Close()
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, target.address(), config)
+	if err != nil {
+		bastion.Close()
+		return nil, err
+	}
+
+	client := ssh.NewClient(c, chans, reqs)
+	go func() {
+		client.Wait()
+		bastion.Close()
+	}()
+	return client, nil
+}
+
+func (r *Runner) clientConfig(e Endpoint, auth []ssh.AuthMethod, pin string) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User:    e.User,
+		Auth:    auth,
+		Timeout: 30 * time.Second,
+	}
+
+	if pin != "" {
+		config.HostKeyCallback = pinnedHostKey(pin)
+		return config, nil
+	}
+
+	path := r.KnownHostsPath
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, algorithms, err := knownHostsCallback(path, e.address(), r.AcceptNewHostKeys, r.stderr())
+	if err != nil {
+		return nil, err
+	}
+	config.HostKeyCallback = callback
+	config.HostKeyAlgorithms = algorithms
+	return config, nil
+}
+
+func (r *Runner) authMethods() ([]ssh.AuthMethod, error) {
+	keys := r.Keys
+	if keys == nil {
+		var err error
+		keys, err = LoadKeys(r.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	signers := []ssh.Signer(keys)
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err == nil {
+			r.agentConn = conn
+			r.agent = agent.NewClient(conn)
+			agentSigners, err := r.agent.Signers()
+			if err == nil {
+				// r.Keys may be shared with other runners, so copy it
+				signers = append(slices.Clip(signers), agentSigners...)
+			}
+		}
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no SSH key found at %s and no keys loaded in ssh-agent", r.KeyPath)
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, nil
+}
+
+// LoadKeys loads the private key at keyPath, or the other default keys next
+// to it when there is none, prompting for the passphrase of encrypted keys.
+func LoadKeys(keyPath string) (Keys, error) {
+	if keyPath == "" {
+		return nil, nil
+	}
+
+	paths := []string{keyPath}
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		// fall back to the other default key names next to it
+		dir := filepath.Dir(keyPath)
+		paths = []string{filepath.Join(dir, "id_ed25519"), filepath.Join(dir, "id_ecdsa")}
+	}
+
+	var keys Keys
+	for _, path := range paths {
+		signer, err := loadSigner(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, signer)
+	}
+	return keys, nil
+}
+
+// closeAgent closes the connection to ssh-agent opened by authMethods.
+func (r *Runner) closeAgent() {
+	if r.agentConn != nil {
+		r.agentConn.Close()
+		r.agentConn = nil
+		r.agent = nil
+	}
+}
 
-		if os.IsPermission(err) && isSnap {
+func loadSigner(path string) (ssh.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if _, isSnap := os.LookupEnv("SNAP"); os.IsPermission(err) && isSnap {
 			msg := "Using the doctl Snap? Grant access to the ssh-keys interface with this command: sudo snap connect doctl:ssh-keys"
 			fmt.Fprintf(color.Error, "%s: %s\n", color.YellowString("Warning"), msg)
+		}
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(b)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase, err := readPassphrase(path)
+		if err != nil {
+			return nil, err
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(b, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("reading private key %s: %w", path, err)
+		}
+		return signer, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading private key %s: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+// pinnedHostKey accepts only the host key with the given SHA256 fingerprint.
+func pinnedHostKey(fingerprint string) ssh.HostKeyCallback {
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		fingerprint = "SHA256:" + fingerprint
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if got := ssh.FingerprintSHA256(key); got != fingerprint {
+			return fmt.Errorf("host key of %s has fingerprint %s, expected %s", hostname, got, fingerprint)
+		}
+		return nil
+	}
+}
+
+var knownHostsMu sync.Mutex
+
+// knownHostsCallback checks host keys against a known hosts file. The keys of
+// hosts it does not know yet are added to it once confirmed, or right away
+// with acceptNew. It also returns the host key algorithms to negotiate so a
+// known host presents the key type on file.
+func knownHostsCallback(path, address string, acceptNew bool, stderr io.Writer) (ssh.HostKeyCallback, []string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	f.Close()
+
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var algorithms []string
+	var keyErr *knownhosts.KeyError
+	if err := check(address, &net.TCPAddr{}, probeKey{}); errors.As(err, &keyErr) {
+		for _, known := range keyErr.Want {
+			if known.Key.Type() == ssh.KeyAlgoRSA {
+				algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+			}
+			algorithms = append(algorithms, known.Key.Type())
+		}
+	}
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
 			return err
 		}
 
-		return err
+		if len(keyErr.Want) > 0 {
+			host, _, _ := net.SplitHostPort(hostname)
+			return fmt.Errorf("host key of %s does not match the key in %s:%d. If the host was rebuilt, remove its old key with: ssh-keygen -R %s", hostname, keyErr.Want[0].Filename, keyErr.Want[0].Line, host)
+		}
+
+		// hosts connected to at the same time are confirmed one at a time
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		if !acceptNew {
+			if err := confirmHostKey(hostname, key); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "%s: Permanently added '%s' (%s) to the list of known hosts.\n", color.YellowString("Warning"), hostname, key.Type())
+		return nil
 	}
 
-	return nil
+	return callback, algorithms, nil
 }
+
+// probeKey is a public key no host has, used to list the known keys of a
+// host.
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "probe" }
+func (probeKey) Marshal() []byte                              { return []byte("probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
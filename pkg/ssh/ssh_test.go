@@ -0,0 +1,607 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testServer is an in-process SSH server supporting the commands echo, exit
+// and wait, port forwarding and the subset of SFTP used by the client.
+type testServer struct {
+	host    string
+	port    int
+	hostKey ssh.Signer
+	config  *ssh.ServerConfig
+	// root is the directory served over SFTP.
+	root string
+	// release unblocks the wait command.
+	release chan struct{}
+}
+
+func newTestServer(t *testing.T) *testServer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	b, err := os.ReadFile("testdata/id_rsa_without_password.pub")
+	require.NoError(t, err)
+	authorized, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &testServer{
+		host:    "127.0.0.1",
+		port:    l.Addr().(*net.TCPAddr).Port,
+		hostKey: hostKey,
+		config:  config,
+		root:    t.TempDir(),
+		release: make(chan struct{}),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) address() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+func (s *testServer) runner(t *testing.T) *Runner {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	return &Runner{
+		User:           "root",
+		Host:           s.host,
+		Port:           s.port,
+		KeyPath:        "testdata/id_rsa_without_password",
+		HostKey:        ssh.FingerprintSHA256(s.hostKey.PublicKey()),
+		Stdin:          strings.NewReader(""),
+		Stdout:         &bytes.Buffer{},
+		Stderr:         &bytes.Buffer{},
+		KnownHostsPath: filepath.Join(t.TempDir(), "known_hosts"),
+	}
+}
+
+func (s *testServer) handle(nc net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(nc, s.config)
+	if err != nil {
+		nc.Close()
+		return
+	}
+	defer conn.Close()
+	go s.globalRequests(conn, reqs)
+
+	for newCh := range chans {
+		switch newCh.ChannelType() {
+		case "session":
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go s.session(ch, reqs)
+		case "direct-tcpip":
+			var p struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			if err := ssh.Unmarshal(newCh.ExtraData(), &p); err != nil {
+				newCh.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			target, err := net.Dial("tcp", net.JoinHostPort(p.Host, strconv.Itoa(int(p.Port))))
+			if err != nil {
+				newCh.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				target.Close()
+				continue
+			}
+			go ssh.DiscardRequests(reqs)
+			go copyBoth(ch, target)
+		default:
+			newCh.Reject(ssh.UnknownChannelType, newCh.ChannelType())
+		}
+	}
+}
+
+func (s *testServer) globalRequests(conn *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if req.Type != "tcpip-forward" {
+			if req.WantReply {
+				req.Reply(req.Type == "cancel-tcpip-forward", nil)
+			}
+			continue
+		}
+
+		var p struct {
+			Addr string
+			Port uint32
+		}
+		if err := ssh.Unmarshal(req.Payload, &p); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort(p.Addr, strconv.Itoa(int(p.Port))))
+		if err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		port := uint32(l.Addr().(*net.TCPAddr).Port)
+		req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+
+		go func() {
+			conn.Wait()
+			l.Close()
+		}()
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				orig := c.RemoteAddr().(*net.TCPAddr)
+				payload := ssh.Marshal(struct {
+					Addr     string
+					Port     uint32
+					OrigAddr string
+					OrigPort uint32
+				}{p.Addr, port, orig.IP.String(), uint32(orig.Port)})
+				ch, reqs, err := conn.OpenChannel("forwarded-tcpip", payload)
+				if err != nil {
+					c.Close()
+					continue
+				}
+				go ssh.DiscardRequests(reqs)
+				go copyBoth(ch, c)
+			}
+		}()
+	}
+}
+
+func (s *testServer) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		switch req.Type {
+		case "exec":
+			var p struct{ Command string }
+			ssh.Unmarshal(req.Payload, &p)
+			req.Reply(true, nil)
+			go func() {
+				status := s.exec(p.Command, ch)
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				ch.Close()
+			}()
+		case "subsystem":
+			var p struct{ Name string }
+			ssh.Unmarshal(req.Payload, &p)
+			req.Reply(p.Name == "sftp", nil)
+			if p.Name == "sftp" {
+				go func() {
+					defer ch.Close()
+					server, err := sftp.NewServer(ch, sftp.WithServerWorkingDirectory(s.root))
+					if err != nil {
+						return
+					}
+					server.Serve()
+				}()
+			}
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *testServer) exec(command string, ch ssh.Channel) uint32 {
+	name, arg, _ := strings.Cut(command, " ")
+	switch name {
+	case "echo":
+		fmt.Fprintln(ch, arg)
+		return 0
+	case "exit":
+		status, _ := strconv.Atoi(arg)
+		return uint32(status)
+	case "wait":
+		<-s.release
+		return 0
+	default:
+		fmt.Fprintf(ch.Stderr(), "%s: command not found\n", name)
+		return 127
+	}
+}
+
+func copyBoth(a, b io.ReadWriteCloser) {
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func echoServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(c, c)
+				c.Close()
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// assertEcho checks that data sent to addr is echoed back, retrying while
+// the forward is being set up.
+func assertEcho(t *testing.T, addr string) {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestParseForward(t *testing.T) {
+	cases := []struct {
+		spec string
+		f    Forward
+		err  string
+	}{
+		{spec: "8080:localhost:80", f: Forward{BindAddress: "localhost", BindPort: 8080, Host: "localhost", HostPort: 80}},
+		{spec: "0.0.0.0:8080:10.0.0.2:80", f: Forward{BindAddress: "0.0.0.0", BindPort: 8080, Host: "10.0.0.2", HostPort: 80}},
+		{spec: "*:8080:10.0.0.2:80", f: Forward{BindAddress: "0.0.0.0", BindPort: 8080, Host: "10.0.0.2", HostPort: 80}},
+		{spec: "[::1]:8080:[fd00::2]:80", f: Forward{BindAddress: "::1", BindPort: 8080, Host: "fd00::2", HostPort: 80}},
+		{spec: "8080:80", err: `invalid port forward "8080:80", must be [bind_address:]port:host:hostport`},
+		{spec: "http:localhost:80", err: `invalid port forward "http:localhost:80": bad port "http"`},
+	}
+
+	for _, c := range cases {
+		f, err := ParseForward(c.spec)
+		if c.err != "" {
+			assert.EqualError(t, err, c.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, c.f, f)
+	}
+}
+
+func TestRunnerCommand(t *testing.T) {
+	s := newTestServer(t)
+	r := s.runner(t)
+	r.Command = "echo hello"
+
+	require.NoError(t, r.Run())
+	assert.Equal(t, "hello\n", r.Stdout.(*bytes.Buffer).String())
+
+	r.Command = "exit 3"
+	var exitErr *ssh.ExitError
+	require.ErrorAs(t, r.Run(), &exitErr)
+	assert.Equal(t, 3, exitErr.ExitStatus())
+}
+
+func TestRunnerHostKeyPinning(t *testing.T) {
+	s := newTestServer(t)
+	r := s.runner(t)
+	r.Command = "echo hello"
+
+	r.HostKey = "SHA256:not-the-key"
+	err := r.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected SHA256:not-the-key")
+}
+
+func TestRunnerKnownHosts(t *testing.T) {
+	s := newTestServer(t)
+	r := s.runner(t)
+	r.Command = "echo hello"
+	r.HostKey = ""
+
+	// without a terminal, the key of a new host is only accepted with
+	// AcceptNewHostKeys
+	err := r.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not known, its ssh-ed25519 key fingerprint is "+ssh.FingerprintSHA256(s.hostKey.PublicKey()))
+	b, err := os.ReadFile(r.KnownHostsPath)
+	require.NoError(t, err)
+	assert.Empty(t, b)
+
+	r.AcceptNewHostKeys = true
+	require.NoError(t, r.Run())
+	b, err = os.ReadFile(r.KnownHostsPath)
+	require.NoError(t, err)
+	assert.Equal(t, knownhosts.Line([]string{knownhosts.Normalize(s.address())}, s.hostKey.PublicKey())+"\n", string(b))
+	assert.Contains(t, r.Stderr.(*bytes.Buffer).String(), "Permanently added")
+
+	r.AcceptNewHostKeys = false
+	require.NoError(t, r.Run())
+
+	// a host presenting another key is rejected
+	other := newTestServer(t)
+	line := knownhosts.Line([]string{knownhosts.Normalize(other.address())}, s.hostKey.PublicKey())
+	require.NoError(t, os.WriteFile(r.KnownHostsPath, []byte(line+"\n"), 0600))
+	r.Port = other.port
+
+	err = r.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the key in")
+}
+
+func TestRunnerLocalForward(t *testing.T) {
+	s := newTestServer(t)
+	echo := echoServer(t)
+	host, port, _ := net.SplitHostPort(echo)
+	hostPort, _ := strconv.Atoi(port)
+
+	r := s.runner(t)
+	r.Command = "wait"
+	local := Forward{BindAddress: "127.0.0.1", BindPort: freePort(t), Host: host, HostPort: hostPort}
+	r.LocalForwards = []Forward{local}
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	assertEcho(t, local.bindAddress())
+	close(s.release)
+	require.NoError(t, <-done)
+}
+
+func TestRunnerRemoteForward(t *testing.T) {
+	s := newTestServer(t)
+	echo := echoServer(t)
+	host, port, _ := net.SplitHostPort(echo)
+	hostPort, _ := strconv.Atoi(port)
+
+	r := s.runner(t)
+	r.Command = "wait"
+	remote := Forward{BindAddress: "127.0.0.1", BindPort: freePort(t), Host: host, HostPort: hostPort}
+	r.RemoteForwards = []Forward{remote}
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	assertEcho(t, remote.bindAddress())
+	close(s.release)
+	require.NoError(t, <-done)
+}
+
+func TestRunnerBastion(t *testing.T) {
+	bastion := newTestServer(t)
+	s := newTestServer(t)
+
+	r := s.runner(t)
+	r.Command = "echo through the bastion"
+	r.HostKey = ""
+	r.Bastion = &Endpoint{User: "root", Host: bastion.host, Port: bastion.port}
+
+	// new host keys are confirmed one host at a time
+	var confirmed []string
+	confirm := confirmHostKey
+	confirmHostKey = func(hostname string, key ssh.PublicKey) error {
+		confirmed = append(confirmed, hostname)
+		return nil
+	}
+	t.Cleanup(func() { confirmHostKey = confirm })
+
+	require.NoError(t, r.Run())
+	assert.Equal(t, "through the bastion\n", r.Stdout.(*bytes.Buffer).String())
+
+	b, err := os.ReadFile(r.KnownHostsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), knownhosts.Normalize(bastion.address()))
+	assert.Contains(t, string(b), knownhosts.Normalize(s.address()))
+	assert.Equal(t, []string{bastion.address(), s.address()}, confirmed)
+}
+
+func TestRunnerSharedKeys(t *testing.T) {
+	s := newTestServer(t)
+
+	b, err := os.ReadFile("testdata/id_rsa_without_password")
+	require.NoError(t, err)
+	key, err := ssh.ParseRawPrivateKey(b)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("secret"))
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "id_rsa")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600))
+
+	prompts := 0
+	read := readPassphrase
+	readPassphrase = func(path string) ([]byte, error) {
+		prompts++
+		return []byte("secret"), nil
+	}
+	t.Cleanup(func() { readPassphrase = read })
+
+	keys, err := LoadKeys(keyPath)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	// runners given the keys do not decrypt them again
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		r := s.runner(t)
+		r.Command = "echo hello"
+		r.KeyPath = keyPath
+		r.Keys = keys
+		go func() { errs <- r.Run() }()
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errs)
+	}
+	assert.Equal(t, 1, prompts)
+}
+
+func TestCopier(t *testing.T) {
+	s := newTestServer(t)
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "app.conf"), []byte("listen 80\n"), 0640))
+	require.NoError(t, os.Mkdir(filepath.Join(src, "static"), 0755))
+	large := bytes.Repeat([]byte("0123456789"), 10000)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "static", "large.bin"), large, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(s.root, "srv"), 0755))
+
+	// a file copied to a directory keeps its name
+	c := &Copier{Runner: *s.runner(t), Transfer: Transfer{Source: filepath.Join(src, "app.conf"), Destination: "srv", Upload: true}}
+	require.NoError(t, c.Run())
+	b, err := os.ReadFile(filepath.Join(s.root, "srv", "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "listen 80\n", string(b))
+
+	c.Transfer = Transfer{Source: filepath.Join(src, "static"), Destination: "srv", Upload: true}
+	assert.EqualError(t, c.Run(), filepath.Join(src, "static")+" is a directory, use --recursive to copy it")
+
+	c.Recursive = true
+	require.NoError(t, c.Run())
+	b, err = os.ReadFile(filepath.Join(s.root, "srv", "static", "large.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, large, b)
+
+	dst := t.TempDir()
+	c.Transfer = Transfer{Source: "~/srv", Destination: dst, Recursive: true}
+	require.NoError(t, c.Run())
+	b, err = os.ReadFile(filepath.Join(dst, "srv", "static", "large.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, large, b)
+	info, err := os.Stat(filepath.Join(dst, "srv", "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0640), info.Mode().Perm())
+
+	c.Transfer = Transfer{Source: "missing.txt", Destination: dst}
+	err = c.Run()
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestRunnerBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executables are shell scripts")
+	}
+
+	bin := t.TempDir()
+	for _, name := range []string{"ssh", "scp"} {
+		script := "#!/bin/sh\necho " + name + " \"$@\"\nexit 3\n"
+		require.NoError(t, os.WriteFile(filepath.Join(bin, name), []byte(script), 0700))
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	r := &Runner{
+		User:              "root",
+		Host:              "203.0.113.10",
+		KeyPath:           "/home/sammy/.ssh/id_ed25519",
+		Port:              2222,
+		AgentForwarding:   true,
+		Command:           "uptime",
+		LocalForwards:     []Forward{{BindAddress: "localhost", BindPort: 8080, Host: "10.0.0.5", HostPort: 80}},
+		Bastion:           &Endpoint{User: "core", Host: "203.0.113.20"},
+		AcceptNewHostKeys: true,
+		UseBinary:         true,
+		Stdout:            &stdout,
+	}
+
+	err := r.Run()
+	assert.Equal(t, 3, ExitStatus(err))
+	assert.Equal(t, "ssh -i /home/sammy/.ssh/id_ed25519 -p 2222 -J core@203.0.113.20 -o StrictHostKeyChecking=accept-new -A -L localhost:8080:10.0.0.5:80 root@203.0.113.10 uptime\n", stdout.String())
+
+	stdout.Reset()
+	c := &Copier{Runner: *r, Transfer: Transfer{Source: "app.conf", Destination: "/srv", Upload: true, Recursive: true}}
+	assert.Equal(t, 3, ExitStatus(c.Run()))
+	assert.Equal(t, "scp -i /home/sammy/.ssh/id_ed25519 -P 2222 -J core@203.0.113.20 -o StrictHostKeyChecking=accept-new -r app.conf root@203.0.113.10:/srv\n", stdout.String())
+
+	r.HostKey = "SHA256:pinned"
+	assert.Equal(t, errBinaryHostKey, r.Run())
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// entry is a directive or record, which spans several lines when it uses
+// parentheses.
+type entry struct {
+	line   int
+	tokens []token
+	// blank is set when the entry starts with whitespace, so the owner of
+	// the previous record applies.
+	blank bool
+}
+
+// lex splits a zone file into entries, removing comments, quotes and
+// escapes.
+func lex(r io.Reader) ([]entry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+
+	var (
+		entries   []entry
+		cur       = entry{line: 1}
+		buf       strings.Builder
+		inToken   bool
+		inQuote   bool
+		depth     int
+		line      = 1
+		lineStart = true
+	)
+
+	flush := func(quoted bool) {
+		if inToken || quoted {
+			cur.tokens = append(cur.tokens, token{text: buf.String(), quoted: quoted})
+		}
+		buf.Reset()
+		inToken = false
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		if ch == '\\' {
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("line %d: trailing backslash", line)
+			}
+			if isDigit(s[i+1]) {
+				if i+3 >= len(s) || !isDigit(s[i+2]) || !isDigit(s[i+3]) {
+					return nil, fmt.Errorf("line %d: invalid escape sequence", line)
+				}
+				n := int(s[i+1]-'0')*100 + int(s[i+2]-'0')*10 + int(s[i+3]-'0')
+				if n > 255 {
+					return nil, fmt.Errorf("line %d: invalid escape sequence \\%s", line, s[i+1:i+4])
+				}
+				buf.WriteByte(byte(n))
+				i += 3
+			} else {
+				if s[i+1] == '\n' {
+					line++
+				}
+				buf.WriteByte(s[i+1])
+				i++
+			}
+			inToken = true
+			lineStart = false
+			continue
+		}
+
+		if inQuote {
+			switch ch {
+			case '"':
+				inQuote = false
+				flush(true)
+			case '\n':
+				return nil, fmt.Errorf("line %d: unterminated quoted string", line)
+			default:
+				buf.WriteByte(ch)
+			}
+			continue
+		}
+
+		switch ch {
+		case ';':
+			for i+1 < len(s) && s[i+1] != '\n' {
+				i++
+			}
+		case '"':
+			flush(false)
+			inQuote = true
+		case '(':
+			flush(false)
+			depth++
+		case ')':
+			flush(false)
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: unbalanced parenthesis", line)
+			}
+			depth--
+		case ' ', '\t', '\r':
+			flush(false)
+			if lineStart && depth == 0 && len(cur.tokens) == 0 {
+				cur.blank = true
+			}
+		case '\n':
+			flush(false)
+			line++
+			if depth == 0 {
+				if len(cur.tokens) > 0 {
+					entries = append(entries, cur)
+				}
+				cur = entry{line: line}
+				lineStart = true
+			}
+			continue
+		default:
+			buf.WriteByte(ch)
+			inToken = true
+		}
+		lineStart = false
+	}
+
+	if inQuote {
+		return nil, errors.New("unterminated quoted string at end of file")
+	}
+	if depth > 0 {
+		return nil, errors.New("unbalanced parenthesis at end of file")
+	}
+
+	flush(false)
+	if len(cur.tokens) > 0 {
+		entries = append(entries, cur)
+	}
+
+	return entries, nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package zonefile reads and writes DNS zone files in the master file format
+// of RFC 1035.
+package zonefile
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Record is a resource record of a zone file. Names, including the names in
+// the data of CNAME, MX, NS, PTR, SOA and SRV records, are fully qualified
+// and end with a dot.
+type Record struct {
+	Name  string
+	TTL   int
+	Class string
+	Type  string
+	// Data holds the fields of the record data, with quotes and escapes
+	// removed.
+	Data []string
+	// Line is the line of the zone file the record starts on.
+	Line int
+}
+
+// minFields is the number of data fields of the record types with a fixed
+// layout.
+var minFields = map[string]int{
+	"A":     1,
+	"AAAA":  1,
+	"CAA":   3,
+	"CNAME": 1,
+	"MX":    2,
+	"NS":    1,
+	"PTR":   1,
+	"SOA":   7,
+	"SRV":   4,
+	"TXT":   1,
+}
+
+// nameFields are the data fields holding domain names, which are relative to
+// the origin unless they end with a dot.
+var nameFields = map[string][]int{
+	"CNAME": {0},
+	"MX":    {1},
+	"NS":    {0},
+	"PTR":   {0},
+	"SOA":   {0, 1},
+	"SRV":   {3},
+}
+
+var classes = map[string]bool{"IN": true, "CS": true, "CH": true, "HS": true}
+
+// Fqdn returns name with a trailing dot.
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// Parse reads the records of a zone file. Relative names are completed with
+// origin until a $ORIGIN directive changes it.
+func Parse(r io.Reader, origin string) ([]Record, error) {
+	entries, err := lex(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{origin: strings.ToLower(Fqdn(origin))}
+	var records []Record
+	for _, e := range entries {
+		record, ok, err := p.parse(e)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", e.line, err)
+		}
+		if ok {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+type parser struct {
+	origin     string
+	owner      string
+	defaultTTL int
+	hasDefault bool
+	lastTTL    int
+}
+
+// parse parses an entry, which is either a directive or a record.
+func (p *parser) parse(e entry) (Record, bool, error) {
+	toks := e.tokens
+
+	if first := toks[0]; !first.quoted && strings.HasPrefix(first.text, "$") {
+		directive := strings.ToUpper(first.text)
+		switch directive {
+		case "$ORIGIN":
+			if len(toks) != 2 {
+				return Record{}, false, fmt.Errorf("%s takes a domain name", directive)
+			}
+			p.origin = p.absolute(toks[1].text)
+		case "$TTL":
+			if len(toks) != 2 {
+				return Record{}, false, fmt.Errorf("%s takes a TTL", directive)
+			}
+			ttl, ok := parseTTL(toks[1].text)
+			if !ok {
+				return Record{}, false, fmt.Errorf("invalid TTL %q", toks[1].text)
+			}
+			p.defaultTTL, p.hasDefault = ttl, true
+		case "$INCLUDE":
+			return Record{}, false, fmt.Errorf("%s is not supported", directive)
+		default:
+			return Record{}, false, fmt.Errorf("unknown directive %s", first.text)
+		}
+		return Record{}, false, nil
+	}
+
+	if !e.blank {
+		p.owner = p.absolute(toks[0].text)
+		toks = toks[1:]
+	} else if p.owner == "" {
+		return Record{}, false, fmt.Errorf("the first record has no owner name")
+	}
+
+	record := Record{Name: p.owner, Class: "IN", Line: e.line}
+	ttlSet, classSet := false, false
+	for len(toks) > 0 && !toks[0].quoted {
+		text := toks[0].text
+		if !classSet && classes[strings.ToUpper(text)] {
+			record.Class = strings.ToUpper(text)
+			classSet = true
+		} else if ttl, ok := parseTTL(text); ok && !ttlSet {
+			record.TTL = ttl
+			ttlSet = true
+		} else {
+			break
+		}
+		toks = toks[1:]
+	}
+
+	if len(toks) == 0 {
+		return Record{}, false, fmt.Errorf("record of %s has no type", record.Name)
+	}
+	record.Type = strings.ToUpper(toks[0].text)
+
+	switch {
+	case ttlSet:
+		p.lastTTL = record.TTL
+	case p.hasDefault:
+		record.TTL = p.defaultTTL
+	default:
+		record.TTL = p.lastTTL
+	}
+
+	for _, t := range toks[1:] {
+		record.Data = append(record.Data, t.text)
+	}
+	if n, ok := minFields[record.Type]; ok && len(record.Data) < n {
+		return Record{}, false, fmt.Errorf("%s record of %s has %d data fields, expected %d", record.Type, record.Name, len(record.Data), n)
+	}
+	for _, i := range nameFields[record.Type] {
+		record.Data[i] = p.absolute(record.Data[i])
+	}
+
+	return record, true, nil
+}
+
+// absolute completes a relative name with the origin.
+func (p *parser) absolute(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "@":
+		return p.origin
+	case strings.HasSuffix(name, "."):
+		return name
+	case p.origin == ".":
+		return name + "."
+	default:
+		return name + "." + p.origin
+	}
+}
+
+// parseTTL parses a TTL in seconds or, as BIND does, as a sequence of
+// numbers with s, m, h, d or w units such as 1h30m.
+func parseTTL(s string) (int, bool) {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0, false
+	}
+	if n, err := strconv.ParseUint(s, 10, 31); err == nil {
+		return int(n), true
+	}
+
+	units := map[byte]int{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	total, n, digits := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= '0' && ch <= '9' {
+			n = n*10 + int(ch-'0')
+			digits = true
+			continue
+		}
+		unit, ok := units[ch|0x20]
+		if !ok || !digits {
+			return 0, false
+		}
+		total += n * unit
+		n, digits = 0, false
+	}
+	if digits {
+		return 0, false
+	}
+	return total, true
+}
+
+// Write writes records as a zone file, with names relative to origin where
+// possible.
+func Write(w io.Writer, origin string, records []Record) error {
+	origin = strings.ToLower(Fqdn(origin))
+
+	if _, err := fmt.Fprintf(w, "$ORIGIN %s\n", origin); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	for _, r := range records {
+		class := r.Class
+		if class == "" {
+			class = "IN"
+		}
+
+		data := make([]string, len(r.Data))
+		for i, d := range r.Data {
+			switch {
+			case r.Type == "TXT", r.Type == "CAA" && i == 2:
+				data[i] = quote(d)
+			default:
+				data[i] = d
+			}
+		}
+		for _, i := range nameFields[r.Type] {
+			if i < len(data) {
+				data[i] = relative(data[i], origin)
+			}
+		}
+
+		_, err := fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", relative(r.Name, origin), r.TTL, class, r.Type, strings.Join(data, " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// relative returns name relative to origin, or name itself when it is
+// outside of origin.
+func relative(name, origin string) string {
+	name = strings.ToLower(Fqdn(name))
+	if name == origin {
+		return "@"
+	}
+	if rel, ok := strings.CutSuffix(name, "."+origin); ok {
+		return rel
+	}
+	return name
+}
+
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '"' || ch == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch < ' ' || ch > '~':
+			fmt.Fprintf(&b, "\\%03d", ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
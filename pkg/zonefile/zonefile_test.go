@@ -0,0 +1,119 @@
+package zonefile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	zone := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2024010101 ; serial
+		7200 3600 1209600 300 )
+	IN	NS	ns1.example.net.
+	300	IN	MX	10 mail
+www	A	203.0.113.10 ; web server
+	600 AAAA 2001:db8::10
+api.example.com. IN 60 CNAME www
+txt	TXT	"v=spf1 include:_spf.example.net ~all" "second \"part\""
+@	CAA	0 issue "letsencrypt.org"
+_sip._tcp	SRV	10 60 5060 sip.example.net.
+$ORIGIN sub.example.com.
+host	A	198.51.100.1
+`
+
+	records, err := Parse(strings.NewReader(zone), "ignored.test")
+	require.NoError(t, err)
+
+	expected := []Record{
+		{Name: "example.com.", TTL: 3600, Class: "IN", Type: "SOA", Data: []string{"ns1.example.com.", "hostmaster.example.com.", "2024010101", "7200", "3600", "1209600", "300"}, Line: 3},
+		{Name: "example.com.", TTL: 3600, Class: "IN", Type: "NS", Data: []string{"ns1.example.net."}, Line: 6},
+		{Name: "example.com.", TTL: 300, Class: "IN", Type: "MX", Data: []string{"10", "mail.example.com."}, Line: 7},
+		{Name: "www.example.com.", TTL: 3600, Class: "IN", Type: "A", Data: []string{"203.0.113.10"}, Line: 8},
+		{Name: "www.example.com.", TTL: 600, Class: "IN", Type: "AAAA", Data: []string{"2001:db8::10"}, Line: 9},
+		{Name: "api.example.com.", TTL: 60, Class: "IN", Type: "CNAME", Data: []string{"www.example.com."}, Line: 10},
+		{Name: "txt.example.com.", TTL: 3600, Class: "IN", Type: "TXT", Data: []string{"v=spf1 include:_spf.example.net ~all", `second "part"`}, Line: 11},
+		{Name: "example.com.", TTL: 3600, Class: "IN", Type: "CAA", Data: []string{"0", "issue", "letsencrypt.org"}, Line: 12},
+		{Name: "_sip._tcp.example.com.", TTL: 3600, Class: "IN", Type: "SRV", Data: []string{"10", "60", "5060", "sip.example.net."}, Line: 13},
+		{Name: "host.sub.example.com.", TTL: 3600, Class: "IN", Type: "A", Data: []string{"198.51.100.1"}, Line: 15},
+	}
+	assert.Equal(t, expected, records)
+}
+
+func TestParseDefaultOrigin(t *testing.T) {
+	records, err := Parse(strings.NewReader("www 300 IN A 203.0.113.10\n"), "example.com")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "www.example.com.", records[0].Name)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+		err  string
+	}{
+		{name: "include", zone: "$INCLUDE other.zone\n", err: "line 1: $INCLUDE is not supported"},
+		{name: "no owner", zone: "  IN A 203.0.113.10\n", err: "line 1: the first record has no owner name"},
+		{name: "no type", zone: "www 300 IN\n", err: "line 1: record of www.example.com. has no type"},
+		{name: "missing fields", zone: "\nmail MX 10\n", err: "line 2: MX record of mail.example.com. has 1 data fields, expected 2"},
+		{name: "unterminated quote", zone: "txt TXT \"open\n", err: "line 1: unterminated quoted string"},
+		{name: "unbalanced parenthesis", zone: "@ SOA ns1 host ( 1 2 3 4 5\n", err: "unbalanced parenthesis at end of file"},
+		{name: "bad ttl", zone: "$TTL forever\n", err: `line 1: invalid TTL "forever"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.zone), "example.com")
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	for in, expected := range map[string]int{"300": 300, "1h30m": 5400, "1W": 604800, "2d": 172800} {
+		ttl, ok := parseTTL(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, expected, ttl, in)
+	}
+
+	for _, in := range []string{"", "MX", "1x", "10m5", "h"} {
+		_, ok := parseTTL(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	records := []Record{
+		{Name: "example.com.", TTL: 1800, Type: "NS", Data: []string{"ns1.digitalocean.com."}},
+		{Name: "example.com.", TTL: 300, Type: "MX", Data: []string{"10", "mail.example.com."}},
+		{Name: "www.example.com.", TTL: 3600, Type: "A", Data: []string{"203.0.113.10"}},
+		{Name: "txt.example.com.", TTL: 3600, Type: "TXT", Data: []string{`say "hi"`}},
+		{Name: "example.com.", TTL: 3600, Type: "CAA", Data: []string{"0", "issue", "letsencrypt.org"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "example.com", records))
+
+	expected := `$ORIGIN example.com.
+@   1800 IN NS  ns1.digitalocean.com.
+@   300  IN MX  10 mail
+www 3600 IN A   203.0.113.10
+txt 3600 IN TXT "say \"hi\""
+@   3600 IN CAA 0 issue "letsencrypt.org"
+`
+	assert.Equal(t, expected, buf.String())
+
+	parsed, err := Parse(&buf, "example.com")
+	require.NoError(t, err)
+	for i := range parsed {
+		parsed[i].Line = 0
+		records[i].Class = "IN"
+	}
+	assert.Equal(t, records, parsed)
+}
@@ -14,24 +14,39 @@ limitations under the License.
 package doctl
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/oauth2"
 )
 
 // recorder traces http connections. It sends the output to a request and
-// response channels.
+// response channels. When a redactor is set, secrets are masked before the
+// output is sent.
 type recorder struct {
-	wrap http.RoundTripper
-	req  chan string
-	resp chan string
+	wrap   http.RoundTripper
+	redact *redactor
+	req    chan string
+	resp   chan string
 }
 
-func newRecorder(transport http.RoundTripper) *recorder {
+func newRecorder(transport http.RoundTripper, redact *redactor) *recorder {
 	return &recorder{
-		wrap: transport,
-		req:  make(chan string),
-		resp: make(chan string),
+		wrap:   transport,
+		redact: redact,
+		req:    make(chan string),
+		resp:   make(chan string),
 	}
 }
 
@@ -40,7 +55,7 @@ func (rec *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("transport.Recorder: dumping request, %v", err)
 	}
-	rec.req <- string(reqBytes)
+	rec.req <- rec.redact.dump(reqBytes)
 
 	resp, rerr := rec.wrap.RoundTrip(req)
 	if rerr != nil {
@@ -51,7 +66,262 @@ func (rec *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("transport.Recorder: dumping response, %v", err)
 	}
-	rec.resp <- string(respBytes)
+	rec.resp <- rec.redact.dump(respBytes)
 
 	return resp, nil
 }
+
+// sensitiveHeaders are the HTTP headers masked by the default redactor.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Proxy-Authorization",
+	"X-Auth-Token",
+}
+
+// sensitiveFields are the JSON and YAML fields masked by the default
+// redactor. They cover API tokens, database passwords, registry docker
+// configs, Spaces secret keys and kubeconfig credentials.
+var sensitiveFields = []string{
+	"access_token",
+	"auth",
+	"client-certificate-data",
+	"client-key-data",
+	"client_secret",
+	"id_token",
+	"password",
+	"private_key",
+	"refresh_token",
+	"secret",
+	"secret_key",
+	"token",
+}
+
+// defaultRedactor masks the default sensitive headers and fields.
+var defaultRedactor = newRedactor(sensitiveHeaders, sensitiveFields)
+
+// uriPassword matches the password in the user info of a URI, e.g. in a
+// database connection string.
+var uriPassword = regexp.MustCompile(`(://[^:/@\s"]+):[^@\s"]+@`)
+
+// redactor masks secrets in dumped HTTP messages.
+type redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+
+	jsonField *regexp.Regexp
+	yamlField *regexp.Regexp
+}
+
+func newRedactor(headers, fields []string) *redactor {
+	r := &redactor{
+		headers: map[string]bool{},
+		fields:  map[string]bool{},
+	}
+
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		r.fields[f] = true
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+
+	names := strings.Join(quoted, "|")
+	r.jsonField = regexp.MustCompile(`("(?:` + names + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	r.yamlField = regexp.MustCompile(`(?m)^(\s*-?\s*(?:` + names + `):[ \t]*)\S.*$`)
+
+	return r
+}
+
+// dump masks the sensitive headers and body fields of a message dumped with
+// httputil. A nil redactor returns the message unchanged.
+func (r *redactor) dump(msg []byte) string {
+	if r == nil {
+		return string(msg)
+	}
+
+	head, body, found := bytes.Cut(msg, []byte("\r\n\r\n"))
+
+	lines := strings.Split(string(head), "\r\n")
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if ok && r.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] {
+			lines[i] = name + ": " + redactedValue
+		}
+	}
+
+	out := strings.Join(lines, "\r\n")
+	if found {
+		out += "\r\n\r\n" + r.body(body)
+	}
+
+	return out
+}
+
+// body masks sensitive fields in a message body. JSON bodies are rewritten
+// field by field; anything else, such as YAML or chunked bodies, is masked
+// by pattern.
+func (r *redactor) body(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		if out, err := json.Marshal(r.value(v)); err == nil {
+			return string(out)
+		}
+	}
+
+	s := r.jsonField.ReplaceAllString(string(b), `${1}"`+redactedValue+`"`)
+	s = r.yamlField.ReplaceAllString(s, "${1}"+redactedValue)
+	return uriPassword.ReplaceAllString(s, "${1}:"+redactedValue+"@")
+}
+
+func (r *redactor) value(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, fv := range t {
+			if _, isString := fv.(string); isString && r.fields[k] {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = r.value(fv)
+		}
+		return t
+	case []any:
+		for i := range t {
+			t[i] = r.value(t[i])
+		}
+		return t
+	case string:
+		if strings.Contains(t, "\n") {
+			// embedded documents, e.g. a kubeconfig
+			t = r.yamlField.ReplaceAllString(t, "${1}"+redactedValue)
+		}
+		return uriPassword.ReplaceAllString(t, "${1}:"+redactedValue+"@")
+	default:
+		return v
+	}
+}
+
+// jsonTracer traces http connections by writing one JSON object per HTTP
+// exchange. When installed below godo's retrying client, every attempt is
+// traced separately along with its attempt number.
+type jsonTracer struct {
+	wrap http.RoundTripper
+	out  io.Writer
+
+	mu       sync.Mutex
+	attempts map[*http.Request]int
+}
+
+// traceEntry is a single HTTP exchange written by the jsonTracer.
+type traceEntry struct {
+	Time       time.Time       `json:"time"`
+	Method     string          `json:"method"`
+	URL        string          `json:"url"`
+	Status     int             `json:"status,omitempty"`
+	DurationMS float64         `json:"duration_ms"`
+	RequestID  string          `json:"request_id,omitempty"`
+	RateLimit  *traceRateLimit `json:"rate_limit,omitempty"`
+	Attempt    int             `json:"attempt"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type traceRateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+func newJSONTracer(transport http.RoundTripper, out io.Writer) *jsonTracer {
+	return &jsonTracer{
+		wrap:     transport,
+		out:      out,
+		attempts: map[*http.Request]int{},
+	}
+}
+
+// logAttempt is a retryablehttp.RequestLogHook recording the retry number of
+// the request about to be sent.
+func (t *jsonTracer) logAttempt(_ retryablehttp.Logger, req *http.Request, retry int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attempts[req] = retry + 1
+}
+
+func (t *jsonTracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	attempt, ok := t.attempts[req]
+	delete(t.attempts, req)
+	t.mu.Unlock()
+	if !ok {
+		attempt = 1
+	}
+
+	entry := traceEntry{
+		Time:    time.Now(),
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Attempt: attempt,
+	}
+
+	resp, err := t.wrap.RoundTrip(req)
+	entry.DurationMS = float64(time.Since(entry.Time).Microseconds()) / 1000
+
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.RequestID = resp.Header.Get("X-Request-Id")
+		entry.RateLimit = rateLimitFromHeader(resp.Header)
+	}
+
+	b, merr := json.Marshal(entry)
+	if merr == nil {
+		t.mu.Lock()
+		t.out.Write(append(b, '\n'))
+		t.mu.Unlock()
+	}
+
+	return resp, err
+}
+
+// rateLimitFromHeader reads the rate limit headers returned by the API.
+func rateLimitFromHeader(h http.Header) *traceRateLimit {
+	limit := h.Get("RateLimit-Limit")
+	if limit == "" {
+		return nil
+	}
+
+	rl := &traceRateLimit{}
+	rl.Limit, _ = strconv.Atoi(limit)
+	rl.Remaining, _ = strconv.Atoi(h.Get("RateLimit-Remaining"))
+	rl.Reset, _ = strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64)
+
+	return rl
+}
+
+// retryableClient returns the retrying client installed by
+// godo.WithRetryAndBackoffs, or nil when retries are disabled.
+func retryableClient(client *godo.Client) *retryablehttp.Client {
+	ot, ok := client.HTTPClient.Transport.(*oauth2.Transport)
+	if !ok {
+		return nil
+	}
+
+	rt, ok := ot.Base.(*retryablehttp.RoundTripper)
+	if !ok {
+		return nil
+	}
+
+	return rt.Client
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// throttle is a http.RoundTripper sharing a token bucket between every
+// request sent by a client. The bucket refills at the configured rate until
+// the API reports that fewer than reserve requests remain in the current rate
+// limit window. From then on the remaining budget is spread evenly over the
+// time left until the window resets, so concurrent requests slow down instead
+// of failing with a 429.
+type throttle struct {
+	wrap    http.RoundTripper
+	limiter *rate.Limiter
+	base    rate.Limit
+	burst   int
+	reserve int
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+// newThrottle builds a throttle allowing requestsPerSecond requests with the
+// given burst. A rate of zero only throttles once the API budget runs low.
+func newThrottle(transport http.RoundTripper, requestsPerSecond float64, burst, reserve int) *throttle {
+	base := rate.Inf
+	if requestsPerSecond > 0 {
+		base = rate.Limit(requestsPerSecond)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &throttle{
+		wrap:    transport,
+		limiter: rate.NewLimiter(base, burst),
+		base:    base,
+		burst:   burst,
+		reserve: reserve,
+		now:     time.Now,
+	}
+}
+
+func (t *throttle) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.wrap.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.observe(resp.Header)
+
+	return resp, nil
+}
+
+// observe adjusts the refill rate of the bucket from the rate limit headers
+// of a response.
+func (t *throttle) observe(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	window := time.Unix(reset, 0).Sub(t.now())
+	if remaining > t.reserve || window <= 0 {
+		t.limiter.SetLimit(t.base)
+		t.limiter.SetBurst(t.burst)
+		return
+	}
+
+	limit := rate.Every(window)
+	if remaining > 0 {
+		limit = rate.Limit(float64(remaining) / window.Seconds())
+	}
+	if limit > t.base {
+		limit = t.base
+	}
+
+	t.limiter.SetLimit(limit)
+	t.limiter.SetBurst(1)
+
+	if remaining == 0 {
+		// drain the bucket so no request is sent before the window resets
+		t.limiter.Reserve()
+	}
+}
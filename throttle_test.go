@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestThrottleObserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name          string
+		rps           float64
+		remaining     string
+		reset         time.Duration
+		expectedLimit rate.Limit
+		expectedBurst int
+	}{
+		{
+			name:          "plenty of budget left",
+			remaining:     "4000",
+			reset:         time.Hour,
+			expectedLimit: rate.Inf,
+			expectedBurst: 10,
+		},
+		{
+			name:          "budget running low",
+			remaining:     "10",
+			reset:         100 * time.Second,
+			expectedLimit: rate.Limit(0.1),
+			expectedBurst: 1,
+		},
+		{
+			name:          "budget exhausted",
+			remaining:     "0",
+			reset:         50 * time.Second,
+			expectedLimit: rate.Every(50 * time.Second),
+			expectedBurst: 1,
+		},
+		{
+			name:          "configured rate is lower",
+			rps:           0.01,
+			remaining:     "10",
+			reset:         100 * time.Second,
+			expectedLimit: rate.Limit(0.01),
+			expectedBurst: 1,
+		},
+		{
+			name:          "window already reset",
+			rps:           5,
+			remaining:     "0",
+			reset:         -time.Second,
+			expectedLimit: rate.Limit(5),
+			expectedBurst: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newThrottle(http.DefaultTransport, tt.rps, 10, 25)
+			th.now = func() time.Time { return now }
+
+			h := http.Header{}
+			h.Set("RateLimit-Remaining", tt.remaining)
+			h.Set("RateLimit-Reset", strconv.FormatInt(now.Add(tt.reset).Unix(), 10))
+			th.observe(h)
+
+			assert.InDelta(t, float64(tt.expectedLimit), float64(th.limiter.Limit()), 1e-9)
+			assert.Equal(t, tt.expectedBurst, th.limiter.Burst())
+		})
+	}
+}
+
+func TestThrottleRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Limit", "5000")
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newThrottle(http.DefaultTransport, 0, 1, 25)}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// the budget is exhausted, the next request has to wait for the reset
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.Error(t, err)
+}